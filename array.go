@@ -0,0 +1,313 @@
+package necpp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// SpeedOfLight is the speed of light in a vacuum, in meters per microsecond,
+// so that dividing it by a frequency in MHz gives a wavelength in meters.
+const SpeedOfLight = 299.792458
+
+// Taper is how the excitation amplitudes of an array are graded from the
+// middle of the array out to its edges.
+//
+// • Uniform - every element gets the same amplitude, for the narrowest beam
+// but sidelobes only 13 dB down.
+//
+// • Binomial - amplitudes follow the binomial coefficients, which gets rid of
+// the sidelobes altogether at the cost of a wide beam.
+//
+// • DolphChebyshev - the narrowest beam for sidelobes all at the same level,
+// given by the array's SidelobeDB.
+type Taper int
+
+const (
+	Uniform Taper = iota
+	Binomial
+	DolphChebyshev
+)
+
+// Array is a rectangular grid of identical elements, NX along the X axis and
+// NY along the Y axis, each fed with its own voltage source.
+//
+// Element adds the wires of a single element, centered wherever it should be
+// for the first element of the array, and using tags starting from 1. The rest
+// of the elements are copied from it with GM cards, so the tags of element i
+// (counting along X first, then Y) are the element's tags plus i times the
+// highest tag the element uses. Feed is the element's feed point, which must be
+// given by tag rather than absolute segment number.
+//
+// The excitations are worked out for a beam steered towards SteerTheta and
+// SteerPhi (in degrees, the same as for a radiation pattern) at FreqMHz.
+type Array struct {
+	Element func(n Engine) error
+	Feed    Port
+	NX      int
+	NY      int
+	DX      float64 // element spacing along X, in meters
+	DY      float64 // element spacing along Y, in meters
+
+	Taper      Taper
+	SidelobeDB float64 // for DolphChebyshev, how far down the sidelobes are
+
+	SteerTheta float64
+	SteerPhi   float64
+	FreqMHz    float64
+
+	// PatternStep is the angle step, in degrees, of the pattern taken by
+	// Run(). Defaults to 5.
+	PatternStep float64
+
+	tagInc int
+}
+
+// ArrayResult is the outcome of running an array model. Weights, Ports and
+// ActiveImpedance are in element order. An element's active impedance is the
+// impedance seen at its feed with all of the elements driven, which is what its
+// feedline actually has to match.
+type ArrayResult struct {
+	FreqMHz         float64
+	Weights         []complex128
+	Ports           []Port
+	ActiveImpedance []complex128
+	Pattern         *RadiationPattern
+}
+
+func (a *Array) check() error {
+	if a.Element == nil {
+		return errors.New("the array has no element")
+	}
+	if a.NX < 1 || a.NY < 1 {
+		return errors.New("the array needs at least one element along X and Y")
+	}
+	if a.Feed.Tag == 0 {
+		return errors.New("the array's feed must be given by tag")
+	}
+	if a.FreqMHz <= 0 {
+		return errors.New("the array needs a design frequency")
+	}
+	return nil
+}
+
+// Weights returns the complex excitation of each element, with the largest
+// amplitude scaled to 1 volt.
+func (a *Array) Weights() ([]complex128, error) {
+	if err := a.check(); err != nil {
+		return nil, err
+	}
+	tx, err := taper(a.Taper, a.NX, a.SidelobeDB)
+	if err != nil {
+		return nil, err
+	}
+	ty, err := taper(a.Taper, a.NY, a.SidelobeDB)
+	if err != nil {
+		return nil, err
+	}
+	k := 2 * math.Pi * a.FreqMHz / SpeedOfLight
+	th := a.SteerTheta * math.Pi / 180
+	ph := a.SteerPhi * math.Pi / 180
+	u, v := math.Sin(th)*math.Cos(ph), math.Sin(th)*math.Sin(ph)
+
+	w := make([]complex128, a.NX*a.NY)
+	maxAmp := 0.0
+	for iy := 0; iy < a.NY; iy++ {
+		for ix := 0; ix < a.NX; ix++ {
+			amp := tx[ix] * ty[iy]
+			// delay each element so the waves all line up in the
+			// steering direction
+			phase := -k * (float64(ix)*a.DX*u + float64(iy)*a.DY*v)
+			w[iy*a.NX+ix] = cmplx.Rect(amp, phase)
+			maxAmp = math.Max(maxAmp, amp)
+		}
+	}
+	for i := range w {
+		w[i] /= complex(maxAmp, 0)
+	}
+	return w, nil
+}
+
+// taper returns the amplitudes of n elements in a line.
+func taper(t Taper, n int, sidelobeDB float64) ([]float64, error) {
+	w := make([]float64, n)
+	switch t {
+	case Uniform:
+		for i := range w {
+			w[i] = 1
+		}
+	case Binomial:
+		// Pascal's triangle, one row at a time
+		w[0] = 1
+		for row := 1; row < n; row++ {
+			for i := row; i > 0; i-- {
+				w[i] += w[i-1]
+			}
+		}
+	case DolphChebyshev:
+		if sidelobeDB <= 0 {
+			return nil, errors.New("a Dolph-Chebyshev taper needs a sidelobe level")
+		}
+		if n == 1 {
+			w[0] = 1
+			break
+		}
+		// The array factor is the Chebyshev polynomial T(n-1) of
+		// x0 cos(psi/2); sampling it at n points around the circle and
+		// taking the inverse DFT gives the weights.
+		r := math.Pow(10, sidelobeDB/20)
+		x0 := math.Cosh(math.Acosh(r) / float64(n-1))
+		mid := float64(n-1) / 2
+		for i := range w {
+			s := 0.0
+			for k := 0; k < n; k++ {
+				psi := 2 * math.Pi * float64(k) / float64(n)
+				s += chebyshev(n-1, x0*math.Cos(psi/2)) * math.Cos(psi*(float64(i)-mid))
+			}
+			w[i] = s / float64(n)
+		}
+	default:
+		return nil, fmt.Errorf("unknown taper %d", int(t))
+	}
+	return w, nil
+}
+
+// chebyshev returns the Chebyshev polynomial of the first kind T(n) at x.
+func chebyshev(n int, x float64) float64 {
+	switch {
+	case x > 1:
+		return math.Cosh(float64(n) * math.Acosh(x))
+	case x < -1:
+		return math.Pow(-1, float64(n)) * math.Cosh(float64(n)*math.Acosh(-x))
+	}
+	return math.Cos(float64(n) * math.Acos(x))
+}
+
+// Build adds the array's elements to the structure: the first element, then GM
+// cards copying it along X and the resulting row along Y. It must be called
+// before GeometryComplete(), on a structure with nothing else in it yet.
+func (a *Array) Build(n Engine) error {
+	if err := a.check(); err != nil {
+		return err
+	}
+	if err := a.Element(n); err != nil {
+		return err
+	}
+	a.tagInc = 0
+	for _, w := range n.Geometry().Wires {
+		if w.Tag > a.tagInc {
+			a.tagInc = w.Tag
+		}
+	}
+	if a.tagInc == 0 {
+		return errors.New("the array's element has no tagged wires")
+	}
+	if a.NX > 1 {
+		if err := n.GmCard(a.tagInc, a.NX-1, 0, 0, 0, a.DX, 0, 0, 0); err != nil {
+			return err
+		}
+	}
+	if a.NY > 1 {
+		if err := n.GmCard(a.tagInc*a.NX, a.NY-1, 0, 0, 0, 0, a.DY, 0, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Port returns the feed point of element i. It's only valid after Build().
+func (a *Array) Port(i int) Port {
+	return Port{Tag: a.Feed.Tag + i*a.tagInc, Segment: a.Feed.Segment}
+}
+
+// excite puts the array's voltage sources on, with first's source before the
+// rest, so it's the one whose impedance libnecpp reports.
+func (a *Array) excite(n Engine, w []complex128, first int) error {
+	if err := n.ExcitationVoltage(a.Port(first).Tag, a.Feed.Segment, w[first]); err != nil {
+		return err
+	}
+	for i := range w {
+		if i == first {
+			continue
+		}
+		if err := n.ExcitationVoltage(a.Port(i).Tag, a.Feed.Segment, w[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run builds and runs the array at its design frequency, returning the steered
+// radiation pattern over the whole sphere and each element's active
+// impedance. finish is called after Build() to complete the model: it should
+// call GeometryComplete() and set up any ground, but leave out the FR card and
+// the excitation, which Run() takes care of.
+//
+// libnecpp only reports the impedance at the first source, so the model is run
+// once per element, with that element's source put on first each time. Each run
+// is on a new engine from newEngine, which can be nil for libnecpp.
+func (a *Array) Run(newEngine EngineFactory, finish BuildFunc) (*ArrayResult, error) {
+	w, err := a.Weights()
+	if err != nil {
+		return nil, err
+	}
+	step := a.PatternStep
+	if step <= 0 {
+		step = 5
+	}
+	res := &ArrayResult{
+		FreqMHz:         a.FreqMHz,
+		Weights:         w,
+		Ports:           make([]Port, len(w)),
+		ActiveImpedance: make([]complex128, len(w)),
+	}
+	for i := range w {
+		n, err := newEngine.engine()
+		if err != nil {
+			return nil, err
+		}
+		err = a.runElement(n, finish, w, i, step, res)
+		n.Delete()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (a *Array) runElement(n Engine, finish BuildFunc, w []complex128, i int, step float64, res *ArrayResult) error {
+	if err := a.Build(n); err != nil {
+		return err
+	}
+	if err := finish(n); err != nil {
+		return err
+	}
+	if err := n.FrCard(Linear, 1, a.FreqMHz, 0); err != nil {
+		return err
+	}
+	if err := a.excite(n, w, i); err != nil {
+		return err
+	}
+	if i == 0 {
+		nTheta := int(math.Round(180/step)) + 1
+		nPhi := int(math.Round(360 / step))
+		if err := n.RpCard(Normal, nTheta, nPhi, MajorMinor, NoNormalization, PowerGain, NoAvg, 0, 0, step, step, 0, 0); err != nil {
+			return err
+		}
+		p, err := n.RadiationPattern(0)
+		if err != nil {
+			return err
+		}
+		res.Pattern = p
+	} else if err := n.XqCard(NoPattern); err != nil {
+		return err
+	}
+	z, err := n.Impedance(0)
+	if err != nil {
+		return err
+	}
+	res.Ports[i] = a.Port(i)
+	res.ActiveImpedance[i] = z
+	return nil
+}
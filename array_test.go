@@ -0,0 +1,109 @@
+package necpp
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestTaper(t *testing.T) {
+	w, err := taper(Binomial, 4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []float64{1, 3, 3, 1} {
+		if w[i] != want {
+			t.Errorf("binomial weights were %v, should have been [1 3 3 1]", w)
+			break
+		}
+	}
+
+	// the sidelobes of a Dolph-Chebyshev array should all be 25 dB down
+	for _, n := range []int{5, 8} {
+		w, err = taper(DolphChebyshev, n, 25)
+		if err != nil {
+			t.Fatal(err)
+		}
+		af := func(psi float64) float64 {
+			var s complex128
+			for i, a := range w {
+				s += complex(a, 0) * cmplx.Exp(complex(0, psi*float64(i)))
+			}
+			return cmplx.Abs(s)
+		}
+		peak := af(0)
+		worst := 0.0
+		// well past the first null
+		for psi := 2.5 * math.Pi / float64(n) * 2; psi <= math.Pi; psi += 0.001 {
+			worst = math.Max(worst, af(psi))
+		}
+		if sl := 20 * math.Log10(worst/peak); math.Abs(sl+25) > 0.1 {
+			t.Errorf("%d element sidelobes were %.2f dB, should have been -25 dB", n, sl)
+		}
+	}
+}
+
+func TestArrayWeights(t *testing.T) {
+	// a line of quarter wave spaced elements steered along +X needs a 90
+	// degree lag per element
+	a := &Array{Element: func(n Engine) error { return nil }, Feed: Port{Tag: 1, Segment: 1}, NX: 3, NY: 1, DX: 0.25 * SpeedOfLight / 10, FreqMHz: 10, SteerTheta: 90}
+	w, err := a.Weights()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range w {
+		want := -90 * float64(i)
+		got := cmplx.Phase(c) * 180 / math.Pi
+		if math.Abs(math.Mod(got-want+540, 360)-180) > 1e-6 || math.Abs(cmplx.Abs(c)-1) > 1e-9 {
+			t.Errorf("element %d weight was %v (%.1f degrees), should have had a phase of %g", i, c, got, want)
+		}
+	}
+}
+
+func TestArrayRun(t *testing.T) {
+	a := &Array{
+		Element: func(n Engine) error {
+			return n.Wire(1, 11, 0, 0, -2.5, 0, 0, 2.5, 0.001, 1, 1)
+		},
+		Feed:        Port{Tag: 1, Segment: 6},
+		NX:          2,
+		NY:          2,
+		DX:          5,
+		DY:          5,
+		FreqMHz:     30,
+		PatternStep: 10,
+	}
+	var made []*FakeEngine
+	newEngine := func() (Engine, error) {
+		f := &FakeEngine{ImpedanceFunc: func(freqMHz float64) complex128 { return complex(freqMHz, 0) }}
+		made = append(made, f)
+		return f, nil
+	}
+	finish := func(n Engine) error { return n.GeometryComplete(NoGroundPlane) }
+	res, err := a.Run(newEngine, finish)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(made) != 4 {
+		t.Fatalf("made %d engines, should have been one for each element", len(made))
+	}
+	if res.Pattern == nil || res.Pattern.NTheta != 19 || res.Pattern.NPhi != 36 {
+		t.Errorf("the pattern wasn't taken at a 10 degree step: %+v", res.Pattern)
+	}
+	for i, f := range made {
+		// the element's own source goes on first
+		var first *Call
+		for j := range f.Calls {
+			if f.Calls[j].Method == "ExcitationVoltage" {
+				first = &f.Calls[j]
+				break
+			}
+		}
+		if want := a.Port(i).Tag; first == nil || first.Args[0] != want {
+			t.Errorf("run %d put the first source on %v, should have been tag %d", i, first, want)
+		}
+		if res.ActiveImpedance[i] != 30 {
+			t.Errorf("element %d active impedance was %v", i, res.ActiveImpedance[i])
+		}
+	}
+}
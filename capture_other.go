@@ -0,0 +1,14 @@
+//go:build windows || !cgo
+// +build windows !cgo
+
+package necpp
+
+import "io"
+
+// captureOutput just runs f on Windows, where libnecpp's printed output can't
+// be captured yet, or without cgo, where there's nothing to capture. Anything
+// that relies on the printed output will find it empty, and the output goes to
+// standard output whatever tee is.
+func captureOutput(f func() error, tee io.Writer) (string, error) {
+	return "", f()
+}
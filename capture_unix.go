@@ -0,0 +1,86 @@
+//go:build !windows && cgo
+// +build !windows,cgo
+
+package necpp
+
+/*
+#include <stdio.h>
+#include <unistd.h>
+
+static void flush_stdout(void) { fflush(stdout); }
+*/
+import "C"
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// captureMu serializes captures, since standard output belongs to the whole
+// process.
+var captureMu sync.Mutex
+
+// captureOutput runs f with the process's standard output redirected into a
+// pipe, and returns everything that was written to it. libnecpp prints its
+// results from C++, so this has to be done at the file descriptor level rather
+// than by swapping os.Stdout. The output is passed on to tee, or to the real
+// standard output if tee is nil, so nothing goes missing unless it's meant to.
+//
+// Anything else the process writes to standard output while f runs, from any
+// goroutine, is caught as well.
+func captureOutput(f func() error, tee io.Writer) (string, error) {
+	captureMu.Lock()
+	defer captureMu.Unlock()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return "", err
+	}
+	C.flush_stdout()
+	saved := C.dup(1)
+	if saved < 0 {
+		r.Close()
+		w.Close()
+		return "", f()
+	}
+	C.dup2(C.int(w.Fd()), 1)
+
+	// the copy of the real standard output gets its own descriptor, so
+	// closing it doesn't close saved
+	var orig *os.File
+	if tee == nil {
+		orig = os.NewFile(uintptr(C.dup(saved)), "stdout")
+		tee = orig
+	}
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		// keep draining the pipe even if tee fails, so libnecpp never
+		// blocks writing to it
+		chunk := make([]byte, 4096)
+		for {
+			k, err := r.Read(chunk)
+			buf.Write(chunk[:k])
+			tee.Write(chunk[:k])
+			if err != nil {
+				break
+			}
+		}
+		close(done)
+	}()
+
+	ferr := f()
+
+	C.flush_stdout()
+	C.dup2(saved, 1)
+	w.Close()
+	<-done
+	r.Close()
+	if orig != nil {
+		orig.Close()
+	}
+	C.close(saved)
+	return buf.String(), ferr
+}
@@ -0,0 +1,99 @@
+//go:build !windows && cgo
+// +build !windows,cgo
+
+package necpp
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCaptureOutput(t *testing.T) {
+	var tee bytes.Buffer
+	out, err := captureOutput(func() error {
+		fmt.Println("STRUCTURE SPECIFICATION")
+		return nil
+	}, &tee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "STRUCTURE SPECIFICATION\n" {
+		t.Errorf("captured %q", out)
+	}
+	if tee.String() != out {
+		t.Errorf("passed on %q, should have been %q", tee.String(), out)
+	}
+
+	// captures from several goroutines at once mustn't get mixed up
+	var wg sync.WaitGroup
+	errs := make(chan string, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			want := fmt.Sprintf("run %d\n", i)
+			out, _ := captureOutput(func() error {
+				fmt.Print(want)
+				return nil
+			}, ioutil.Discard)
+			if out != want {
+				errs <- fmt.Sprintf("captured %q, should have been %q", out, want)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		t.Error(e)
+	}
+}
+
+func TestUncapturedRunsConcurrently(t *testing.T) {
+	// with the capture lock held, contexts that never had SetOutput() called
+	// can still solve, side by side
+	captureMu.Lock()
+	defer captureMu.Unlock()
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			n, err := New()
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer n.Delete()
+			if err = n.Wire(1, 9, 0, 0, 0, 0, 0, 5, 0.001, 1, 1); err == nil {
+				err = n.GeometryComplete(NoGroundPlane)
+			}
+			if err == nil {
+				err = n.FrCard(Linear, 1, 28, 0)
+			}
+			if err == nil {
+				err = n.ExcitationVoltage(1, 5, 1)
+			}
+			if err == nil {
+				err = n.XqCard(NoPattern)
+			}
+			if err == nil {
+				_, err = n.Impedance(0)
+			}
+			errs <- err
+		}()
+	}
+	timeout := time.After(30 * time.Second)
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Error(err)
+			}
+		case <-timeout:
+			t.Fatal("the contexts were held up waiting to capture their output")
+		}
+	}
+}
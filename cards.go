@@ -0,0 +1,233 @@
+package necpp
+
+import "errors"
+
+// GainErrno is the number returned by the Gain* functions when no radiation
+// pattern was previously requested.
+const GainErrno float64 = -999.0
+
+var ErrNoPatternRequested = errors.New("no radiation pattern previously requested")
+
+// PatchType is the shape of a patch for the Surface Patch (SP Card).
+type PatchType int
+
+const (
+	Arbitrary PatchType = iota // an arbitrary patch shape (the default)
+	Rectangular
+	Triangular
+	Quadrilateral
+)
+
+// GeoGroundPlaneFlag is used to indicate the type of ground plane to use with
+// the antenna when indicating the geometry is complete.
+//
+// The types of ground plane to use are:
+//
+// • NoGroundPlane - no ground plane is present. (Fairly self-explanatory.)
+//
+// • CurrentExpansionModified - Structure symmetry is modified as required, and
+// the current expansion is modified so that the currents and segments touching
+// the ground (x, Y plane) are interpolated to their images below the ground
+// (charge at base is zero)
+//
+// • CurrentExpansionUnmodified - indicates a ground is present. Structure
+// symmetry is modified as required. Current expansion, however, is not
+// modified, Thus, currents on segments touching the ground will go to zero at
+// the ground.
+type GeoGroundPlaneFlag int
+
+const (
+	CurrentExpansionUnmodified GeoGroundPlaneFlag = iota - 1
+	NoGroundPlane
+	CurrentExpansionModified
+	MooMoo
+)
+
+// GroundTypeFlag indicates the general type of ground for the antenna.
+//
+// The flags for ground types are:
+//
+// • Nullified - Nullifies ground parameters previously used and sets free-space
+// condition. The remainder of the parameters are ignored in this case.
+//
+// • Finite - Finite ground, reflection coefficient approximation.
+//
+// • Perfect - Perfectly conducting ground.
+//
+// • FiniteSomNorton - Finite ground, Sommerfeld/Norton method.
+type GroundTypeFlag int
+
+const (
+	Nullified GroundTypeFlag = iota - 1
+	Finite
+	Perfect
+	FiniteSomNorton
+)
+
+// FrequencyRange is used to set the type of frequency range for FR cards.
+type FrequencyRange int
+
+const (
+	Linear      FrequencyRange = iota // a linear range
+	Logarithmic                       // a logarithmic range
+)
+
+// WireKernel sets the type of wire kernel to use with EkCard
+//
+// • ReturnToNormal - Return to normal kernel
+//
+// • ExtendedThinWire - Use extended thin wire kernel
+type WireKernel int
+
+const (
+	ReturnToNormal WireKernel = iota - 1
+	ExtendedThinWire
+)
+
+// Excitation sets the type of excitation for ExCard
+type Excitation int
+
+const (
+	VoltageApplied    Excitation = iota // voltage source (applied-E-field source)
+	IncidentLinear                      // incident plane wave, linear polarization.
+	IncidentRightHand                   // incident plane wave, right-hand (thumb along the incident k vector) elliptic polarization.
+	IncidentLeftHand                    // incident plane wave, left-hand elliptic polarization.
+	Elementary                          // elementary current source
+	VoltageSlope                        // voltage source (current-slope-discontinuity)
+)
+
+// ExecutionOption control the generation of radiation patterns with XqCard()
+//
+// Options for radiation patterns:
+//
+// • NoPattern - no patterns requested (the normal case).
+//
+// • XZPlane - generates a pattern cut in the XZ plane, i.e., phi = 0 degrees
+// and theta varies from 0 degrees to 90 degrees in 1 degree steps.
+//
+// • YZPlane - generates a pattern cut in the YZ plane, i.e., phi = 90 degrees
+// theta varies from 0 degrees to 90 degrees in 1 degree steps.
+//
+// • BothPlane - generates both of the cuts described for XZPlane and YZPlane.
+type ExecutionOption int
+
+const (
+	NoPattern ExecutionOption = iota
+	XZPlane
+	YZPlane
+	BothPlane
+)
+
+// wow, there are a lot of flags for RP cards. :-/
+
+// RpCalcMode is used to select radiation patterns for RP cards.
+//
+// RP card calculation flags:
+//
+// • Normal - normal mode. Space-wave fields are computed. An infinite ground
+// plane is included if it has been specified previously on a GN card;
+// otherwise, the antenna is in free space.
+//
+// • SurfaceWave - surface wave propagating along ground is added to the normal
+// space wave. This option changes the meaning of some of the other parameters
+// on the RP card as explained below, and the results appear in a special output
+// format. Ground parameters must have been input on a GN card. The following
+// options cause calculation of only the space wave but with special ground
+// conditions. Ground conditions include a two-medium ground (cliff where the
+// media join in a circle or a line), and a radial wire ground screen. Ground
+// parameters and dimensions must be input on a GN or GD card before the RP card
+// is read. The RP card only selects the option for inclusion in the field
+// calculation. (Refer to the GN and GD cards for further explanation.)
+//
+// • LinearCliff - linear cliff with antenna above upper level. Lower medium
+// parameters are as specified for the second medium on the GN card or on the
+// GD card.
+//
+// • CircularCliff - circular cliff centered at origin of coordinate system:
+// with antenna above upper level. Lower medium parameters are as specified for
+// the second medium on the GN card or on the GD card.
+//
+// • RadialScreen - radial wire ground screen centered at origin.
+//
+// • RadialLinearCliff - both radial wire ground screen and linear cliff.
+//
+// • RadialCircularCliff - both radial wire ground screen ant circular cliff.
+type RpCalcMode int
+
+const (
+	Normal RpCalcMode = iota
+	SurfaceWave
+	LinearCliff
+	CircularCliff
+	RadialScreen
+	RadialLinearCliff
+	RadialCircularCliff
+)
+
+// RpOutputFormat is used to select the output format for RP cards.
+//
+// • MajorMinor - major axis, minor axis and total gain printed.
+//
+// • VerticalHorizontal - vertical, horizontal ant total gain printed.
+type RpOutputFormat int
+
+const (
+	MajorMinor RpOutputFormat = iota
+	VerticalHorizontal
+)
+
+// RpNormalization is used to select the normalization type for RP cards.
+//
+// Radiation pattern normalization flags:
+//
+// • NoNormalization - no normalized gain.
+//
+// • MajorAxisNorm - major axis gain normalized.
+//
+// • MinorAxisNorm - minor axis gain normalized.
+//
+// • VerticalAxisNorm - vertical axis gain normalized.
+//
+// • HorizontalAxisNorm - horizontal axis gain normalized.
+//
+// • TotalNormalized - total gain normalized.
+type RpNormalization int
+
+const (
+	NoNormalization RpNormalization = iota
+	MajorAxisNorm
+	MinorAxisNorm
+	VerticalAxisNorm
+	HorizontalAxisNorm
+	TotalNormalized
+)
+
+// RpGain is used to select the type of gain for RP cards for standard printing
+// and normalization constants. These ones have self explanatory names.
+type RpGain int
+
+const (
+	PowerGain RpGain = iota
+	DirectiveGain
+)
+
+// RpAveraging is used to select the type of averaging for RP cards to set the
+// calculation of average power gain over the region covered by field points.
+//
+// RpAveraging flags:
+//
+// • NoAvg - no averaging
+//
+// • AvgGain - average gain computed.
+//
+// • AvgGainPrtSuppressed - average gain computed, printing of gain at the field
+// points used for averaging is suppressed. If nTheta or NPH is equal to one,
+// average gain will not be computed for any value of A since the area of the
+// region covered by field points vanishes.
+type RpAveraging int
+
+const (
+	NoAvg RpAveraging = iota
+	AvgGain
+	AvgGainPrtSuppressed
+)
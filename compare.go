@@ -0,0 +1,163 @@
+package necpp
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+// ReflectionCoefficient returns the voltage reflection coefficient of the
+// impedance z against the real reference impedance z0.
+func ReflectionCoefficient(z complex128, z0 float64) complex128 {
+	return (z - complex(z0, 0)) / (z + complex(z0, 0))
+}
+
+// VSWR returns the voltage standing wave ratio of the impedance z on a line
+// with the real characteristic impedance z0. A complete mismatch gives
+// +Inf. That includes any z with no resistance, which rounding would otherwise
+// turn into a huge but finite VSWR.
+func VSWR(z complex128, z0 float64) float64 {
+	g := cmplx.Abs(ReflectionCoefficient(z, z0))
+	if g >= 1 || real(z) <= 0 {
+		return math.Inf(1)
+	}
+	return (1 + g) / (1 - g)
+}
+
+// ComparisonPoint compares measured and simulated impedance at one of the
+// measured frequencies.
+type ComparisonPoint struct {
+	FreqMHz        float64
+	Measured       complex128
+	Simulated      complex128 // interpolated to the measured frequency
+	Error          complex128 // Simulated - Measured
+	ErrorMagnitude float64    // |Simulated - Measured| in ohms
+	MeasuredVSWR   float64
+	SimulatedVSWR  float64
+	VSWRDifference float64 // SimulatedVSWR - MeasuredVSWR; zero if both are +Inf
+}
+
+// Comparison is the report produced by CompareImpedance().
+type Comparison struct {
+	Z0     float64
+	Points []ComparisonPoint
+
+	// The resonant frequency of each sweep, in MHz, as Resonance() finds it:
+	// where the reactance crosses zero, or where the VSWR is lowest if it
+	// never does. Both sweeps have points, so there's always one.
+	MeasuredResonanceMHz  float64
+	SimulatedResonanceMHz float64
+	ResonanceShiftMHz     float64 // SimulatedResonanceMHz - MeasuredResonanceMHz
+
+	MaxErrorMagnitude  float64
+	RMSErrorMagnitude  float64
+	MaxVSWRDifference  float64 // largest absolute VSWR difference
+	MeanVSWRDifference float64 // mean absolute VSWR difference
+}
+
+// CompareImpedance compares a measured impedance sweep, such as one read in with
+// ReadTouchstoneFile(), against a simulated one from NecppCtx.ImpedanceSweep().
+// The simulated impedance is linearly interpolated to each measured frequency;
+// measured points outside the range of the simulated sweep are left out of
+// the per-frequency comparison. VSWRs are calculated against z0.
+func CompareImpedance(measured []ImpedancePoint, simulated []ImpedancePoint, z0 float64) (*Comparison, error) {
+	if len(measured) == 0 || len(simulated) == 0 {
+		return nil, errors.New("both sweeps must have at least one point")
+	}
+	meas := sortedSweep(measured)
+	sim := sortedSweep(simulated)
+
+	c := &Comparison{Z0: z0}
+	var sumSq, sumVSWR float64
+	for _, m := range meas {
+		s, ok := interpolateImpedance(sim, m.FreqMHz)
+		if !ok {
+			continue
+		}
+		p := ComparisonPoint{
+			FreqMHz:       m.FreqMHz,
+			Measured:      m.Impedance,
+			Simulated:     s,
+			Error:         s - m.Impedance,
+			MeasuredVSWR:  VSWR(m.Impedance, z0),
+			SimulatedVSWR: VSWR(s, z0),
+		}
+		p.ErrorMagnitude = cmplx.Abs(p.Error)
+		if !math.IsInf(p.SimulatedVSWR, 1) || !math.IsInf(p.MeasuredVSWR, 1) {
+			// two complete mismatches don't differ, and Inf - Inf would
+			// make the whole report NaN
+			p.VSWRDifference = p.SimulatedVSWR - p.MeasuredVSWR
+		}
+		c.Points = append(c.Points, p)
+
+		sumSq += p.ErrorMagnitude * p.ErrorMagnitude
+		sumVSWR += math.Abs(p.VSWRDifference)
+		c.MaxErrorMagnitude = math.Max(c.MaxErrorMagnitude, p.ErrorMagnitude)
+		c.MaxVSWRDifference = math.Max(c.MaxVSWRDifference, math.Abs(p.VSWRDifference))
+	}
+	if len(c.Points) == 0 {
+		return nil, errors.New("the measured and simulated sweeps don't overlap")
+	}
+	c.RMSErrorMagnitude = math.Sqrt(sumSq / float64(len(c.Points)))
+	c.MeanVSWRDifference = sumVSWR / float64(len(c.Points))
+
+	c.MeasuredResonanceMHz = Resonance(meas, z0)
+	c.SimulatedResonanceMHz = Resonance(sim, z0)
+	c.ResonanceShiftMHz = c.SimulatedResonanceMHz - c.MeasuredResonanceMHz
+	return c, nil
+}
+
+// Resonance finds the resonant frequency of an impedance sweep, in MHz. This
+// is the first frequency where the reactance crosses zero, linearly
+// interpolated between sweep points. If the reactance never crosses zero, the
+// frequency with the lowest VSWR against z0 is returned instead. An empty
+// sweep returns zero.
+func Resonance(sweep []ImpedancePoint, z0 float64) float64 {
+	if len(sweep) == 0 {
+		return 0
+	}
+	s := sortedSweep(sweep)
+	for i := 1; i < len(s); i++ {
+		x0, x1 := imag(s[i-1].Impedance), imag(s[i].Impedance)
+		if x0 == 0 {
+			return s[i-1].FreqMHz
+		}
+		if (x0 < 0) != (x1 < 0) || x1 == 0 {
+			f0, f1 := s[i-1].FreqMHz, s[i].FreqMHz
+			return f0 + (f1-f0)*(-x0)/(x1-x0)
+		}
+	}
+	best := s[0]
+	for _, p := range s[1:] {
+		if VSWR(p.Impedance, z0) < VSWR(best.Impedance, z0) {
+			best = p
+		}
+	}
+	return best.FreqMHz
+}
+
+func sortedSweep(sweep []ImpedancePoint) []ImpedancePoint {
+	s := make([]ImpedancePoint, len(sweep))
+	copy(s, sweep)
+	sort.Slice(s, func(i, j int) bool { return s[i].FreqMHz < s[j].FreqMHz })
+	return s
+}
+
+// interpolateImpedance linearly interpolates a sorted sweep at freq. It
+// returns false if freq is outside of the sweep.
+func interpolateImpedance(sweep []ImpedancePoint, freq float64) (complex128, bool) {
+	i := sort.Search(len(sweep), func(i int) bool { return sweep[i].FreqMHz >= freq })
+	if i == len(sweep) {
+		return 0, false
+	}
+	if sweep[i].FreqMHz == freq {
+		return sweep[i].Impedance, true
+	}
+	if i == 0 {
+		return 0, false
+	}
+	a, b := sweep[i-1], sweep[i]
+	t := (freq - a.FreqMHz) / (b.FreqMHz - a.FreqMHz)
+	return a.Impedance + complex(t, 0)*(b.Impedance-a.Impedance), true
+}
@@ -0,0 +1,80 @@
+package necpp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCompareImpedance(t *testing.T) {
+	measured := []ImpedancePoint{
+		{FreqMHz: 13.9, Impedance: complex(45, -20)},
+		{FreqMHz: 14.0, Impedance: complex(50, 0)},
+		{FreqMHz: 14.1, Impedance: complex(55, 20)},
+	}
+	// the same curve, 100 kHz higher
+	simulated := []ImpedancePoint{
+		{FreqMHz: 13.8, Impedance: complex(40, -40)},
+		{FreqMHz: 14.0, Impedance: complex(45, -20)},
+		{FreqMHz: 14.2, Impedance: complex(55, 20)},
+	}
+	c, err := CompareImpedance(measured, simulated, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Points) != 3 {
+		t.Fatalf("expected 3 compared points, got %d", len(c.Points))
+	}
+	if math.Abs(c.MeasuredResonanceMHz-14.0) > 1e-9 {
+		t.Errorf("measured resonance was %g, should have been 14", c.MeasuredResonanceMHz)
+	}
+	if math.Abs(c.ResonanceShiftMHz-0.1) > 1e-9 {
+		t.Errorf("resonance shift was %g, should have been 0.1", c.ResonanceShiftMHz)
+	}
+	if c.Points[1].MeasuredVSWR != 1 {
+		t.Errorf("VSWR of a matched load was %g", c.Points[1].MeasuredVSWR)
+	}
+}
+
+func TestVSWR(t *testing.T) {
+	if v := VSWR(complex(100, 0), 50); math.Abs(v-2) > 1e-9 {
+		t.Errorf("VSWR of 100 ohms on a 50 ohm line was %g, should have been 2", v)
+	}
+	for _, z := range []complex128{0, complex(0, 30), complex(0, -200), complex(-5, 10)} {
+		if v := VSWR(z, 50); !math.IsInf(v, 1) {
+			t.Errorf("VSWR of %v was %g, should have been +Inf", z, v)
+		}
+	}
+}
+
+func TestCompareImpedanceNoResistance(t *testing.T) {
+	// the first point is a pure reactance in both sweeps
+	measured := []ImpedancePoint{{FreqMHz: 14.0, Impedance: complex(0, -30)}, {FreqMHz: 14.1, Impedance: complex(50, 0)}}
+	simulated := []ImpedancePoint{{FreqMHz: 14.0, Impedance: complex(0, -30)}, {FreqMHz: 14.1, Impedance: complex(100, 0)}}
+	c, err := CompareImpedance(measured, simulated, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := c.Points[0].VSWRDifference; d != 0 {
+		t.Errorf("two infinite VSWRs differed by %g, should have been 0", d)
+	}
+	if math.Abs(c.MaxVSWRDifference-1) > 1e-9 || math.Abs(c.MeanVSWRDifference-0.5) > 1e-9 {
+		t.Errorf("max and mean VSWR differences were %g and %g, should have been 1 and 0.5", c.MaxVSWRDifference, c.MeanVSWRDifference)
+	}
+
+	// only one of them a pure reactance: infinitely different, but not NaN
+	simulated[0].Impedance = complex(50, -30)
+	if c, err = CompareImpedance(measured, simulated, 50); err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(c.MaxVSWRDifference, 1) || !math.IsInf(c.MeanVSWRDifference, 1) {
+		t.Errorf("max and mean VSWR differences were %g and %g, should have been +Inf", c.MaxVSWRDifference, c.MeanVSWRDifference)
+	}
+}
+
+func TestCompareImpedanceNoOverlap(t *testing.T) {
+	measured := []ImpedancePoint{{FreqMHz: 7.0, Impedance: 50}}
+	simulated := []ImpedancePoint{{FreqMHz: 14.0, Impedance: 50}, {FreqMHz: 14.2, Impedance: 50}}
+	if _, err := CompareImpedance(measured, simulated, 50); err == nil {
+		t.Errorf("comparing sweeps that don't overlap should have failed")
+	}
+}
@@ -0,0 +1,225 @@
+package necpp
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CrashError is returned by Worker.Run() when the worker process dies without
+// finishing the model, which is what happens when libnecpp aborts or segfaults
+// on a bad geometry. The program that ran the worker carries on unharmed.
+//
+// • ExitCode - the worker's exit code, or -1 if it was killed by a signal.
+//
+// • Status - how the worker ended, e.g. "signal: segmentation fault".
+//
+// • Card - the card the worker was running when it died, by its method name
+// (e.g. "RpCard"), or empty if it died before the first card.
+//
+// • CardIndex - the position of that card in the model's deck, counting from
+// zero.
+//
+// • Stderr - the end of what the worker wrote to standard error, which is
+// where libnecpp's abort messages and Go's panics go.
+type CrashError struct {
+	ExitCode  int
+	Status    string
+	Card      string
+	CardIndex int
+	Stderr    string
+}
+
+func (e *CrashError) Error() string {
+	msg := "the worker process crashed (" + e.Status + ")"
+	if e.Card != "" {
+		msg += fmt.Sprintf(" running card %d, %s", e.CardIndex+1, e.Card)
+	}
+	if s := strings.TrimSpace(e.Stderr); s != "" {
+		lines := strings.Split(s, "\n")
+		msg += ": " + lines[len(lines)-1]
+	}
+	return msg
+}
+
+// stderrLimit is how much of the end of a worker's standard error is kept.
+const stderrLimit = 8192
+
+// tailWriter keeps the last limit bytes written to it.
+type tailWriter struct {
+	limit int
+	buf   []byte
+}
+
+func (t *tailWriter) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > 2*t.limit {
+		t.buf = append([]byte(nil), t.buf[len(t.buf)-t.limit:]...)
+	}
+	return len(p), nil
+}
+
+func (t *tailWriter) String() string {
+	if len(t.buf) > t.limit {
+		return string(t.buf[len(t.buf)-t.limit:])
+	}
+	return string(t.buf)
+}
+
+// tracer is an Engine that writes the name of each card to a log before it's
+// run, so that if the process dies partway through, the card it died on is
+// known. The log should be unbuffered, so the names are out of the process as
+// soon as they're written.
+type tracer struct {
+	Engine
+	log io.Writer
+}
+
+func (t *tracer) card(method string) {
+	io.WriteString(t.log, method+"\n")
+}
+
+func (t *tracer) Wire(tagId int, segmentCount int, xw1 float64, yw1 float64, zw1 float64, xw2 float64, yw2 float64, zw2 float64, rad float64, rdel float64, rrad float64) error {
+	t.card("Wire")
+	return t.Engine.Wire(tagId, segmentCount, xw1, yw1, zw1, xw2, yw2, zw2, rad, rdel, rrad)
+}
+
+func (t *tracer) SpCard(ns PatchType, x1 float64, y1 float64, z1 float64, x2 float64, y2 float64, z2 float64) error {
+	t.card("SpCard")
+	return t.Engine.SpCard(ns, x1, y1, z1, x2, y2, z2)
+}
+
+func (t *tracer) ScCard(i2 int, x3 float64, y3 float64, z3 float64, x4 float64, y4 float64, z4 float64) error {
+	t.card("ScCard")
+	return t.Engine.ScCard(i2, x3, y3, z3, x4, y4, z4)
+}
+
+func (t *tracer) GmCard(itsi int, nrpt int, rox float64, roy float64, roz float64, xs float64, ys float64, zs float64, its int) error {
+	t.card("GmCard")
+	return t.Engine.GmCard(itsi, nrpt, rox, roy, roz, xs, ys, zs, its)
+}
+
+func (t *tracer) GxCard(i1 int, i2 int) error {
+	t.card("GxCard")
+	return t.Engine.GxCard(i1, i2)
+}
+
+func (t *tracer) GeometryComplete(gpflag GeoGroundPlaneFlag) error {
+	t.card("GeometryComplete")
+	return t.Engine.GeometryComplete(gpflag)
+}
+
+func (t *tracer) MediumParameters(permittivity float64, permeability float64) error {
+	t.card("MediumParameters")
+	return t.Engine.MediumParameters(permittivity, permeability)
+}
+
+func (t *tracer) GnCard(iperf GroundTypeFlag, nradl int, epse float64, sig float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	t.card("GnCard")
+	return t.Engine.GnCard(iperf, nradl, epse, sig, tmp3, tmp4, tmp5, tmp6)
+}
+
+func (t *tracer) GdCard(tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64) error {
+	t.card("GdCard")
+	return t.Engine.GdCard(tmp1, tmp2, tmp3, tmp4)
+}
+
+// SetGround goes through the tracer's own GnCard() and GdCard().
+func (t *tracer) SetGround(g Ground) error {
+	return setGround(t, g)
+}
+
+func (t *tracer) FrCard(inIfrq FrequencyRange, inNfrq int, inFreqMhz float64, inDelFreq float64) error {
+	t.card("FrCard")
+	return t.Engine.FrCard(inIfrq, inNfrq, inFreqMhz, inDelFreq)
+}
+
+func (t *tracer) EkCard(itmp1 WireKernel) error {
+	t.card("EkCard")
+	return t.Engine.EkCard(itmp1)
+}
+
+func (t *tracer) LdCard(ldtype int, ldtag int, ldtagf int, ldtagt int, tmp1 float64, tmp2 float64, tmp3 float64) error {
+	t.card("LdCard")
+	return t.Engine.LdCard(ldtype, ldtag, ldtagf, ldtagt, tmp1, tmp2, tmp3)
+}
+
+func (t *tracer) ExCard(extype Excitation, i2 int, i3 int, i4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	t.card("ExCard")
+	return t.Engine.ExCard(extype, i2, i3, i4, tmp1, tmp2, tmp3, tmp4, tmp5, tmp6)
+}
+
+func (t *tracer) ExcitationVoltage(tag int, segment int, voltageExcitation complex128) error {
+	t.card("ExcitationVoltage")
+	return t.Engine.ExcitationVoltage(tag, segment, voltageExcitation)
+}
+
+func (t *tracer) ExcitationCurrent(x float64, y float64, z float64, a float64, beta float64, moment float64) error {
+	t.card("ExcitationCurrent")
+	return t.Engine.ExcitationCurrent(x, y, z, a, beta, moment)
+}
+
+func (t *tracer) ExcitationPlanewave(nTheta int, nPhi int, theta float64, phi float64, eta float64, dTheta float64, dPhi float64, polRatio float64) error {
+	t.card("ExcitationPlanewave")
+	return t.Engine.ExcitationPlanewave(nTheta, nPhi, theta, phi, eta, dTheta, dPhi, polRatio)
+}
+
+func (t *tracer) TlCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	t.card("TlCard")
+	return t.Engine.TlCard(itmp1, itmp2, itmp3, itmp4, tmp1, tmp2, tmp3, tmp4, tmp5, tmp6)
+}
+
+func (t *tracer) NtCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	t.card("NtCard")
+	return t.Engine.NtCard(itmp1, itmp2, itmp3, itmp4, tmp1, tmp2, tmp3, tmp4, tmp5, tmp6)
+}
+
+// ConnectTwoPort goes through the tracer's own NtCard().
+func (t *tracer) ConnectTwoPort(p1 Port, p2 Port, tp TwoPort) error {
+	return connectTwoPort(t, p1, p2, tp)
+}
+
+func (t *tracer) XqCard(itmp1 ExecutionOption) error {
+	t.card("XqCard")
+	return t.Engine.XqCard(itmp1)
+}
+
+func (t *tracer) RpCard(calcMode RpCalcMode, nTheta int, nPhi int, outputFormat RpOutputFormat, normalization RpNormalization, d RpGain, a RpAveraging, theta0 float64, phi0 float64, deltaTheta float64, deltaPhi float64, radialDistance float64, gainNorm float64) error {
+	t.card("RpCard")
+	return t.Engine.RpCard(calcMode, nTheta, nPhi, outputFormat, normalization, d, a, theta0, phi0, deltaTheta, deltaPhi, radialDistance, gainNorm)
+}
+
+func (t *tracer) PtCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	t.card("PtCard")
+	return t.Engine.PtCard(itmp1, itmp2, itmp3, itmp4)
+}
+
+func (t *tracer) PqCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	t.card("PqCard")
+	return t.Engine.PqCard(itmp1, itmp2, itmp3, itmp4)
+}
+
+func (t *tracer) KhCard(tmp1 float64) error {
+	t.card("KhCard")
+	return t.Engine.KhCard(tmp1)
+}
+
+func (t *tracer) NeCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	t.card("NeCard")
+	return t.Engine.NeCard(itmp1, itmp2, itmp3, itmp4, tmp1, tmp2, tmp3, tmp4, tmp5, tmp6)
+}
+
+func (t *tracer) NhCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	t.card("NhCard")
+	return t.Engine.NhCard(itmp1, itmp2, itmp3, itmp4, tmp1, tmp2, tmp3, tmp4, tmp5, tmp6)
+}
+
+func (t *tracer) CpCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	t.card("CpCard")
+	return t.Engine.CpCard(itmp1, itmp2, itmp3, itmp4)
+}
+
+func (t *tracer) PlCard(ploutputFilename string, itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	t.card("PlCard")
+	return t.Engine.PlCard(ploutputFilename, itmp1, itmp2, itmp3, itmp4)
+}
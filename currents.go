@@ -0,0 +1,119 @@
+package necpp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoCurrents is returned by Currents() when no segment currents could be
+// found in libnecpp's output.
+var ErrNoCurrents = errors.New("no segment currents calculated")
+
+// currentTable is one "currents and location" table from NEC's output, and the
+// frequency it was printed at.
+type currentTable struct {
+	freqMHz  float64
+	currents []SegmentCurrent
+}
+
+// Currents returns the segment currents from a run of the simulation. The
+// index counts the solutions in order: with an FR card sweeping several
+// frequencies, index 0 is the first frequency, 1 the second, and so on; a plane
+// wave excitation stepped over several angles of incidence gives one solution
+// for each angle, theta changing fastest, within each frequency.
+//
+// As with NearField(), the currents are taken from libnecpp's printed output,
+// so SetOutput() must have been called on a NecppCtx, and a PT card that turns
+// off the printing of currents will leave nothing to find.
+func (r *recorder) Currents(index int) ([]SegmentCurrent, error) {
+	if r.parseErr != nil {
+		return nil, r.parseErr
+	}
+	tables := r.currents
+	if len(tables) == 0 {
+		return nil, ErrNoCurrents
+	}
+	if index < 0 || index >= len(tables) {
+		return nil, fmt.Errorf("current index %d out of range; there are %d", index, len(tables))
+	}
+	return tables[index].currents, nil
+}
+
+// parseCurrents picks the segment current tables out of NEC's printed output.
+// NEC gives the positions and lengths of segments in wavelengths, which are
+// turned back into meters here.
+func parseCurrents(out string) ([]currentTable, error) {
+	var tables []currentTable
+	var cur *currentTable
+	freq := 0.0
+
+	sc := newScanner(out)
+	for sc.Scan() {
+		line := sc.Text()
+		if f, ok := frequency(line); ok {
+			freq = f
+			continue
+		}
+		if strings.Contains(line, "CURRENTS AND LOCATION") {
+			if cur != nil && len(cur.currents) > 0 {
+				tables = append(tables, *cur)
+			}
+			cur = &currentTable{freqMHz: freq}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		vals, ok := parseFloats(strings.Fields(line))
+		if !ok || len(vals) != 10 {
+			if len(cur.currents) > 0 {
+				// the end of the table
+				tables = append(tables, *cur)
+				cur = nil
+			}
+			continue
+		}
+		scale := 1.0
+		if cur.freqMHz > 0 {
+			scale = SpeedOfLight / cur.freqMHz
+		}
+		cur.currents = append(cur.currents, SegmentCurrent{
+			Segment: int(vals[0]),
+			Tag:     int(vals[1]),
+			X:       vals[2] * scale,
+			Y:       vals[3] * scale,
+			Z:       vals[4] * scale,
+			Length:  vals[5] * scale,
+			Current: complex(vals[6], vals[7]),
+		})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if cur != nil && len(cur.currents) > 0 {
+		tables = append(tables, *cur)
+	}
+	return tables, nil
+}
+
+// portCurrent finds the current on a port's segment. A port given by tag counts
+// segments from 1 within that tag, in the order they were printed.
+func portCurrent(currents []SegmentCurrent, p Port) (complex128, bool) {
+	k := 0
+	for _, c := range currents {
+		if p.Tag == 0 {
+			if c.Segment == p.Segment {
+				return c.Current, true
+			}
+			continue
+		}
+		if c.Tag == p.Tag {
+			k++
+			if k == p.Segment {
+				return c.Current, true
+			}
+		}
+	}
+	return 0, false
+}
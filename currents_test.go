@@ -0,0 +1,61 @@
+package necpp
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+const currentsOutput = `
+                               --------- FREQUENCY --------
+                                FREQUENCY= 2.9979E+02 MHZ
+                                WAVELENGTH= 1.0000E+00 METERS
+
+                           -------- CURRENTS AND LOCATION --------
+                                  DISTANCES IN WAVELENGTHS
+
+   SEG.  TAG    COORD. OF SEG. CENTER     SEG.            - - - CURRENT (AMPS) - - -
+   No.   No.     X         Y         Z      LENGTH     REAL      IMAGINARY    MAGN        PHASE
+     1     1    0.0000    0.0000   -0.2000   0.10000  1.0000E-03 -1.0000E-03  1.4142E-03  -45.000
+     2     1    0.0000    0.0000   -0.1000   0.10000  2.0000E-03  0.0000E+00  2.0000E-03    0.000
+     3     2    0.5000    0.0000    0.0000   0.10000  0.0000E+00  3.0000E-03  3.0000E-03   90.000
+
+                           -------- CURRENTS AND LOCATION --------
+                                  DISTANCES IN WAVELENGTHS
+
+   SEG.  TAG    COORD. OF SEG. CENTER     SEG.            - - - CURRENT (AMPS) - - -
+   No.   No.     X         Y         Z      LENGTH     REAL      IMAGINARY    MAGN        PHASE
+     1     1    0.0000    0.0000   -0.2000   0.10000  4.0000E-03  0.0000E+00  4.0000E-03    0.000
+     2     1    0.0000    0.0000   -0.1000   0.10000  5.0000E-03  0.0000E+00  5.0000E-03    0.000
+     3     2    0.5000    0.0000    0.0000   0.10000  6.0000E-03  0.0000E+00  6.0000E-03    0.000
+`
+
+func TestParseCurrents(t *testing.T) {
+	tables, err := parseCurrents(currentsOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 2 {
+		t.Fatalf("found %d current tables, should have been 2", len(tables))
+	}
+	cs := tables[0].currents
+	if len(cs) != 3 {
+		t.Fatalf("found %d segments, should have been 3", len(cs))
+	}
+	if cmplx.Abs(cs[0].Current-complex(1e-3, -1e-3)) > 1e-12 {
+		t.Errorf("first segment's current was %v", cs[0].Current)
+	}
+	// at 299.79 MHz a wavelength is a meter
+	if math.Abs(cs[2].X-0.5) > 1e-4 || math.Abs(cs[2].Length-0.1) > 1e-4 {
+		t.Errorf("segment 3 was at X %g with length %g, should have been 0.5 and 0.1", cs[2].X, cs[2].Length)
+	}
+	if c, ok := portCurrent(cs, Port{Tag: 2, Segment: 1}); !ok || cmplx.Abs(c-complex(0, 3e-3)) > 1e-12 {
+		t.Errorf("tag 2 segment 1 current was %v", c)
+	}
+	if c, ok := portCurrent(cs, Port{Segment: 2}); !ok || cmplx.Abs(c-2e-3) > 1e-12 {
+		t.Errorf("absolute segment 2 current was %v", c)
+	}
+	if _, ok := portCurrent(cs, Port{Tag: 3, Segment: 1}); ok {
+		t.Errorf("tag 3 doesn't exist, and shouldn't have been found")
+	}
+}
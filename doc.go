@@ -15,29 +15,57 @@ After that's installed, go-libnecpp can be installed with the usual 'go get'.
 
 Groups of Methods
 
-The methods in this library can be grouped into a few general groups - antenna geometry, antenna environment, simulation output, output analysis, and initialization/cleanup type methods. While they are grouped together in the source, godoc rearranges them into alphabetical order.
+The methods in this library can be grouped into a few general groups - antenna geometry, antenna environment, simulation output, output analysis, import and export of results, RF exposure, builders for whole models and common structures, and initialization/cleanup type methods. While they are grouped together in the source, godoc rearranges them into alphabetical order.
 
 The groupings of methods in this library are:
 
 Initialization and Cleanup
 
-New(), Delete()
+New(), NewEngine(), Delete(), Close(), LogLeaks(), Reset(), NewContextPool(), ContextPool.Get(), ContextPool.Put(), ContextPool.Close()
 
 Antenna Geometry
 
-Wire(), SpCard(), ScCard(), GmCard(), GxCard(), GeometryComplete()
+Wire(), SpCard(), ScCard(), GmCard(), GxCard(), GeometryComplete(), Geometry()
 
 Antenna Environment
 
-MediumParameters(), GnCard(), FrCard(), EkCard(), LdCard(), ExCard(), ExcitationVoltage(), ExcitationCurrent(), ExcitationPlanewave(), TlCard(), NtCard(), XqCard(), GdCard()
+MediumParameters(), GnCard(), SetGround(), FrCard(), EkCard(), LdCard(), ExCard(), ExcitationVoltage(), ExcitationCurrent(), ExcitationPlanewave(), TlCard(), NtCard(), ConnectTwoPort(), XqCard(), GdCard()
 
 Simulation Output
 
-RpCard(), PtCard(), PqCard(), KhCard(), NeCard(), NhCard(), CpCard(), PlCard()
+RpCard(), PtCard(), PqCard(), KhCard(), NeCard(), NhCard(), CpCard(), PlCard(), SetOutput(), SetOutputLimit(), Output()
 
 Output Analysis
 
-Gain(), GainMax(), GainMin(), GainMean(), GainRhcpMax(), GainRhcpMin(), GainRhcpMean(), GainRhcpSd(), GainLhcpMax(), GainLhcpMin(), GainLhcpMean(), GainLhcpSd()
+Gain(), GainMax(), GainMin(), GainMean(), GainRhcpMax(), GainRhcpMin(), GainRhcpMean(), GainRhcpSd(), GainLhcpMax(), GainLhcpMin(), GainLhcpMean(), GainLhcpSd(), Impedance(), Frequencies(), ImpedanceSweep(), RadiationPattern(), RadiationPatterns(), NearField(), Currents(), Receive(), BistaticRCS(), MonostaticRCS()
+
+Import and Export
+
+WriteS1P(), WriteS2P(), TwoPortSweep(), ZMatrix(), ReadTouchstone(), ReadTouchstoneFile(), ParseOutput(), ParseOutputFile(), Listing(), CompareImpedance(), Resonance(), VSWR(), WritePatternCSV(), WritePatternJSON(), WriteCurrentsCSV(), WriteCurrentsJSON(), WriteSweepCSV(), WriteSweepJSON()
+
+RF Exposure
+
+InputPower(), ExposureLimits(), EvaluateExposure()
+
+Builders
+
+NewModel(), Model.Clone(), Model.Run(), Model.RunEngine(), Model.RunWorker(), Worker.Run(), Radials.Add(), Radials.Screen(), CompareRadials(), Array.Build(), Array.Weights(), Array.Run()
+
+Engines
+
+Everything a model is built and run with is also in the Engine interface, which NecppCtx implements. The functions that run a model more than once, such as ZMatrix(), CompareRadials() and Array.Run(), take an EngineFactory to make an engine for each run, with nil meaning NewEngine(), which uses libnecpp. FakeEngine implements it without libnecpp, recording the cards it's given and answering with scripted impedances and gains, so code built on this package can be tested with CGO_ENABLED=0. Built that way, New() returns ErrNoLibnecpp.
+
+Solver, made with NewSolver(), is an Engine written in pure Go. It solves straight thin wire structures in free space or over a perfect ground with the method of moments, and returns an error for cards it can't model, such as surface patches, finite grounds and transmission lines. Its results are close to libnecpp's, but not identical. It uses triangle basis functions with Galerkin testing unless SetBasis() asks for pulse basis functions point matched against Pocklington's equation.
+
+A Worker runs a model in a child process instead, which can be killed when its context is cancelled, partway through a card if need be. If libnecpp crashes on the model, only the worker dies, and a *CrashError says how and on which card; setting Isolated on a Model makes Run() work that way. By default the worker is the running program, started again, so its main() has to call ServeWorkerIfRequested() before anything else. The necpp-worker command is a ready-made worker, for programs that would rather not be started again as their own.
+
+Subpackages
+
+The plot subpackage renders radiation patterns and impedance sweeps as SVG images, radiation patterns as 3D meshes (OBJ, STL, and VTK), and an antenna's geometry as SVG projections or VTK polydata.
+
+The matching subpackage designs L, Pi and T networks, stub matches and quarter wave transformers for a feed impedance, and can put them back into the model.
+
+The feedline subpackage carries an impedance sweep through a length of lossy coax or ladder line, giving the impedance, VSWR and loss at the transmitter end.
 
 Documentation
 
@@ -0,0 +1,114 @@
+package necpp
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrNoLibnecpp is returned by New(), and by NecppCtx's methods, when the
+// package has been built without cgo and so without libnecpp.
+var ErrNoLibnecpp = errors.New("necpp was built without cgo, so libnecpp isn't available")
+
+// Engine is what a model is built up in and run on: the cards, and the results
+// that can be had afterwards. NecppCtx is the Engine that runs the model with
+// libnecpp. FakeEngine is one that records the cards it's given and hands back
+// made up results instead, so code built on this package can be tested without
+// libnecpp, or with CGO_ENABLED=0.
+//
+// The methods are the same as NecppCtx's, and are documented there.
+type Engine interface {
+	Delete() error
+
+	// antenna geometry
+	Wire(tagId int, segmentCount int, xw1 float64, yw1 float64, zw1 float64, xw2 float64, yw2 float64, zw2 float64, rad float64, rdel float64, rrad float64) error
+	SpCard(ns PatchType, x1 float64, y1 float64, z1 float64, x2 float64, y2 float64, z2 float64) error
+	ScCard(i2 int, x3 float64, y3 float64, z3 float64, x4 float64, y4 float64, z4 float64) error
+	GmCard(itsi int, nrpt int, rox float64, roy float64, roz float64, xs float64, ys float64, zs float64, its int) error
+	GxCard(i1 int, i2 int) error
+	GeometryComplete(gpflag GeoGroundPlaneFlag) error
+	Geometry() *Geometry
+
+	// antenna environment
+	MediumParameters(permittivity float64, permeability float64) error
+	GnCard(iperf GroundTypeFlag, nradl int, epse float64, sig float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error
+	GdCard(tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64) error
+	SetGround(g Ground) error
+	FrCard(inIfrq FrequencyRange, inNfrq int, inFreqMhz float64, inDelFreq float64) error
+	EkCard(itmp1 WireKernel) error
+	LdCard(ldtype int, ldtag int, ldtagf int, ldtagt int, tmp1 float64, tmp2 float64, tmp3 float64) error
+	ExCard(extype Excitation, i2 int, i3 int, i4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error
+	ExcitationVoltage(tag int, segment int, voltageExcitation complex128) error
+	ExcitationCurrent(x float64, y float64, z float64, a float64, beta float64, moment float64) error
+	ExcitationPlanewave(nTheta int, nPhi int, theta float64, phi float64, eta float64, dTheta float64, dPhi float64, polRatio float64) error
+	TlCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error
+	NtCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error
+	ConnectTwoPort(p1 Port, p2 Port, t TwoPort) error
+	XqCard(itmp1 ExecutionOption) error
+
+	// simulation output
+	RpCard(calcMode RpCalcMode, nTheta int, nPhi int, outputFormat RpOutputFormat, normalization RpNormalization, d RpGain, a RpAveraging, theta0 float64, phi0 float64, deltaTheta float64, deltaPhi float64, radialDistance float64, gainNorm float64) error
+	PtCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int) error
+	PqCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int) error
+	KhCard(tmp1 float64) error
+	NeCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error
+	NhCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error
+	CpCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int) error
+	PlCard(ploutputFilename string, itmp1 int, itmp2 int, itmp3 int, itmp4 int) error
+	SetOutput(w io.Writer)
+	SetOutputLimit(limit int)
+	Output() string
+
+	// output analysis
+	Gain(freqIndex int, thetaIndex int, phiIndex int) (float64, error)
+	GainMax(freqIndex int) (float64, error)
+	GainMin(freqIndex int) (float64, error)
+	GainMean(freqIndex int) (float64, error)
+	GainSd(freqIndex int) (float64, error)
+	GainRhcpMax(freqIndex int) (float64, error)
+	GainRhcpMin(freqIndex int) (float64, error)
+	GainRhcpMean(freqIndex int) (float64, error)
+	GainRhcpSd(freqIndex int) (float64, error)
+	GainLhcpMax(freqIndex int) (float64, error)
+	GainLhcpMin(freqIndex int) (float64, error)
+	GainLhcpMean(freqIndex int) (float64, error)
+	GainLhcpSd(freqIndex int) (float64, error)
+	Impedance(freqIndex int) (complex128, error)
+	Frequencies() []float64
+	ImpedanceSweep() ([]ImpedancePoint, error)
+	RadiationPattern(freqIndex int) (*RadiationPattern, error)
+	RadiationPatterns() ([]*RadiationPattern, error)
+	Listing() (*Listing, error)
+	NearField(freqIndex int) (*NearField, error)
+	Currents(index int) ([]SegmentCurrent, error)
+	InputPower(freqIndex int) (float64, error)
+}
+
+// EngineFactory makes a new Engine. The functions that build and run a model
+// more than once, such as ZMatrix() and Array.Run(), take one, and run each
+// model on an engine of its own from it. nil means NewEngine(), which runs the
+// models with libnecpp; to run them on the Solver, or on a FakeEngine in tests,
+// pass a function that makes one of those instead.
+type EngineFactory func() (Engine, error)
+
+// NewEngine makes a NecppCtx with New(), as an Engine.
+func NewEngine() (Engine, error) {
+	n, err := New()
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// engine makes a new engine with f, or with NewEngine() if f is nil.
+func (f EngineFactory) engine() (Engine, error) {
+	if f == nil {
+		return NewEngine()
+	}
+	return f()
+}
+
+var (
+	_ Engine = (*NecppCtx)(nil)
+	_ Engine = (*FakeEngine)(nil)
+	_ Engine = (*Solver)(nil)
+)
@@ -0,0 +1,224 @@
+package necpp
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"math/cmplx"
+	"strconv"
+)
+
+// Column names for the CSV files and keys for the JSON written by the
+// functions below. These are part of the package's API, and won't be changed.
+// Units are given in the name: angles are in degrees, gains in dBi, distances
+// in meters, currents in amps, and impedances in ohms.
+var (
+	patternColumns = []string{"freq_mhz", "theta_deg", "phi_deg", "gain_total_dbi", "gain_vert_dbi", "gain_hor_dbi", "gain_rhcp_dbi", "gain_lhcp_dbi"}
+	currentColumns = []string{"segment", "tag", "x_m", "y_m", "z_m", "length_m", "current_real_a", "current_imag_a", "current_mag_a", "current_phase_deg"}
+	sweepColumns   = []string{"freq_mhz", "resistance_ohm", "reactance_ohm", "z_mag_ohm", "z_phase_deg", "reflection_mag", "return_loss_db", "vswr"}
+)
+
+// WritePatternCSV writes radiation patterns to w as CSV, one row per point,
+// with a header row. Several patterns, such as one per frequency of a sweep,
+// can be written to the same table. The polarization columns are left empty
+// for patterns that aren't Polarized.
+func WritePatternCSV(w io.Writer, patterns ...*RadiationPattern) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(patternColumns); err != nil {
+		return err
+	}
+	for _, p := range patterns {
+		for _, pt := range p.Points {
+			row := []string{ftoa(p.FreqMHz), ftoa(pt.Theta), ftoa(pt.Phi), ftoa(pt.Total), "", "", "", ""}
+			if p.Polarized {
+				row[4], row[5], row[6], row[7] = ftoa(pt.Vertical), ftoa(pt.Horizontal), ftoa(pt.RHCP), ftoa(pt.LHCP)
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type jsonPattern struct {
+	FreqMHz   float64            `json:"freq_mhz"`
+	NTheta    int                `json:"n_theta"`
+	NPhi      int                `json:"n_phi"`
+	Polarized bool               `json:"polarized"`
+	Points    []jsonPatternPoint `json:"points"`
+}
+
+type jsonPatternPoint struct {
+	Theta      float64  `json:"theta_deg"`
+	Phi        float64  `json:"phi_deg"`
+	Total      float64  `json:"gain_total_dbi"`
+	Vertical   *float64 `json:"gain_vert_dbi,omitempty"`
+	Horizontal *float64 `json:"gain_hor_dbi,omitempty"`
+	RHCP       *float64 `json:"gain_rhcp_dbi,omitempty"`
+	LHCP       *float64 `json:"gain_lhcp_dbi,omitempty"`
+}
+
+// WritePatternJSON writes radiation patterns to w as a JSON array, with one
+// object per pattern. The polarization gains are left out of the points of
+// patterns that aren't Polarized.
+func WritePatternJSON(w io.Writer, patterns ...*RadiationPattern) error {
+	out := make([]jsonPattern, len(patterns))
+	for i, p := range patterns {
+		jp := jsonPattern{FreqMHz: p.FreqMHz, NTheta: p.NTheta, NPhi: p.NPhi, Polarized: p.Polarized}
+		jp.Points = make([]jsonPatternPoint, len(p.Points))
+		for j := range p.Points {
+			pt := &p.Points[j]
+			jp.Points[j] = jsonPatternPoint{Theta: pt.Theta, Phi: pt.Phi, Total: pt.Total}
+			if p.Polarized {
+				jp.Points[j].Vertical = &pt.Vertical
+				jp.Points[j].Horizontal = &pt.Horizontal
+				jp.Points[j].RHCP = &pt.RHCP
+				jp.Points[j].LHCP = &pt.LHCP
+			}
+		}
+		out[i] = jp
+	}
+	return writeJSON(w, out)
+}
+
+// WriteCurrentsCSV writes segment currents to w as CSV, one row per segment,
+// with a header row.
+func WriteCurrentsCSV(w io.Writer, currents []SegmentCurrent) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(currentColumns); err != nil {
+		return err
+	}
+	for _, c := range currents {
+		row := []string{
+			strconv.Itoa(c.Segment), strconv.Itoa(c.Tag),
+			ftoa(c.X), ftoa(c.Y), ftoa(c.Z), ftoa(c.Length),
+			ftoa(real(c.Current)), ftoa(imag(c.Current)),
+			ftoa(cmplx.Abs(c.Current)), ftoa(phaseDeg(c.Current)),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type jsonCurrent struct {
+	Segment int     `json:"segment"`
+	Tag     int     `json:"tag"`
+	X       float64 `json:"x_m"`
+	Y       float64 `json:"y_m"`
+	Z       float64 `json:"z_m"`
+	Length  float64 `json:"length_m"`
+	Real    float64 `json:"current_real_a"`
+	Imag    float64 `json:"current_imag_a"`
+	Mag     float64 `json:"current_mag_a"`
+	Phase   float64 `json:"current_phase_deg"`
+}
+
+// WriteCurrentsJSON writes segment currents to w as a JSON array, with one
+// object per segment.
+func WriteCurrentsJSON(w io.Writer, currents []SegmentCurrent) error {
+	out := make([]jsonCurrent, len(currents))
+	for i, c := range currents {
+		out[i] = jsonCurrent{
+			Segment: c.Segment, Tag: c.Tag,
+			X: c.X, Y: c.Y, Z: c.Z, Length: c.Length,
+			Real: real(c.Current), Imag: imag(c.Current),
+			Mag: cmplx.Abs(c.Current), Phase: phaseDeg(c.Current),
+		}
+	}
+	return writeJSON(w, out)
+}
+
+// WriteSweepCSV writes an impedance sweep to w as CSV, one row per frequency,
+// with a header row. The reflection coefficient, return loss and VSWR are
+// calculated against the reference impedance z0. An infinite VSWR or return
+// loss is written as "inf".
+func WriteSweepCSV(w io.Writer, sweep []ImpedancePoint, z0 float64) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(sweepColumns); err != nil {
+		return err
+	}
+	for _, p := range sweep {
+		s := newJSONSweepPoint(p, z0)
+		row := []string{
+			ftoa(s.FreqMHz), ftoa(s.Resistance), ftoa(s.Reactance),
+			ftoa(s.ZMag), ftoa(s.ZPhase), ftoa(s.ReflectionMag),
+			ftoaPtr(s.ReturnLoss), ftoaPtr(s.VSWR),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type jsonSweepPoint struct {
+	FreqMHz       float64  `json:"freq_mhz"`
+	Resistance    float64  `json:"resistance_ohm"`
+	Reactance     float64  `json:"reactance_ohm"`
+	ZMag          float64  `json:"z_mag_ohm"`
+	ZPhase        float64  `json:"z_phase_deg"`
+	ReflectionMag float64  `json:"reflection_mag"`
+	ReturnLoss    *float64 `json:"return_loss_db"`
+	VSWR          *float64 `json:"vswr"`
+}
+
+func newJSONSweepPoint(p ImpedancePoint, z0 float64) jsonSweepPoint {
+	g := cmplx.Abs(ReflectionCoefficient(p.Impedance, z0))
+	s := jsonSweepPoint{
+		FreqMHz:       p.FreqMHz,
+		Resistance:    real(p.Impedance),
+		Reactance:     imag(p.Impedance),
+		ZMag:          cmplx.Abs(p.Impedance),
+		ZPhase:        phaseDeg(p.Impedance),
+		ReflectionMag: g,
+	}
+	// JSON can't hold infinities, so those are left as nulls
+	if g > 0 {
+		rl := -20 * math.Log10(g)
+		s.ReturnLoss = &rl
+	}
+	if v := VSWR(p.Impedance, z0); !math.IsInf(v, 0) {
+		s.VSWR = &v
+	}
+	return s
+}
+
+// WriteSweepJSON writes an impedance sweep to w as a JSON array, with one
+// object per frequency. The reflection coefficient, return loss and VSWR are
+// calculated against the reference impedance z0; an infinite VSWR or return
+// loss is written as null.
+func WriteSweepJSON(w io.Writer, sweep []ImpedancePoint, z0 float64) error {
+	out := make([]jsonSweepPoint, len(sweep))
+	for i, p := range sweep {
+		out[i] = newJSONSweepPoint(p, z0)
+	}
+	return writeJSON(w, out)
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func ftoa(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func ftoaPtr(f *float64) string {
+	if f == nil {
+		return "inf"
+	}
+	return ftoa(*f)
+}
+
+func phaseDeg(c complex128) float64 {
+	return cmplx.Phase(c) * 180 / math.Pi
+}
@@ -0,0 +1,52 @@
+package necpp
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+)
+
+func TestWritePatternCSV(t *testing.T) {
+	p := newRadiationPattern(patternGrid{nTheta: 3, nPhi: 2, theta0: 0, dTheta: 45, phi0: 0, dPhi: 90, freqMHz: 14})
+	for i := range p.Points {
+		p.Points[i].Total = float64(i)
+	}
+	var buf bytes.Buffer
+	if err := WritePatternCSV(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 7 {
+		t.Fatalf("expected 7 rows, got %d", len(rows))
+	}
+	// theta steps fastest, so the fifth point is the second theta of the
+	// second phi
+	want := []string{"14", "45", "90", "4", "", "", "", ""}
+	for i, v := range want {
+		if rows[5][i] != v {
+			t.Errorf("column %s was %q, should have been %q", patternColumns[i], rows[5][i], v)
+		}
+	}
+}
+
+func TestWriteSweepJSON(t *testing.T) {
+	sweep := []ImpedancePoint{{FreqMHz: 7.1, Impedance: complex(50, 0)}, {FreqMHz: 7.2, Impedance: complex(0, 30)}}
+	var buf bytes.Buffer
+	if err := WriteSweepJSON(&buf, sweep, 50); err != nil {
+		t.Fatal(err)
+	}
+	var out []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out[0]["vswr"] != 1.0 {
+		t.Errorf("matched VSWR was %v, should have been 1", out[0]["vswr"])
+	}
+	if v, ok := out[1]["vswr"]; !ok || v != nil {
+		t.Errorf("VSWR of a pure reactance was %v, should have been null", v)
+	}
+}
@@ -0,0 +1,282 @@
+package necpp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// FreeSpaceImpedance is the impedance of free space, in ohms, used to turn
+// field strengths into equivalent plane wave power densities.
+const FreeSpaceImpedance = 376.73
+
+// ExposureStandard is a set of RF exposure limits.
+//
+// • FCCOET65 - the FCC's maximum permissible exposure limits (47 CFR 1.1310),
+// as explained in OET Bulletin 65.
+//
+// • ICNIRP1998 - the ICNIRP 1998 guidelines' reference levels.
+type ExposureStandard int
+
+const (
+	FCCOET65 ExposureStandard = iota
+	ICNIRP1998
+)
+
+func (s ExposureStandard) String() string {
+	switch s {
+	case FCCOET65:
+		return "FCC OET-65"
+	case ICNIRP1998:
+		return "ICNIRP 1998"
+	}
+	return fmt.Sprintf("ExposureStandard(%d)", int(s))
+}
+
+// ExposureTier is who the exposure limits are for.
+//
+// • Controlled - occupational exposure, by people who know about it and can
+// do something about it.
+//
+// • Uncontrolled - exposure of the general public.
+type ExposureTier int
+
+const (
+	Controlled ExposureTier = iota
+	Uncontrolled
+)
+
+func (t ExposureTier) String() string {
+	switch t {
+	case Controlled:
+		return "controlled/occupational"
+	case Uncontrolled:
+		return "uncontrolled/general public"
+	}
+	return fmt.Sprintf("ExposureTier(%d)", int(t))
+}
+
+// ExposureLimit is the limit on the RMS electric field strength E in volts per
+// meter, the RMS magnetic field strength H in amps per meter, and the
+// equivalent plane wave power density S in watts per square meter. A zero
+// means the standard sets no limit on that quantity at that frequency.
+type ExposureLimit struct {
+	E float64
+	H float64
+	S float64
+}
+
+// ExposureLimits returns the exposure limits a standard sets at a frequency in
+// MHz, for the given tier. It returns an error for frequencies the standard
+// doesn't cover.
+func ExposureLimits(std ExposureStandard, tier ExposureTier, freqMHz float64) (ExposureLimit, error) {
+	f := freqMHz
+	var l ExposureLimit
+	switch std {
+	case FCCOET65:
+		// the FCC gives power densities in mW/cm^2, which is 10 W/m^2
+		if tier == Controlled {
+			switch {
+			case f >= 0.3 && f <= 3:
+				l = ExposureLimit{E: 614, H: 1.63, S: 1000}
+			case f > 3 && f <= 30:
+				l = ExposureLimit{E: 1842 / f, H: 4.89 / f, S: 9000 / (f * f)}
+			case f > 30 && f <= 300:
+				l = ExposureLimit{E: 61.4, H: 0.163, S: 10}
+			case f > 300 && f <= 1500:
+				l = ExposureLimit{S: f / 30}
+			case f > 1500 && f <= 100000:
+				l = ExposureLimit{S: 50}
+			}
+		} else {
+			switch {
+			case f >= 0.3 && f <= 1.34:
+				l = ExposureLimit{E: 614, H: 1.63, S: 1000}
+			case f > 1.34 && f <= 30:
+				l = ExposureLimit{E: 824 / f, H: 2.19 / f, S: 1800 / (f * f)}
+			case f > 30 && f <= 300:
+				l = ExposureLimit{E: 27.5, H: 0.073, S: 2}
+			case f > 300 && f <= 1500:
+				l = ExposureLimit{S: f / 150}
+			case f > 1500 && f <= 100000:
+				l = ExposureLimit{S: 10}
+			}
+		}
+	case ICNIRP1998:
+		if tier == Controlled {
+			switch {
+			case f >= 0.065 && f <= 1:
+				l = ExposureLimit{E: 610, H: 1.6 / f}
+			case f > 1 && f <= 10:
+				l = ExposureLimit{E: 610 / f, H: 1.6 / f}
+			case f > 10 && f <= 400:
+				l = ExposureLimit{E: 61, H: 0.16, S: 10}
+			case f > 400 && f <= 2000:
+				l = ExposureLimit{E: 3 * math.Sqrt(f), H: 0.008 * math.Sqrt(f), S: f / 40}
+			case f > 2000 && f <= 300000:
+				l = ExposureLimit{E: 137, H: 0.36, S: 50}
+			}
+		} else {
+			switch {
+			case f >= 0.15 && f <= 1:
+				l = ExposureLimit{E: 87, H: 0.73 / f}
+			case f > 1 && f <= 10:
+				l = ExposureLimit{E: 87 / math.Sqrt(f), H: 0.73 / f}
+			case f > 10 && f <= 400:
+				l = ExposureLimit{E: 28, H: 0.073, S: 2}
+			case f > 400 && f <= 2000:
+				l = ExposureLimit{E: 1.375 * math.Sqrt(f), H: 0.0037 * math.Sqrt(f), S: f / 200}
+			case f > 2000 && f <= 300000:
+				l = ExposureLimit{E: 61, H: 0.16, S: 10}
+			}
+		}
+	default:
+		return l, fmt.Errorf("unknown exposure standard %d", int(std))
+	}
+	if l == (ExposureLimit{}) {
+		return l, fmt.Errorf("%s has no %s limits at %g MHz", std, tier, freqMHz)
+	}
+	return l, nil
+}
+
+// ExposureOptions gives the conditions to evaluate RF exposure under.
+type ExposureOptions struct {
+	Standard ExposureStandard
+	Tier     ExposureTier
+
+	// TransmitPower is the average power, in watts, the antenna will be fed
+	// with. Any duty cycle should already be taken into account.
+	TransmitPower float64
+
+	// InputPower is the power, in watts, fed to the antenna in the
+	// simulation the near fields came from. See InputPower().
+	InputPower float64
+
+	// Center is the point compliance distances are measured from, usually
+	// the antenna's feed point or center.
+	Center [3]float64
+}
+
+// ExposurePoint is the exposure at one point of a near field grid. The power
+// density is the larger of the equivalent plane wave power densities of the
+// electric and magnetic fields, in watts per square meter. Fraction is the
+// exposure as a fraction of the limit; anything over 1 is over the limit.
+type ExposurePoint struct {
+	Position     [3]float64
+	Distance     float64 // meters from the Center
+	ERMS         float64 // V/m
+	HRMS         float64 // A/m
+	PowerDensity float64 // W/m^2
+	Fraction     float64
+}
+
+// ExposureReport is the result of evaluating the exposure over a near field
+// grid.
+//
+// ComplianceDistance is the distance from the Center beyond which every point
+// of the grid is within the limits, or zero if all of them are. If the points
+// farthest out are still over the limit, it is +Inf, and the grid needs to be
+// made bigger to find it.
+type ExposureReport struct {
+	FreqMHz            float64
+	Standard           ExposureStandard
+	Tier               ExposureTier
+	Limit              ExposureLimit
+	Points             []ExposurePoint
+	MaxFraction        float64
+	Compliant          bool
+	ComplianceDistance float64
+}
+
+// EvaluateExposure works out the exposure over a near field grid for a
+// transmitter power, and compares it with a standard's limits at the near
+// field's frequency. NEC's field strengths are peak values for the power fed to
+// the antenna in the simulation, so they're scaled by the ratio of the
+// transmit power to that, and converted to RMS.
+//
+// Only the parts of the near field that were calculated are used: if the grid
+// only has an electric field, the magnetic field limits aren't checked. Both
+// should be calculated close to the antenna, where they aren't related like a
+// plane wave's.
+func EvaluateExposure(nf *NearField, opts ExposureOptions) (*ExposureReport, error) {
+	if opts.InputPower <= 0 {
+		return nil, errors.New("the input power must be positive")
+	}
+	if opts.TransmitPower < 0 {
+		return nil, errors.New("the transmit power can't be negative")
+	}
+	limit, err := ExposureLimits(opts.Standard, opts.Tier, nf.FreqMHz)
+	if err != nil {
+		return nil, err
+	}
+	r := &ExposureReport{
+		FreqMHz:  nf.FreqMHz,
+		Standard: opts.Standard,
+		Tier:     opts.Tier,
+		Limit:    limit,
+		Points:   make([]ExposurePoint, len(nf.Points)),
+	}
+	// peak to RMS, and the simulated power to the transmitter's
+	scale := math.Sqrt(opts.TransmitPower/opts.InputPower) / math.Sqrt2
+	maxDist := 0.0
+	for i, p := range nf.Points {
+		ep := ExposurePoint{
+			Position: p.Position,
+			Distance: dist(p.Position, opts.Center),
+			ERMS:     scale * vectorMagnitude(p.E),
+			HRMS:     scale * vectorMagnitude(p.H),
+		}
+		ep.PowerDensity = math.Max(ep.ERMS*ep.ERMS/FreeSpaceImpedance, ep.HRMS*ep.HRMS*FreeSpaceImpedance)
+		// compared as ratios of power, so they're all on the same footing
+		if limit.E > 0 && nf.Electric {
+			ep.Fraction = math.Max(ep.Fraction, math.Pow(ep.ERMS/limit.E, 2))
+		}
+		if limit.H > 0 && nf.Magnetic {
+			ep.Fraction = math.Max(ep.Fraction, math.Pow(ep.HRMS/limit.H, 2))
+		}
+		if limit.S > 0 {
+			ep.Fraction = math.Max(ep.Fraction, ep.PowerDensity/limit.S)
+		}
+		r.Points[i] = ep
+		r.MaxFraction = math.Max(r.MaxFraction, ep.Fraction)
+		maxDist = math.Max(maxDist, ep.Distance)
+	}
+	r.Compliant = r.MaxFraction <= 1
+	for _, ep := range r.Points {
+		if ep.Fraction > 1 {
+			r.ComplianceDistance = math.Max(r.ComplianceDistance, ep.Distance)
+		}
+	}
+	if !r.Compliant && r.ComplianceDistance >= maxDist {
+		r.ComplianceDistance = math.Inf(1)
+	}
+	return r, nil
+}
+
+func vectorMagnitude(v [3]complex128) float64 {
+	s := 0.0
+	for _, c := range v {
+		a := cmplx.Abs(c)
+		s += a * a
+	}
+	return math.Sqrt(s)
+}
+
+// InputPower returns the total power fed to the antenna in the simulation, in
+// watts, from the power budget libnecpp prints for each frequency. The index
+// counts the power budgets in the order they were printed. As with NearField(),
+// SetOutput() must have been called on a NecppCtx for there to be any.
+func (r *recorder) InputPower(freqIndex int) (float64, error) {
+	if r.parseErr != nil {
+		return 0, r.parseErr
+	}
+	budgets := r.results.Power
+	if len(budgets) == 0 {
+		return 0, ErrNoPowerBudget
+	}
+	if freqIndex < 0 || freqIndex >= len(budgets) {
+		return 0, fmt.Errorf("power budget index %d out of range; there are %d", freqIndex, len(budgets))
+	}
+	return budgets[freqIndex].InputPower, nil
+}
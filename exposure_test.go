@@ -0,0 +1,66 @@
+package necpp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExposureLimits(t *testing.T) {
+	tests := []struct {
+		std  ExposureStandard
+		tier ExposureTier
+		freq float64
+		s    float64
+	}{
+		{FCCOET65, Controlled, 14, 9000.0 / 196},
+		{FCCOET65, Uncontrolled, 146, 2},
+		{FCCOET65, Uncontrolled, 450, 3},
+		{ICNIRP1998, Controlled, 144, 10},
+		{ICNIRP1998, Uncontrolled, 1000, 5},
+	}
+	for _, tt := range tests {
+		l, err := ExposureLimits(tt.std, tt.tier, tt.freq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(l.S-tt.s) > 1e-9 {
+			t.Errorf("%s %s at %g MHz: power density limit was %g, should have been %g", tt.std, tt.tier, tt.freq, l.S, tt.s)
+		}
+	}
+	if _, err := ExposureLimits(FCCOET65, Controlled, 0.1); err == nil {
+		t.Errorf("0.1 MHz is below the FCC limits, and should have been an error")
+	}
+}
+
+func TestEvaluateExposure(t *testing.T) {
+	// a field falling off as 1/r along the X axis, with 100 V/m peak at 1 m
+	// for 1 W in
+	nf := &NearField{FreqMHz: 146, N: [3]int{10, 1, 1}, Electric: true}
+	for x := 1.0; x <= 10; x++ {
+		nf.Points = append(nf.Points, NearFieldPoint{Position: [3]float64{x, 0, 0}, E: [3]complex128{0, 0, complex(100/x, 0)}})
+	}
+	r, err := EvaluateExposure(nf, ExposureOptions{Standard: FCCOET65, Tier: Uncontrolled, TransmitPower: 1, InputPower: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 100 V/m peak is 70.7 V/m RMS, which is 13.3 W/m^2, against a limit of
+	// 27.5 V/m or 2 W/m^2. The field drops under 27.5 V/m RMS at 2.57 m, so
+	// the last grid point over the limit is the one at 2 m
+	if r.Compliant {
+		t.Errorf("the exposure should have been over the limit")
+	}
+	if r.ComplianceDistance != 2 {
+		t.Errorf("compliance distance was %g, should have been 2", r.ComplianceDistance)
+	}
+	if p := r.Points[0].PowerDensity; math.Abs(p-5000/FreeSpaceImpedance) > 1e-9 {
+		t.Errorf("power density at 1 m was %g", p)
+	}
+
+	r, err = EvaluateExposure(nf, ExposureOptions{Standard: FCCOET65, Tier: Uncontrolled, TransmitPower: 1000, InputPower: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(r.ComplianceDistance, 1) {
+		t.Errorf("at 1 kW the grid doesn't reach far enough, so the distance should have been infinite, not %g", r.ComplianceDistance)
+	}
+}
@@ -0,0 +1,447 @@
+package necpp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Call is one method call made on a FakeEngine: the name of the method, and
+// the arguments it was called with, in order.
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// FakeEngine is an Engine that doesn't simulate anything. It records the cards
+// it's given, keeps track of the geometry, frequencies and radiation patterns
+// the same way NecppCtx does, and answers Impedance() and the Gain methods with
+// whatever its functions say. It needs neither libnecpp nor cgo, so it can
+// stand in for a NecppCtx when testing code built on this package.
+//
+// The zero value is ready to use, and answers 50 ohms and 0 dBi everywhere.
+//
+// • Calls - every method called that makes a card, in order.
+//
+// • ImpedanceFunc - the impedance of the first source at a frequency in MHz.
+//
+// • GainFunc - the gain, in dBi, at a frequency and a direction (theta and phi,
+// in degrees). The circular polarization gains are taken to be half of it, as
+// they would be for a linearly polarized antenna.
+//
+// • Report - written to the output, as libnecpp's printed report would be,
+// whenever a card runs the simulation (XQ, RP, NE and NH cards), so it can be
+// a NEC output listing for Listing(), Currents() and the like to read.
+//
+// • Errors - methods to fail, and the error they fail with, by method name.
+type FakeEngine struct {
+	recorder
+	Calls         []Call
+	ImpedanceFunc func(freqMHz float64) complex128
+	GainFunc      func(freqMHz float64, theta float64, phi float64) float64
+	Report        string
+	Errors        map[string]error
+
+	ran bool // the simulation has been run at least once
+}
+
+// Called returns the calls made to the named method, in order.
+func (f *FakeEngine) Called(method string) []Call {
+	var calls []Call
+	for _, c := range f.Calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// call records a call to a method, and returns the error it's meant to fail
+// with, if any.
+func (f *FakeEngine) call(method string, args ...interface{}) error {
+	f.Calls = append(f.Calls, Call{Method: method, Args: args})
+	return f.Errors[method]
+}
+
+// run stands in for running the simulation, printing the report.
+func (f *FakeEngine) run() {
+	f.ran = true
+	if f.Report == "" {
+		return
+	}
+	f.record(f.Report)
+	if f.stdout != nil {
+		io.WriteString(f.stdout, f.Report)
+	}
+}
+
+// Delete records the call; there's nothing to free.
+func (f *FakeEngine) Delete() error {
+	return f.call("Delete")
+}
+
+// Wire records a GW card.
+func (f *FakeEngine) Wire(tagId int, segmentCount int, xw1 float64, yw1 float64, zw1 float64, xw2 float64, yw2 float64, zw2 float64, rad float64, rdel float64, rrad float64) error {
+	if err := f.call("Wire", tagId, segmentCount, xw1, yw1, zw1, xw2, yw2, zw2, rad, rdel, rrad); err != nil {
+		return err
+	}
+	f.wire(WireSpec{Tag: tagId, Segments: segmentCount, X1: xw1, Y1: yw1, Z1: zw1, X2: xw2, Y2: yw2, Z2: zw2, Radius: rad, RDel: rdel, RRad: rrad})
+	return nil
+}
+
+// SpCard records an SP card.
+func (f *FakeEngine) SpCard(ns PatchType, x1 float64, y1 float64, z1 float64, x2 float64, y2 float64, z2 float64) error {
+	if err := f.call("SpCard", ns, x1, y1, z1, x2, y2, z2); err != nil {
+		return err
+	}
+	f.spCard(ns, x1, y1, z1, x2, y2, z2)
+	return nil
+}
+
+// ScCard records an SC card.
+func (f *FakeEngine) ScCard(i2 int, x3 float64, y3 float64, z3 float64, x4 float64, y4 float64, z4 float64) error {
+	if err := f.call("ScCard", i2, x3, y3, z3, x4, y4, z4); err != nil {
+		return err
+	}
+	f.scCard(x3, y3, z3, x4, y4, z4)
+	return nil
+}
+
+// GmCard records a GM card.
+func (f *FakeEngine) GmCard(itsi int, nrpt int, rox float64, roy float64, roz float64, xs float64, ys float64, zs float64, its int) error {
+	if err := f.call("GmCard", itsi, nrpt, rox, roy, roz, xs, ys, zs, its); err != nil {
+		return err
+	}
+	f.geom.move(itsi, nrpt, rox, roy, roz, xs, ys, zs, its)
+	return nil
+}
+
+// GxCard records a GX card.
+func (f *FakeEngine) GxCard(i1 int, i2 int) error {
+	if err := f.call("GxCard", i1, i2); err != nil {
+		return err
+	}
+	f.geom.reflect(i1, i2)
+	return nil
+}
+
+// GeometryComplete records a GE card.
+func (f *FakeEngine) GeometryComplete(gpflag GeoGroundPlaneFlag) error {
+	return f.call("GeometryComplete", gpflag)
+}
+
+// MediumParameters records the call.
+func (f *FakeEngine) MediumParameters(permittivity float64, permeability float64) error {
+	return f.call("MediumParameters", permittivity, permeability)
+}
+
+// GnCard records a GN card.
+func (f *FakeEngine) GnCard(iperf GroundTypeFlag, nradl int, epse float64, sig float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	return f.call("GnCard", iperf, nradl, epse, sig, tmp3, tmp4, tmp5, tmp6)
+}
+
+// GdCard records a GD card.
+func (f *FakeEngine) GdCard(tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64) error {
+	return f.call("GdCard", tmp1, tmp2, tmp3, tmp4)
+}
+
+// SetGround makes the GN card (and GD card, if needed) for a ground.
+func (f *FakeEngine) SetGround(g Ground) error {
+	return setGround(f, g)
+}
+
+// FrCard records an FR card.
+func (f *FakeEngine) FrCard(inIfrq FrequencyRange, inNfrq int, inFreqMhz float64, inDelFreq float64) error {
+	if err := f.call("FrCard", inIfrq, inNfrq, inFreqMhz, inDelFreq); err != nil {
+		return err
+	}
+	f.frCard(inIfrq, inNfrq, inFreqMhz, inDelFreq)
+	return nil
+}
+
+// EkCard records an EK card.
+func (f *FakeEngine) EkCard(itmp1 WireKernel) error {
+	return f.call("EkCard", itmp1)
+}
+
+// LdCard records an LD card.
+func (f *FakeEngine) LdCard(ldtype int, ldtag int, ldtagf int, ldtagt int, tmp1 float64, tmp2 float64, tmp3 float64) error {
+	if err := f.call("LdCard", ldtype, ldtag, ldtagf, ldtagt, tmp1, tmp2, tmp3); err != nil {
+		return err
+	}
+	f.ldCard(ldtype, ldtag, ldtagf, ldtagt, tmp1, tmp2, tmp3)
+	return nil
+}
+
+// ExCard records an EX card.
+func (f *FakeEngine) ExCard(extype Excitation, i2 int, i3 int, i4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	if err := f.call("ExCard", extype, i2, i3, i4, tmp1, tmp2, tmp3, tmp4, tmp5, tmp6); err != nil {
+		return err
+	}
+	if extype == VoltageApplied || extype == VoltageSlope {
+		f.feed(i2, i3, complex(tmp1, tmp2))
+	}
+	return nil
+}
+
+// ExcitationVoltage records a voltage source.
+func (f *FakeEngine) ExcitationVoltage(tag int, segment int, voltageExcitation complex128) error {
+	if err := f.call("ExcitationVoltage", tag, segment, voltageExcitation); err != nil {
+		return err
+	}
+	f.feed(tag, segment, voltageExcitation)
+	return nil
+}
+
+// ExcitationCurrent records a current source.
+func (f *FakeEngine) ExcitationCurrent(x float64, y float64, z float64, a float64, beta float64, moment float64) error {
+	return f.call("ExcitationCurrent", x, y, z, a, beta, moment)
+}
+
+// ExcitationPlanewave records a plane wave excitation.
+func (f *FakeEngine) ExcitationPlanewave(nTheta int, nPhi int, theta float64, phi float64, eta float64, dTheta float64, dPhi float64, polRatio float64) error {
+	return f.call("ExcitationPlanewave", nTheta, nPhi, theta, phi, eta, dTheta, dPhi, polRatio)
+}
+
+// TlCard records a TL card.
+func (f *FakeEngine) TlCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	return f.call("TlCard", itmp1, itmp2, itmp3, itmp4, tmp1, tmp2, tmp3, tmp4, tmp5, tmp6)
+}
+
+// NtCard records an NT card.
+func (f *FakeEngine) NtCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	return f.call("NtCard", itmp1, itmp2, itmp3, itmp4, tmp1, tmp2, tmp3, tmp4, tmp5, tmp6)
+}
+
+// ConnectTwoPort makes the NT card for a two port network.
+func (f *FakeEngine) ConnectTwoPort(p1 Port, p2 Port, t TwoPort) error {
+	return connectTwoPort(f, p1, p2, t)
+}
+
+// XqCard records an XQ card, and runs the simulation.
+func (f *FakeEngine) XqCard(itmp1 ExecutionOption) error {
+	if err := f.call("XqCard", itmp1); err != nil {
+		return err
+	}
+	f.run()
+	f.xqCard(itmp1)
+	return nil
+}
+
+// RpCard records an RP card, and runs the simulation.
+func (f *FakeEngine) RpCard(calcMode RpCalcMode, nTheta int, nPhi int, outputFormat RpOutputFormat, normalization RpNormalization, d RpGain, a RpAveraging, theta0 float64, phi0 float64, deltaTheta float64, deltaPhi float64, radialDistance float64, gainNorm float64) error {
+	if err := f.call("RpCard", calcMode, nTheta, nPhi, outputFormat, normalization, d, a, theta0, phi0, deltaTheta, deltaPhi, radialDistance, gainNorm); err != nil {
+		return err
+	}
+	f.run()
+	f.recordPatterns(patternGrid{nTheta: nTheta, nPhi: nPhi, theta0: theta0, phi0: phi0, dTheta: deltaTheta, dPhi: deltaPhi})
+	return nil
+}
+
+// PtCard records a PT card.
+func (f *FakeEngine) PtCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	return f.call("PtCard", itmp1, itmp2, itmp3, itmp4)
+}
+
+// PqCard records a PQ card.
+func (f *FakeEngine) PqCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	return f.call("PqCard", itmp1, itmp2, itmp3, itmp4)
+}
+
+// KhCard records a KH card.
+func (f *FakeEngine) KhCard(tmp1 float64) error {
+	return f.call("KhCard", tmp1)
+}
+
+// NeCard records an NE card, and runs the simulation.
+func (f *FakeEngine) NeCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	if err := f.call("NeCard", itmp1, itmp2, itmp3, itmp4, tmp1, tmp2, tmp3, tmp4, tmp5, tmp6); err != nil {
+		return err
+	}
+	f.run()
+	return nil
+}
+
+// NhCard records an NH card, and runs the simulation.
+func (f *FakeEngine) NhCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	if err := f.call("NhCard", itmp1, itmp2, itmp3, itmp4, tmp1, tmp2, tmp3, tmp4, tmp5, tmp6); err != nil {
+		return err
+	}
+	f.run()
+	return nil
+}
+
+// CpCard records a CP card.
+func (f *FakeEngine) CpCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	return f.call("CpCard", itmp1, itmp2, itmp3, itmp4)
+}
+
+// PlCard records a PL card. No file is written.
+func (f *FakeEngine) PlCard(ploutputFilename string, itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	return f.call("PlCard", ploutputFilename, itmp1, itmp2, itmp3, itmp4)
+}
+
+// gain is the gain GainFunc gives at one point of a recorded pattern.
+func (f *FakeEngine) gain(g patternGrid, thetaIndex int, phiIndex int) float64 {
+	if f.GainFunc == nil {
+		return 0
+	}
+	return f.GainFunc(g.freqMHz, g.theta0+float64(thetaIndex)*g.dTheta, g.phi0+float64(phiIndex)*g.dPhi)
+}
+
+// Gain returns GainFunc's gain at a point of a pattern requested with
+// RpCard() or XqCard(), or GainErrno and an error if there's no such pattern.
+func (f *FakeEngine) Gain(freqIndex int, thetaIndex int, phiIndex int) (float64, error) {
+	if freqIndex < 0 || freqIndex >= len(f.patterns) {
+		return GainErrno, ErrNoPatternRequested
+	}
+	return f.gain(f.patterns[freqIndex], thetaIndex, phiIndex), nil
+}
+
+// gains returns every gain of a pattern, in dBi.
+func (f *FakeEngine) gains(freqIndex int) ([]float64, error) {
+	if freqIndex < 0 || freqIndex >= len(f.patterns) {
+		return nil, ErrNoPatternRequested
+	}
+	g := f.patterns[freqIndex]
+	p := newRadiationPattern(g)
+	gains := make([]float64, len(p.Points))
+	for i := range gains {
+		gains[i] = f.gain(g, i%p.NTheta, i/p.NTheta)
+	}
+	return gains, nil
+}
+
+// gainStat works out one of the gain statistics of a pattern, less offset dB.
+func (f *FakeEngine) gainStat(freqIndex int, offset float64, stat func([]float64) float64) (float64, error) {
+	gains, err := f.gains(freqIndex)
+	if err != nil {
+		return GainErrno, err
+	}
+	return stat(gains) - offset, nil
+}
+
+func maxGain(g []float64) float64 {
+	m := g[0]
+	for _, v := range g {
+		m = math.Max(m, v)
+	}
+	return m
+}
+
+func minGain(g []float64) float64 {
+	m := g[0]
+	for _, v := range g {
+		m = math.Min(m, v)
+	}
+	return m
+}
+
+func meanGain(g []float64) float64 {
+	s := 0.0
+	for _, v := range g {
+		s += v
+	}
+	return s / float64(len(g))
+}
+
+func sdGain(g []float64) float64 {
+	m := meanGain(g)
+	s := 0.0
+	for _, v := range g {
+		s += (v - m) * (v - m)
+	}
+	return math.Sqrt(s / float64(len(g)))
+}
+
+// halfPower is what a linearly polarized antenna loses, in dB, to each hand of
+// circular polarization.
+var halfPower = 10 * math.Log10(2)
+
+// GainMax returns the largest of GainFunc's gains over a pattern.
+func (f *FakeEngine) GainMax(freqIndex int) (float64, error) {
+	return f.gainStat(freqIndex, 0, maxGain)
+}
+
+// GainMin returns the smallest of GainFunc's gains over a pattern.
+func (f *FakeEngine) GainMin(freqIndex int) (float64, error) {
+	return f.gainStat(freqIndex, 0, minGain)
+}
+
+// GainMean returns the mean of GainFunc's gains over a pattern.
+func (f *FakeEngine) GainMean(freqIndex int) (float64, error) {
+	return f.gainStat(freqIndex, 0, meanGain)
+}
+
+// GainSd returns the standard deviation of GainFunc's gains over a pattern.
+func (f *FakeEngine) GainSd(freqIndex int) (float64, error) {
+	return f.gainStat(freqIndex, 0, sdGain)
+}
+
+func (f *FakeEngine) GainRhcpMax(freqIndex int) (float64, error) {
+	return f.gainStat(freqIndex, halfPower, maxGain)
+}
+
+func (f *FakeEngine) GainRhcpMin(freqIndex int) (float64, error) {
+	return f.gainStat(freqIndex, halfPower, minGain)
+}
+
+func (f *FakeEngine) GainRhcpMean(freqIndex int) (float64, error) {
+	return f.gainStat(freqIndex, halfPower, meanGain)
+}
+
+func (f *FakeEngine) GainRhcpSd(freqIndex int) (float64, error) {
+	return f.gainStat(freqIndex, 0, sdGain)
+}
+
+func (f *FakeEngine) GainLhcpMax(freqIndex int) (float64, error) {
+	return f.gainStat(freqIndex, halfPower, maxGain)
+}
+
+func (f *FakeEngine) GainLhcpMin(freqIndex int) (float64, error) {
+	return f.gainStat(freqIndex, halfPower, minGain)
+}
+
+func (f *FakeEngine) GainLhcpMean(freqIndex int) (float64, error) {
+	return f.gainStat(freqIndex, halfPower, meanGain)
+}
+
+func (f *FakeEngine) GainLhcpSd(freqIndex int) (float64, error) {
+	return f.gainStat(freqIndex, 0, sdGain)
+}
+
+// Impedance returns ImpedanceFunc's impedance at one of the frequencies of the
+// FR card, once the simulation has been run.
+func (f *FakeEngine) Impedance(freqIndex int) (complex128, error) {
+	if !f.ran {
+		return complex(GainErrno, GainErrno), errors.New("the simulation hasn't been run")
+	}
+	freqs := f.Frequencies()
+	if freqs == nil {
+		freqs = []float64{0}
+	}
+	if freqIndex < 0 || freqIndex >= len(freqs) {
+		return complex(GainErrno, GainErrno), fmt.Errorf("frequency index %d out of range; there are %d", freqIndex, len(freqs))
+	}
+	if f.ImpedanceFunc == nil {
+		return 50, nil
+	}
+	return f.ImpedanceFunc(freqs[freqIndex]), nil
+}
+
+// ImpedanceSweep returns ImpedanceFunc's impedance at each of the frequencies
+// of the FR card.
+func (f *FakeEngine) ImpedanceSweep() ([]ImpedancePoint, error) {
+	return f.impedanceSweep(f.Impedance)
+}
+
+// RadiationPattern returns GainFunc's gain at every point of a pattern.
+func (f *FakeEngine) RadiationPattern(freqIndex int) (*RadiationPattern, error) {
+	return f.radiationPattern(freqIndex, f.Gain)
+}
+
+// RadiationPatterns returns all of the patterns requested so far.
+func (f *FakeEngine) RadiationPatterns() ([]*RadiationPattern, error) {
+	return f.radiationPatterns(f.Gain)
+}
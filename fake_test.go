@@ -0,0 +1,158 @@
+package necpp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFakeEngineModel(t *testing.T) {
+	f := &FakeEngine{
+		ImpedanceFunc: func(freqMHz float64) complex128 { return complex(freqMHz, -freqMHz) },
+		GainFunc:      func(freqMHz float64, theta float64, phi float64) float64 { return -theta / 10 },
+		Report:        listingOutput,
+	}
+	m := simpleModel()
+	m.SetFrequency(10, 5, 3)
+	res, err := m.RunEngine(context.Background(), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Wire", "GeometryComplete", "GnCard", "FrCard", "ExcitationVoltage", "RpCard"}
+	if len(f.Calls) != len(want) {
+		t.Fatalf("got %d calls, should have been %d", len(f.Calls), len(want))
+	}
+	for i, c := range f.Calls {
+		if c.Method != want[i] {
+			t.Errorf("call %d was %s, should have been %s", i, c.Method, want[i])
+		}
+	}
+	if fr := f.Called("FrCard"); len(fr) != 1 || fr[0].Args[1] != 3 || fr[0].Args[2] != 10.0 {
+		t.Errorf("the FR card was %v", fr)
+	}
+
+	if len(res.Impedance) != 3 || res.Impedance[2].Impedance != complex(20, -20) {
+		t.Errorf("impedance sweep was %v", res.Impedance)
+	}
+	if len(res.Patterns) != 3 {
+		t.Fatalf("got %d patterns, should have been 3", len(res.Patterns))
+	}
+	p := res.Patterns[1]
+	if p.FreqMHz != 15 || p.Point(10, 0).Total != -1 || p.MaxGain().Theta != 0 {
+		t.Errorf("pattern at %g MHz had %g dBi at 10 degrees", p.FreqMHz, p.Point(10, 0).Total)
+	}
+	if len(res.Geometry.Wires) != 1 || len(res.Geometry.Feeds) != 1 {
+		t.Errorf("geometry has %d wires and %d feeds", len(res.Geometry.Wires), len(res.Geometry.Feeds))
+	}
+	if len(res.Listing.Inputs) == 0 {
+		t.Errorf("the report wasn't parsed into the listing")
+	}
+}
+
+func TestFakeEngineGains(t *testing.T) {
+	f := &FakeEngine{GainFunc: func(freqMHz float64, theta float64, phi float64) float64 { return phi }}
+	if _, err := f.GainMax(0); err != ErrNoPatternRequested {
+		t.Errorf("gain before a pattern was requested gave %v, should have been %v", err, ErrNoPatternRequested)
+	}
+	if _, err := f.Impedance(0); err == nil {
+		t.Errorf("impedance before running should have been an error")
+	}
+	if err := f.RpCard(Normal, 1, 4, MajorMinor, NoNormalization, PowerGain, NoAvg, 90, 0, 0, 10, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	stats := []struct {
+		name string
+		get  func(int) (float64, error)
+		want float64
+	}{
+		{"max", f.GainMax, 30},
+		{"min", f.GainMin, 0},
+		{"mean", f.GainMean, 15},
+		{"sd", f.GainSd, math.Sqrt(125)},
+		{"rhcp max", f.GainRhcpMax, 30 - halfPower},
+		{"lhcp sd", f.GainLhcpSd, math.Sqrt(125)},
+	}
+	for _, s := range stats {
+		got, err := s.get(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(got-s.want) > 1e-9 {
+			t.Errorf("%s gain was %g, should have been %g", s.name, got, s.want)
+		}
+	}
+	if z, err := f.Impedance(0); err != nil || z != 50 {
+		t.Errorf("default impedance was %v, %v, should have been 50 ohms", z, err)
+	}
+}
+
+func TestFakeEngineErrors(t *testing.T) {
+	bad := errors.New("bad wire")
+	f := &FakeEngine{Errors: map[string]error{"Wire": bad}}
+	if _, err := simpleModel().RunEngine(context.Background(), f); err != bad {
+		t.Errorf("running gave %v, should have been %v", err, bad)
+	}
+	if len(f.Geometry().Wires) != 0 {
+		t.Errorf("a failed wire was added to the geometry")
+	}
+}
+
+func TestOutputLimit(t *testing.T) {
+	f := &FakeEngine{Report: "RUN\nPOWER BUDGET\n"}
+	f.SetOutputLimit(40)
+	for i := 0; i < 10; i++ {
+		if err := f.XqCard(NoPattern); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out := f.Output(); out != strings.Repeat(f.Report, 2) {
+		t.Errorf("kept %q, should have been the last two whole runs", out)
+	}
+
+	f.SetOutputLimit(-1)
+	for i := 0; i < 10; i++ {
+		f.XqCard(NoPattern)
+	}
+	if out := f.Output(); out != strings.Repeat(f.Report, 12) {
+		t.Errorf("kept %d bytes with no limit, should have been %d", len(out), 12*len(f.Report))
+	}
+}
+
+func TestOutputLimitKeepsResults(t *testing.T) {
+	f := new(FakeEngine)
+	f.SetOutputLimit(100)
+	for i := 0; i < 10; i++ {
+		f.Report = fmt.Sprintf("FREQUENCY= %d.0000E+00 MHZ\nPOWER BUDGET\nINPUT POWER   =  %d.0000E+00 Watts\nEND\n", i+1, i+1)
+		if err := f.XqCard(NoPattern); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a run that doesn't say what frequency it's at is still at the last one
+	f.Report = "POWER BUDGET\nINPUT POWER   =  1.1000E+01 Watts\nEND\n"
+	if err := f.XqCard(NoPattern); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Output()) > 100 {
+		t.Errorf("kept %d bytes of output, past the limit", len(f.Output()))
+	}
+	for i := 0; i < 11; i++ {
+		p, err := f.InputPower(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p != float64(i+1) {
+			t.Errorf("input power %d was %g, should have been %d", i, p, i+1)
+		}
+	}
+	l, err := f.Listing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Power) != 11 || l.Power[10].FreqMHz != 10 {
+		t.Errorf("the listing had %d power budgets, the last at %g MHz; should have been 11, at 10 MHz", len(l.Power), l.Power[len(l.Power)-1].FreqMHz)
+	}
+}
@@ -0,0 +1,169 @@
+/*
+Package feedline models the lossy transmission line between an antenna and the
+transmitter, so that the impedance, VSWR and loss can be given where they're
+actually measured: at the shack end of the line.
+
+Cables are described by their characteristic impedance, velocity factor and
+matched loss. The matched loss follows the usual two term model, with one part
+rising with the square root of frequency (conductor loss, from skin effect)
+and one rising in proportion to it (dielectric loss). The line itself is
+treated as having a real characteristic impedance, which is close enough above
+a megahertz or so.
+*/
+package feedline
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+
+	"github.com/ctdk/go-libnecpp"
+)
+
+// metersPer100Feet is for converting the loss figures cable makers give.
+const metersPer100Feet = 30.48
+
+// Cable is a kind of transmission line. K1 and K2 give its matched loss in dB
+// per 100 feet, as K1*sqrt(f) + K2*f with f in MHz.
+type Cable struct {
+	Name           string
+	Z0             float64
+	VelocityFactor float64
+	K1             float64
+	K2             float64
+}
+
+// Some common cables, with loss figures fitted to typical makers' data.
+var (
+	RG58      = Cable{Name: "RG-58", Z0: 50, VelocityFactor: 0.66, K1: 0.4, K2: 0.009}
+	RG213     = Cable{Name: "RG-213", Z0: 50, VelocityFactor: 0.66, K1: 0.18, K2: 0.003}
+	LMR400    = Cable{Name: "LMR-400", Z0: 50, VelocityFactor: 0.85, K1: 0.122, K2: 0.00026}
+	Window450 = Cable{Name: "450 ohm window line", Z0: 450, VelocityFactor: 0.91, K1: 0.027, K2: 0.0011}
+	Ladder600 = Cable{Name: "600 ohm open wire line", Z0: 600, VelocityFactor: 0.98, K1: 0.011, K2: 0.0003}
+)
+
+// LossPoint is a matched loss figure from a cable's data sheet.
+type LossPoint struct {
+	FreqMHz    float64
+	DBPer100Ft float64
+}
+
+// FitCable makes a Cable out of the matched loss figures from a data sheet,
+// by a least squares fit of K1 and K2. It needs figures at two frequencies at
+// least.
+func FitCable(name string, z0 float64, vf float64, points []LossPoint) (Cable, error) {
+	if len(points) < 2 {
+		return Cable{}, errors.New("at least two loss figures are needed")
+	}
+	// the normal equations for loss = K1 sqrt(f) + K2 f
+	var a11, a12, a22, b1, b2 float64
+	for _, p := range points {
+		s, f := math.Sqrt(p.FreqMHz), p.FreqMHz
+		a11 += s * s
+		a12 += s * f
+		a22 += f * f
+		b1 += s * p.DBPer100Ft
+		b2 += f * p.DBPer100Ft
+	}
+	det := a11*a22 - a12*a12
+	if det == 0 {
+		return Cable{}, errors.New("the loss figures need to be at different frequencies")
+	}
+	return Cable{
+		Name:           name,
+		Z0:             z0,
+		VelocityFactor: vf,
+		K1:             (b1*a22 - b2*a12) / det,
+		K2:             (a11*b2 - a12*b1) / det,
+	}, nil
+}
+
+// MatchedLoss returns the cable's loss, in dB per 100 feet, when it's
+// terminated in its characteristic impedance.
+func (c Cable) MatchedLoss(freqMHz float64) float64 {
+	return c.K1*math.Sqrt(freqMHz) + c.K2*freqMHz
+}
+
+// gamma returns the propagation constant of the cable, per meter.
+func (c Cable) gamma(freqMHz float64) complex128 {
+	alpha := c.MatchedLoss(freqMHz) / metersPer100Feet / (20 / math.Ln10)
+	vf := c.VelocityFactor
+	if vf <= 0 {
+		vf = 1
+	}
+	beta := 2 * math.Pi * freqMHz / (necpp.SpeedOfLight * vf)
+	return complex(alpha, beta)
+}
+
+// Feedline is a length, in meters, of cable.
+type Feedline struct {
+	Cable  Cable
+	Length float64
+}
+
+// transfer returns the voltage and current at the shack end of the line for a
+// load of zl drawing 1 amp at the antenna end.
+func (f Feedline) transfer(zl complex128, freqMHz float64) (complex128, complex128) {
+	gl := f.Cable.gamma(freqMHz) * complex(f.Length, 0)
+	z0 := complex(f.Cable.Z0, 0)
+	ch, sh := cmplx.Cosh(gl), cmplx.Sinh(gl)
+	return ch*zl + z0*sh, sh*zl/z0 + ch
+}
+
+// InputImpedance returns the impedance at the shack end of the line with a
+// load of zl on the antenna end.
+func (f Feedline) InputImpedance(zl complex128, freqMHz float64) complex128 {
+	v, i := f.transfer(zl, freqMHz)
+	return v / i
+}
+
+// Loss returns the total loss in the line, in dB, with a load of zl on the
+// antenna end. This is the matched loss plus the extra loss caused by the
+// standing waves on the line.
+func (f Feedline) Loss(zl complex128, freqMHz float64) float64 {
+	v, i := f.transfer(zl, freqMHz)
+	pIn := real(v * cmplx.Conj(i))
+	pOut := real(zl)
+	return 10 * math.Log10(pIn/pOut)
+}
+
+// FeedPoint is what's seen at the shack end of a feedline at one frequency.
+// VSWR is measured against the reference impedance given to Sweep(), and
+// AntennaVSWR is the VSWR on the line at the antenna end, against the cable's
+// own impedance.
+type FeedPoint struct {
+	FreqMHz     float64
+	Impedance   complex128
+	VSWR        float64
+	AntennaVSWR float64
+	MatchedLoss float64 // dB
+	TotalLoss   float64 // dB
+}
+
+// Sweep runs an antenna's impedance sweep through the feedline.
+func (f Feedline) Sweep(sweep []necpp.ImpedancePoint, z0 float64) []FeedPoint {
+	out := make([]FeedPoint, len(sweep))
+	for i, p := range sweep {
+		z := f.InputImpedance(p.Impedance, p.FreqMHz)
+		out[i] = FeedPoint{
+			FreqMHz:     p.FreqMHz,
+			Impedance:   z,
+			VSWR:        necpp.VSWR(z, z0),
+			AntennaVSWR: necpp.VSWR(p.Impedance, f.Cable.Z0),
+			MatchedLoss: f.Cable.MatchedLoss(p.FreqMHz) * f.Length / metersPer100Feet,
+			TotalLoss:   f.Loss(p.Impedance, p.FreqMHz),
+		}
+	}
+	return out
+}
+
+// ShackSweep returns the impedance sweep as seen at the shack end of the line,
+// which can be used anywhere an antenna's sweep can, such as WriteS1P() or
+// CompareImpedance() against measurements taken at the shack end.
+func (f Feedline) ShackSweep(sweep []necpp.ImpedancePoint) []necpp.ImpedancePoint {
+	out := make([]necpp.ImpedancePoint, len(sweep))
+	for i, p := range sweep {
+		out[i] = necpp.ImpedancePoint{FreqMHz: p.FreqMHz, Impedance: f.InputImpedance(p.Impedance, p.FreqMHz)}
+	}
+	return out
+}
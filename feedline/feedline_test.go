@@ -0,0 +1,71 @@
+package feedline
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/ctdk/go-libnecpp"
+)
+
+func TestMatched(t *testing.T) {
+	f := Feedline{Cable: RG213, Length: 30.48}
+	if z := f.InputImpedance(50, 14); cmplx.Abs(z-50) > 1e-9 {
+		t.Errorf("a matched line should have looked like 50 ohms, got %v", z)
+	}
+	want := RG213.MatchedLoss(14)
+	if l := f.Loss(50, 14); math.Abs(l-want) > 1e-9 {
+		t.Errorf("matched loss over 100 ft should have been %g dB, got %g", want, l)
+	}
+}
+
+func TestMismatched(t *testing.T) {
+	f := Feedline{Cable: RG58, Length: 25}
+	zl := complex(150, 60)
+	ml := RG58.MatchedLoss(28) * f.Length / metersPer100Feet
+	g := cmplx.Abs((zl - 50) / (zl + 50))
+	// the usual formula for total loss with a mismatched load
+	a := math.Pow(10, ml/10)
+	want := 10 * math.Log10((a*a-g*g)/(a*(1-g*g)))
+	if l := f.Loss(zl, 28); math.Abs(l-want) > 1e-6 {
+		t.Errorf("total loss should have been %g dB, got %g", want, l)
+	}
+
+	// the VSWR is lower at the shack end, because of the loss
+	pts := f.Sweep([]necpp.ImpedancePoint{{FreqMHz: 28, Impedance: zl}}, 50)
+	if pts[0].VSWR >= pts[0].AntennaVSWR {
+		t.Errorf("VSWR at the shack end (%g) should have been lower than at the antenna (%g)", pts[0].VSWR, pts[0].AntennaVSWR)
+	}
+	if pts[0].TotalLoss <= pts[0].MatchedLoss {
+		t.Errorf("total loss (%g) should have been more than the matched loss (%g)", pts[0].TotalLoss, pts[0].MatchedLoss)
+	}
+}
+
+func TestHalfWave(t *testing.T) {
+	lossless := Cable{Name: "lossless", Z0: 50, VelocityFactor: 0.66}
+	f := Feedline{Cable: lossless, Length: 0.5 * necpp.SpeedOfLight * 0.66 / 7.1}
+	zl := complex(30, -20)
+	if z := f.InputImpedance(zl, 7.1); cmplx.Abs(z-zl) > 1e-6 {
+		t.Errorf("a lossless half wave line should have repeated %v, got %v", zl, z)
+	}
+	if s := f.ShackSweep([]necpp.ImpedancePoint{{FreqMHz: 7.1, Impedance: zl}}); cmplx.Abs(s[0].Impedance-zl) > 1e-6 {
+		t.Errorf("ShackSweep gave %v rather than %v", s[0].Impedance, zl)
+	}
+}
+
+func TestFitCable(t *testing.T) {
+	var pts []LossPoint
+	for _, f := range []float64{10, 50, 100, 400} {
+		pts = append(pts, LossPoint{FreqMHz: f, DBPer100Ft: LMR400.MatchedLoss(f)})
+	}
+	c, err := FitCable("fitted", 50, 0.85, pts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(c.K1-LMR400.K1) > 1e-9 || math.Abs(c.K2-LMR400.K2) > 1e-9 {
+		t.Errorf("fit gave K1 %g K2 %g, should have been %g and %g", c.K1, c.K2, LMR400.K1, LMR400.K2)
+	}
+	if _, err := FitCable("bad", 50, 0.85, pts[:1]); err == nil {
+		t.Errorf("one loss figure should have been an error")
+	}
+}
@@ -0,0 +1,304 @@
+package necpp
+
+import (
+	"math"
+)
+
+// WireSpec is a straight wire, as given to Wire().
+type WireSpec struct {
+	Tag      int
+	Segments int
+	X1       float64
+	Y1       float64
+	Z1       float64
+	X2       float64
+	Y2       float64
+	Z2       float64
+	Radius   float64
+	RDel     float64 // ratio of the lengths of successive segments
+	RRad     float64 // ratio of the radii of successive segments
+}
+
+// PatchSpec is a surface patch, as given to SpCard() (and ScCard()). The
+// corners of Rectangular, Triangular and Quadrilateral patches are all filled
+// in, including the fourth corner of a rectangle that NEC works out itself.
+// For Arbitrary patches, Corners holds just the center of the patch, with its
+// orientation and size in Elevation, Azimuth and Area.
+type PatchSpec struct {
+	Shape     PatchType
+	Corners   [][3]float64
+	Elevation float64 // degrees, of the outward normal above the XY plane
+	Azimuth   float64 // degrees, of the outward normal from the X axis
+	Area      float64 // square meters
+}
+
+// Feed is a voltage source on the antenna.
+type Feed struct {
+	Port
+	Voltage complex128
+}
+
+// Load is the loading given to LdCard(), with the values in the same order.
+type Load struct {
+	Type int
+	Tag  int
+	From int
+	To   int
+	R    float64
+	L    float64
+	C    float64
+}
+
+// Geometry is the structure of an antenna, as built up by the geometry methods
+// on NecppCtx, along with where it's fed and loaded. It's mostly useful for
+// checking a model over by drawing it.
+type Geometry struct {
+	Wires   []WireSpec
+	Patches []PatchSpec
+	Feeds   []Feed
+	Loads   []Load
+}
+
+// Segment is a single segment of a wire, with the two ways NEC has of
+// numbering it: an absolute segment number, and the number of the segment
+// among all of the segments that have its tag (which is what's used with a
+// non-zero tag in a Port).
+type Segment struct {
+	Number   int
+	Tag      int
+	TagIndex int
+	Start    [3]float64
+	End      [3]float64
+	Radius   float64
+}
+
+// Center returns the midpoint of the segment.
+func (s Segment) Center() [3]float64 {
+	return [3]float64{(s.Start[0] + s.End[0]) / 2, (s.Start[1] + s.End[1]) / 2, (s.Start[2] + s.End[2]) / 2}
+}
+
+// Length returns the length of the segment.
+func (s Segment) Length() float64 {
+	return dist(s.Start, s.End)
+}
+
+// Copy returns a deep copy of the geometry.
+func (g *Geometry) Copy() *Geometry {
+	c := &Geometry{
+		Wires: append([]WireSpec(nil), g.Wires...),
+		Feeds: append([]Feed(nil), g.Feeds...),
+		Loads: append([]Load(nil), g.Loads...),
+	}
+	for _, p := range g.Patches {
+		p.Corners = append([][3]float64(nil), p.Corners...)
+		c.Patches = append(c.Patches, p)
+	}
+	return c
+}
+
+// Segments splits all of the wires up into their segments, numbered the way
+// NEC numbers them. Tapered wires (with RDel other than 1) are split the way
+// NEC does, with each segment RDel times as long as the one before it.
+func (g *Geometry) Segments() []Segment {
+	var segs []Segment
+	tagCount := make(map[int]int)
+	for _, w := range g.Wires {
+		n := w.Segments
+		if n < 1 {
+			continue
+		}
+		start := [3]float64{w.X1, w.Y1, w.Z1}
+		end := [3]float64{w.X2, w.Y2, w.Z2}
+		rdel := w.RDel
+		if rdel <= 0 {
+			rdel = 1
+		}
+		rrad := w.RRad
+		if rrad <= 0 {
+			rrad = 1
+		}
+		// the fraction of the wire's length the first segment takes up
+		frac := 1 / float64(n)
+		if rdel != 1 {
+			frac = (1 - rdel) / (1 - math.Pow(rdel, float64(n)))
+		}
+		pos := 0.0
+		radius := w.Radius
+		for i := 0; i < n; i++ {
+			next := pos + frac
+			if i == n-1 {
+				next = 1
+			}
+			tagCount[w.Tag]++
+			segs = append(segs, Segment{
+				Number:   len(segs) + 1,
+				Tag:      w.Tag,
+				TagIndex: tagCount[w.Tag],
+				Start:    lerp(start, end, pos),
+				End:      lerp(start, end, next),
+				Radius:   radius,
+			})
+			pos = next
+			frac *= rdel
+			radius *= rrad
+		}
+	}
+	return segs
+}
+
+// FindSegment finds the segment a port is on.
+func (g *Geometry) FindSegment(p Port) (Segment, bool) {
+	for _, s := range g.Segments() {
+		if (p.Tag == 0 && s.Number == p.Segment) || (p.Tag != 0 && s.Tag == p.Tag && s.TagIndex == p.Segment) {
+			return s, true
+		}
+	}
+	return Segment{}, false
+}
+
+// move applies a GM card to the geometry.
+func (g *Geometry) move(itsi int, nrpt int, rox float64, roy float64, roz float64, xs float64, ys float64, zs float64, its int) {
+	rot := rotationMatrix(rox, roy, roz)
+	tf := func(p [3]float64) [3]float64 {
+		r := matVec(rot, p)
+		return [3]float64{r[0] + xs, r[1] + ys, r[2] + zs}
+	}
+	first := 0
+	if its != 0 {
+		first = -1
+		for i, w := range g.Wires {
+			if w.Tag == its {
+				first = i
+				break
+			}
+		}
+		if first < 0 {
+			return
+		}
+	}
+	if nrpt == 0 {
+		for i := first; i < len(g.Wires); i++ {
+			g.Wires[i] = transformWire(g.Wires[i], tf, itsi)
+		}
+		// the patches always come after the wires, so they move along
+		for i := range g.Patches {
+			g.Patches[i] = transformPatch(g.Patches[i], tf, rot)
+		}
+		return
+	}
+	wires := g.Wires[first:]
+	patches := g.Patches
+	for k := 0; k < nrpt; k++ {
+		var nw []WireSpec
+		for _, w := range wires {
+			nw = append(nw, transformWire(w, tf, itsi))
+		}
+		var np []PatchSpec
+		for _, p := range patches {
+			np = append(np, transformPatch(p, tf, rot))
+		}
+		g.Wires = append(g.Wires, nw...)
+		g.Patches = append(g.Patches, np...)
+		wires, patches = nw, np
+	}
+}
+
+// reflect applies a GX card to the geometry. Reflections along Z are done
+// first, then Y, then X, doubling the tag increment after each one.
+func (g *Geometry) reflect(i1 int, i2 int) {
+	axes := []struct {
+		on   bool
+		axis int
+	}{
+		{i2%10 != 0, 2},
+		{(i2/10)%10 != 0, 1},
+		{(i2/100)%10 != 0, 0},
+	}
+	inc := i1
+	for _, a := range axes {
+		if !a.on {
+			continue
+		}
+		var m [3][3]float64
+		for i := 0; i < 3; i++ {
+			m[i][i] = 1
+		}
+		m[a.axis][a.axis] = -1
+		tf := func(p [3]float64) [3]float64 { return matVec(m, p) }
+		n := len(g.Wires)
+		for i := 0; i < n; i++ {
+			g.Wires = append(g.Wires, transformWire(g.Wires[i], tf, inc))
+		}
+		np := len(g.Patches)
+		for i := 0; i < np; i++ {
+			g.Patches = append(g.Patches, transformPatch(g.Patches[i], tf, m))
+		}
+		inc *= 2
+	}
+}
+
+func transformWire(w WireSpec, tf func([3]float64) [3]float64, tagInc int) WireSpec {
+	p1 := tf([3]float64{w.X1, w.Y1, w.Z1})
+	p2 := tf([3]float64{w.X2, w.Y2, w.Z2})
+	w.X1, w.Y1, w.Z1 = p1[0], p1[1], p1[2]
+	w.X2, w.Y2, w.Z2 = p2[0], p2[1], p2[2]
+	if w.Tag != 0 {
+		w.Tag += tagInc
+	}
+	return w
+}
+
+func transformPatch(p PatchSpec, tf func([3]float64) [3]float64, lin [3][3]float64) PatchSpec {
+	corners := make([][3]float64, len(p.Corners))
+	for i, c := range p.Corners {
+		corners[i] = tf(c)
+	}
+	p.Corners = corners
+	if p.Shape == Arbitrary {
+		el := p.Elevation * math.Pi / 180
+		az := p.Azimuth * math.Pi / 180
+		nrm := matVec(lin, [3]float64{math.Cos(el) * math.Cos(az), math.Cos(el) * math.Sin(az), math.Sin(el)})
+		p.Elevation = math.Asin(math.Max(-1, math.Min(1, nrm[2]))) * 180 / math.Pi
+		p.Azimuth = math.Atan2(nrm[1], nrm[0]) * 180 / math.Pi
+	}
+	return p
+}
+
+// rotationMatrix returns the matrix for rotating about the X, Y and Z axes, in
+// that order, by the given angles in degrees.
+func rotationMatrix(rox float64, roy float64, roz float64) [3][3]float64 {
+	a, b, c := rox*math.Pi/180, roy*math.Pi/180, roz*math.Pi/180
+	rx := [3][3]float64{{1, 0, 0}, {0, math.Cos(a), -math.Sin(a)}, {0, math.Sin(a), math.Cos(a)}}
+	ry := [3][3]float64{{math.Cos(b), 0, math.Sin(b)}, {0, 1, 0}, {-math.Sin(b), 0, math.Cos(b)}}
+	rz := [3][3]float64{{math.Cos(c), -math.Sin(c), 0}, {math.Sin(c), math.Cos(c), 0}, {0, 0, 1}}
+	return matMul3(rz, matMul3(ry, rx))
+}
+
+func matMul3(a [3][3]float64, b [3][3]float64) [3][3]float64 {
+	var m [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				m[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return m
+}
+
+func matVec(m [3][3]float64, v [3]float64) [3]float64 {
+	return [3]float64{
+		m[0][0]*v[0] + m[0][1]*v[1] + m[0][2]*v[2],
+		m[1][0]*v[0] + m[1][1]*v[1] + m[1][2]*v[2],
+		m[2][0]*v[0] + m[2][1]*v[1] + m[2][2]*v[2],
+	}
+}
+
+func lerp(a [3]float64, b [3]float64, t float64) [3]float64 {
+	return [3]float64{a[0] + (b[0]-a[0])*t, a[1] + (b[1]-a[1])*t, a[2] + (b[2]-a[2])*t}
+}
+
+func dist(a [3]float64, b [3]float64) float64 {
+	dx, dy, dz := b[0]-a[0], b[1]-a[1], b[2]-a[2]
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
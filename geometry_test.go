@@ -0,0 +1,54 @@
+package necpp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSegments(t *testing.T) {
+	g := &Geometry{Wires: []WireSpec{
+		{Tag: 1, Segments: 4, Z2: 1, Radius: 0.01},
+		{Tag: 2, Segments: 3, X2: 7, Radius: 0.01, RDel: 2, RRad: 0.5},
+	}}
+	segs := g.Segments()
+	if len(segs) != 7 {
+		t.Fatalf("there were %d segments, should have been 7", len(segs))
+	}
+	if segs[4].Number != 5 || segs[4].TagIndex != 1 {
+		t.Errorf("segment 5 was numbered %d, tag index %d", segs[4].Number, segs[4].TagIndex)
+	}
+	// a tapered wire of 7 m with each segment twice as long as the last
+	// should be split up 1, 2, 4
+	for i, l := range []float64{1, 2, 4} {
+		if got := segs[4+i].Length(); math.Abs(got-l) > 1e-9 {
+			t.Errorf("tapered segment %d was %g m long, should have been %g", i+1, got, l)
+		}
+	}
+	if r := segs[6].Radius; math.Abs(r-0.0025) > 1e-12 {
+		t.Errorf("last tapered segment's radius was %g, should have been 0.0025", r)
+	}
+	s, ok := g.FindSegment(Port{Tag: 1, Segment: 2})
+	if !ok || s.Center() != [3]float64{0, 0, 0.375} {
+		t.Errorf("tag 1 segment 2 was %v, %v", s, ok)
+	}
+}
+
+func TestMoveAndReflect(t *testing.T) {
+	g := &Geometry{Wires: []WireSpec{{Tag: 1, Segments: 1, X1: 1, X2: 2}}}
+	// two copies, each rotated 90 degrees about Z from the last
+	g.move(1, 2, 0, 0, 90, 0, 0, 0, 1)
+	if len(g.Wires) != 3 {
+		t.Fatalf("there were %d wires, should have been 3", len(g.Wires))
+	}
+	w := g.Wires[2]
+	if w.Tag != 3 || math.Abs(w.X1+1) > 1e-9 || math.Abs(w.Y1) > 1e-9 {
+		t.Errorf("second copy was %+v", w)
+	}
+	g.reflect(10, 100)
+	if len(g.Wires) != 6 {
+		t.Fatalf("there were %d wires after reflecting, should have been 6", len(g.Wires))
+	}
+	if w := g.Wires[3]; w.Tag != 11 || w.X1 != -1 {
+		t.Errorf("reflected wire was %+v", w)
+	}
+}
@@ -0,0 +1,173 @@
+package necpp
+
+import (
+	"errors"
+)
+
+// Soil is the electrical makeup of a ground medium: its relative permittivity
+// (dielectric constant) and its conductivity in siemens (mhos) per meter.
+type Soil struct {
+	Permittivity float64
+	Conductivity float64
+}
+
+// Ground presets, so everybody's "average ground" is the same. These are the
+// kinds of ground ITU-R P.527 gives, with the values its curves give at 1 MHz.
+// Some of them change through the HF bands, so for an accurate model at a
+// higher frequency read the values off P.527's curves instead.
+var (
+	SeaWater        = Soil{Permittivity: 70, Conductivity: 5}
+	FreshWater      = Soil{Permittivity: 80, Conductivity: 0.003}
+	WetGround       = Soil{Permittivity: 30, Conductivity: 0.01}
+	MediumDryGround = Soil{Permittivity: 15, Conductivity: 0.001}
+	VeryDryGround   = Soil{Permittivity: 3, Conductivity: 0.0001}
+)
+
+// More ground presets, with the values amateur antenna modelling has long used,
+// as given in The ARRL Antenna Book and by EZNEC. They don't come from P.527.
+var (
+	GoodGround    = Soil{Permittivity: 20, Conductivity: 0.0303}
+	AverageGround = Soil{Permittivity: 13, Conductivity: 0.005}
+	PoorGround    = Soil{Permittivity: 13, Conductivity: 0.002}
+	CityGround    = Soil{Permittivity: 5, Conductivity: 0.001}
+	DrySand       = Soil{Permittivity: 10, Conductivity: 0.002}
+)
+
+// GroundScreen is a ground screen of radial wires centered on the origin,
+// lying on the ground. It's only taken into account in the radiation pattern,
+// as a change to the ground's reflection coefficient; it isn't part of the
+// structure, and has no effect on the antenna's impedance.
+type GroundScreen struct {
+	Radials    int
+	Radius     float64 // the length of each radial, in meters
+	WireRadius float64 // meters
+}
+
+// SecondMedium is a second ground medium, beyond a cliff. Distance is how far
+// the change of medium is from the origin, and Depth how far below the first
+// medium the second one's surface is, both in meters. With Circular the change
+// of medium is a circle around the origin; otherwise it's a straight line,
+// parallel to the Y axis, at X = Distance. Like a GroundScreen, it's only
+// taken into account in the radiation pattern.
+type SecondMedium struct {
+	Soil
+	Distance float64
+	Depth    float64
+	Circular bool
+}
+
+// Ground describes the ground under the antenna. Type is Perfect, Finite or
+// FiniteSomNorton; a Ground with a Type of Nullified puts the antenna back in
+// free space. Soil isn't used for a Perfect ground, and neither a Screen nor a
+// SecondMedium can go with one.
+type Ground struct {
+	Type         GroundTypeFlag
+	Soil         Soil
+	Screen       *GroundScreen
+	SecondMedium *SecondMedium
+}
+
+// gnArgs and gdArgs are the parameters of the GN and GD cards a Ground makes.
+type gnArgs struct {
+	iperf GroundTypeFlag
+	nradl int
+	f     [6]float64
+}
+
+type gdArgs struct {
+	f [4]float64
+}
+
+// cards works out the GN card, and the GD card if one is needed, for the
+// ground. The GN card can hold either a radial screen or a second medium, so
+// when there are both the second medium goes on a GD card.
+func (g Ground) cards() (gnArgs, *gdArgs, error) {
+	gn := gnArgs{iperf: g.Type}
+	switch g.Type {
+	case Nullified:
+		return gn, nil, nil
+	case Perfect:
+		if g.Screen != nil || g.SecondMedium != nil {
+			return gn, nil, errors.New("a perfect ground can't have a radial screen or a second medium")
+		}
+		return gn, nil, nil
+	case Finite, FiniteSomNorton:
+	default:
+		return gn, nil, errors.New("unknown ground type")
+	}
+	if err := g.Soil.check(); err != nil {
+		return gn, nil, err
+	}
+	gn.f[0], gn.f[1] = g.Soil.Permittivity, g.Soil.Conductivity
+
+	var gd *gdArgs
+	if m := g.SecondMedium; m != nil {
+		if err := m.Soil.check(); err != nil {
+			return gn, nil, err
+		}
+		if m.Distance < 0 || m.Depth < 0 {
+			return gn, nil, errors.New("the second medium's distance and depth can't be negative")
+		}
+		gd = &gdArgs{f: [4]float64{m.Permittivity, m.Conductivity, m.Distance, m.Depth}}
+	}
+	if s := g.Screen; s != nil {
+		if s.Radials < 1 || s.Radius <= 0 || s.WireRadius <= 0 {
+			return gn, nil, errors.New("a radial screen needs at least one radial, and a positive length and wire radius")
+		}
+		gn.nradl = s.Radials
+		gn.f[2], gn.f[3] = s.Radius, s.WireRadius
+		return gn, gd, nil
+	}
+	if gd != nil {
+		copy(gn.f[2:], gd.f[:])
+	}
+	return gn, nil, nil
+}
+
+func (s Soil) check() error {
+	if s.Permittivity < 1 || s.Conductivity < 0 {
+		return errors.New("ground permittivity must be at least 1, and conductivity can't be negative")
+	}
+	return nil
+}
+
+// CalcMode returns the RpCalcMode that takes the ground's radial screen and
+// second medium into account in a radiation pattern. They're ignored by RP
+// cards with any other mode.
+func (g Ground) CalcMode() RpCalcMode {
+	screen := g.Screen != nil
+	switch {
+	case g.SecondMedium == nil && screen:
+		return RadialScreen
+	case g.SecondMedium == nil:
+		return Normal
+	case g.SecondMedium.Circular && screen:
+		return RadialCircularCliff
+	case g.SecondMedium.Circular:
+		return CircularCliff
+	case screen:
+		return RadialLinearCliff
+	}
+	return LinearCliff
+}
+
+// SetGround puts the antenna over a ground, making the GN card (and GD card,
+// if needed) for it. Remember to use the ground's CalcMode() on the RP cards
+// if it has a radial screen or second medium.
+func (n *NecppCtx) SetGround(g Ground) error {
+	return setGround(n, g)
+}
+
+func setGround(e Engine, g Ground) error {
+	gn, gd, err := g.cards()
+	if err != nil {
+		return err
+	}
+	if err := e.GnCard(gn.iperf, gn.nradl, gn.f[0], gn.f[1], gn.f[2], gn.f[3], gn.f[4], gn.f[5]); err != nil {
+		return err
+	}
+	if gd != nil {
+		return e.GdCard(gd.f[0], gd.f[1], gd.f[2], gd.f[3])
+	}
+	return nil
+}
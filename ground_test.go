@@ -0,0 +1,40 @@
+package necpp
+
+import (
+	"testing"
+)
+
+func TestGroundCards(t *testing.T) {
+	second := &SecondMedium{Soil: SeaWater, Distance: 100, Depth: 30}
+	screen := &GroundScreen{Radials: 60, Radius: 20, WireRadius: 0.001}
+
+	gn, gd, err := Ground{Type: Finite, Soil: AverageGround, SecondMedium: second}.cards()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gd != nil || gn.nradl != 0 || gn.f != [6]float64{13, 0.005, 70, 5, 100, 30} {
+		t.Errorf("second medium on its own should all go on the GN card, got %+v and %+v", gn, gd)
+	}
+
+	g := Ground{Type: FiniteSomNorton, Soil: PoorGround, Screen: screen, SecondMedium: second}
+	gn, gd, err = g.cards()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gn.nradl != 60 || gn.f != [6]float64{13, 0.002, 20, 0.001, 0, 0} {
+		t.Errorf("GN card with a radial screen was %+v", gn)
+	}
+	if gd == nil || gd.f != [4]float64{70, 5, 100, 30} {
+		t.Errorf("second medium with a radial screen should go on a GD card, got %+v", gd)
+	}
+	if m := g.CalcMode(); m != RadialLinearCliff {
+		t.Errorf("calculation mode was %d, should have been RadialLinearCliff", m)
+	}
+
+	if _, _, err := (Ground{Type: Perfect, Screen: screen}).cards(); err == nil {
+		t.Errorf("a perfect ground with a radial screen should have been an error")
+	}
+	if _, _, err := (Ground{Type: Finite}).cards(); err == nil {
+		t.Errorf("a finite ground with no soil should have been an error")
+	}
+}
@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 package necpp
 
 /*
@@ -9,248 +12,21 @@ import "C"
 import (
 	"errors"
 	"fmt"
+	"runtime"
 	"strings"
 )
 
-// GainErrno is the number returned by the Gain* functions when no radiation
-// pattern was previously requested.
-const GainErrno float64 = -999.0
-
-var ErrNoPatternRequested = errors.New("no radiation pattern previously requested")
-
-// PatchType is the shape of a patch for the Surface Patch (SP Card).
-type PatchType int
-
-const (
-	Arbitrary PatchType = iota // an arbitrary patch shape (the default)
-	Rectangular
-	Triangular
-	Quadrilateral
-)
-
-// GeoGroundPlaneFlag is used to indicate the type of ground plane to use with
-// the antenna when indicating the geometry is complete.
-//
-// The types of ground plane to use are:
-//
-// • NoGroundPlane - no ground plane is present. (Fairly self-explanatory.)
-//
-// • CurrentExpansionModified - Structure symmetry is modified as required, and
-// the current expansion is modified so that the currents and segments touching
-// the ground (x, Y plane) are interpolated to their images below the ground
-// (charge at base is zero)
-//
-// • CurrentExpansionUnmodified - indicates a ground is present. Structure
-// symmetry is modified as required. Current expansion, however, is not
-// modified, Thus, currents on segments touching the ground will go to zero at
-// the ground.
-type GeoGroundPlaneFlag int
-
-const (
-	CurrentExpansionUnmodified GeoGroundPlaneFlag = iota - 1
-	NoGroundPlane
-	CurrentExpansionModified
-	MooMoo
-)
-
-// GroundTypeFlag indicates the general type of ground for the antenna.
-//
-// The flags for ground types are:
-//
-// • Nullified - Nullifies ground parameters previously used and sets free-space
-// condition. The remainder of the parameters are ignored in this case.
-//
-// • Finite - Finite ground, reflection coefficient approximation.
-//
-// • Perfect - Perfectly conducting ground.
-//
-// • FiniteSomNorton - Finite ground, Sommerfeld/Norton method.
-type GroundTypeFlag int
-
-const (
-	Nullified GroundTypeFlag = iota - 1
-	Finite
-	Perfect
-	FiniteSomNorton
-)
-
-// FrequencyRange is used to set the type of frequency range for FR cards.
-type FrequencyRange int
-
-const (
-	Linear      FrequencyRange = iota // a linear range
-	Logarithmic                       // a logarithmic range
-)
-
-// WireKernel sets the type of wire kernel to use with EkCard
-//
-// • ReturnToNormal - Return to normal kernel
-//
-// • ExtendedThinWire - Use extended thin wire kernel
-type WireKernel int
-
-const (
-	ReturnToNormal WireKernel = iota - 1
-	ExtendedThinWire
-)
-
-// Excitation sets the type of excitation for ExCard
-type Excitation int
-
-const (
-	VoltageApplied    Excitation = iota // voltage source (applied-E-field source)
-	IncidentLinear                      // incident plane wave, linear polarization.
-	IncidentRightHand                   // incident plane wave, right-hand (thumb along the incident k vector) elliptic polarization.
-	IncidentLeftHand                    // incident plane wave, left-hand elliptic polarization.
-	Elementary                          // elementary current source
-	VoltageSlope                        // voltage source (current-slope-discontinuity)
-)
-
-// ExecutionOption control the generation of radiation patterns with XqCard()
-//
-// Options for radiation patterns:
-//
-// • NoPattern - no patterns requested (the normal case).
-//
-// • XZPlane - generates a pattern cut in the XZ plane, i.e., phi = 0 degrees
-// and theta varies from 0 degrees to 90 degrees in 1 degree steps.
-//
-// • YZPlane - generates a pattern cut in the YZ plane, i.e., phi = 90 degrees
-// theta varies from 0 degrees to 90 degrees in 1 degree steps.
-//
-// • BothPlane - generates both of the cuts described for XZPlane and YZPlane.
-type ExecutionOption int
-
-const (
-	NoPattern ExecutionOption = iota
-	XZPlane
-	YZPlane
-	BothPlane
-)
-
-// wow, there are a lot of flags for RP cards. :-/
-
-// RpCalcMode is used to select radiation patterns for RP cards.
-//
-// RP card calculation flags:
-//
-// • Normal - normal mode. Space-wave fields are computed. An infinite ground
-// plane is included if it has been specified previously on a GN card;
-// otherwise, the antenna is in free space.
-//
-// • SurfaceWave - surface wave propagating along ground is added to the normal
-// space wave. This option changes the meaning of some of the other parameters
-// on the RP card as explained below, and the results appear in a special output
-// format. Ground parameters must have been input on a GN card. The following
-// options cause calculation of only the space wave but with special ground
-// conditions. Ground conditions include a two-medium ground (cliff where the
-// media join in a circle or a line), and a radial wire ground screen. Ground
-// parameters and dimensions must be input on a GN or GD card before the RP card
-// is read. The RP card only selects the option for inclusion in the field
-// calculation. (Refer to the GN and GD cards for further explanation.)
-//
-// • LinearCliff - linear cliff with antenna above upper level. Lower medium
-// parameters are as specified for the second medium on the GN card or on the
-// GD card.
-//
-// • CircularCliff - circular cliff centered at origin of coordinate system:
-// with antenna above upper level. Lower medium parameters are as specified for
-// the second medium on the GN card or on the GD card.
-//
-// • RadialScreen - radial wire ground screen centered at origin.
-//
-// • RadialLinearCliff - both radial wire ground screen and linear cliff.
-//
-// • RadialCircularCliff - both radial wire ground screen ant circular cliff.
-type RpCalcMode int
-
-const (
-	Normal RpCalcMode = iota
-	SurfaceWave
-	LinearCliff
-	CircularCliff
-	RadialScreen
-	RadialLinearCliff
-	RadialCircularCliff
-)
-
-// RpOutputFormat is used to select the output format for RP cards.
-//
-// • MajorMinor - major axis, minor axis and total gain printed.
-//
-// • VerticalHorizontal - vertical, horizontal ant total gain printed.
-type RpOutputFormat int
-
-const (
-	MajorMinor RpOutputFormat = iota
-	VerticalHorizontal
-)
-
-// RpNormalization is used to select the normalization type for RP cards.
-//
-// Radiation pattern normalization flags:
-//
-// • NoNormalization - no normalized gain.
-//
-// • MajorAxisNorm - major axis gain normalized.
-//
-// • MinorAxisNorm - minor axis gain normalized.
-//
-// • VerticalAxisNorm - vertical axis gain normalized.
-//
-// • HorizontalAxisNorm - horizontal axis gain normalized.
-//
-// • TotalNormalized - total gain normalized.
-type RpNormalization int
-
-const (
-	NoNormalization RpNormalization = iota
-	MajorAxisNorm
-	MinorAxisNorm
-	VerticalAxisNorm
-	HorizontalAxisNorm
-	TotalNormalized
-)
-
-// RpGain is used to select the type of gain for RP cards for standard printing
-// and normalization constants. These ones have self explanatory names.
-type RpGain int
-
-const (
-	PowerGain RpGain = iota
-	DirectiveGain
-)
-
-// RpAveraging is used to select the type of averaging for RP cards to set the
-// calculation of average power gain over the region covered by field points.
-//
-// RpAveraging flags:
-//
-// • NoAvg - no averaging
-//
-// • AvgGain - average gain computed.
-//
-// • AvgGainPrtSuppressed - average gain computed, printing of gain at the field
-// points used for averaging is suppressed. If nTheta or NPH is equal to one,
-// average gain will not be computed for any value of A since the area of the
-// region covered by field points vanishes.
-type RpAveraging int
-
-const (
-	NoAvg RpAveraging = iota
-	AvgGain
-	AvgGainPrtSuppressed
-)
-
 // NecppCtx is the nec context, and contains the libnecpp nec_context struct
-// within itself.
+// within itself. Once it's been deleted, its methods return ErrClosed rather
+// than passing the freed struct to libnecpp.
 type NecppCtx struct {
 	necContext *C.nec_context
+	recorder
 }
 
 // New creates a new NEC context object, which contains the nec_context struct
-// pointer from libnecpp. After it's done being used, call Delete() to free the
-// struct.
+// pointer from libnecpp. After it's done being used, call Delete() or Close()
+// to free the struct. See LogLeaks() for finding contexts that never are.
 func New() (*NecppCtx, error) {
 	n := new(NecppCtx)
 	nCtx := C.nec_create()
@@ -259,6 +35,7 @@ func New() (*NecppCtx, error) {
 		return nil, err
 	}
 	n.necContext = nCtx
+	watchLeaks(n)
 	return n, nil
 }
 
@@ -275,8 +52,13 @@ func (n *NecppCtx) errorMessage() error {
 // these functions wrap around the various C functions from libnecpp - if they
 // return a non-zero value, there's been an error of some kind. Get and return
 // that error.
+//
+// They also keep n alive until the C function has returned. Its last use is
+// otherwise reading n.necContext for the call, and a finalizer set by
+// LogLeaks() could free the nec_context while libnecpp is still using it.
 
 func (n *NecppCtx) errWrap(ret C.long) error {
+	runtime.KeepAlive(n)
 	if ret != 0 {
 		err := n.errorMessage()
 		return err
@@ -284,10 +66,27 @@ func (n *NecppCtx) errWrap(ret C.long) error {
 	return nil
 }
 
+// run runs one of the cards that makes libnecpp print something, keeping hold
+// of what it prints along the way.
+func (n *NecppCtx) run(card func() C.long) error {
+	if n.necContext == nil {
+		return ErrClosed
+	}
+	if !n.capture {
+		return n.errWrap(card())
+	}
+	out, err := captureOutput(func() error {
+		return n.errWrap(card())
+	}, n.stdout)
+	n.record(out)
+	return err
+}
+
 // the gain functions are a little different, in that they return a meaningful
 // number. If that number is -999.0, though, no radiation pattern as requested.
 
 func (n *NecppCtx) gainErrWrap(gain C.double) (float64, error) {
+	runtime.KeepAlive(n)
 	gainRet := float64(gain)
 	if gainRet == GainErrno {
 		return gainRet, ErrNoPatternRequested
@@ -296,9 +95,42 @@ func (n *NecppCtx) gainErrWrap(gain C.double) (float64, error) {
 }
 
 // Delete frees the nec_context struct. Call this after you're finished
-// simulating the antenna.
+// simulating the antenna. Deleting a context that's already been deleted does
+// nothing.
 func (n *NecppCtx) Delete() error {
-	return n.errWrap(C.nec_delete(n.necContext))
+	if n.necContext == nil {
+		return nil
+	}
+	err := n.errWrap(C.nec_delete(n.necContext))
+	n.necContext = nil
+	runtime.SetFinalizer(n, nil)
+	return err
+}
+
+// Reset puts the context back the way New() left it, before any geometry, so
+// it can be used for another model without making a new one. Whether the printed
+// report is caught and where it goes, as set by SetOutput(), and how much of it is kept, as set by
+// SetOutputLimit(), are kept; everything else, including the report so far, is
+// thrown away.
+//
+// libnecpp can't clear a nec_context once GeometryComplete() has been called,
+// so the one underneath is freed and replaced with a new one, which costs just
+// what Delete() and New() would. All that's saved is allocating the NecppCtx
+// itself. Reset() is for carrying on with the same *NecppCtx, and what's been
+// set up on it, like the output writer or the check for leaks, not for speed;
+// a ContextPool makes and frees the contexts out of the way instead.
+func (n *NecppCtx) Reset() error {
+	if n.necContext == nil {
+		return ErrClosed
+	}
+	nCtx := C.nec_create()
+	if nCtx == nil {
+		return errors.New("nec_context was NULL")
+	}
+	err := n.errWrap(C.nec_delete(n.necContext))
+	n.necContext = nCtx
+	n.recorder = recorder{stdout: n.stdout, capture: n.capture, outLimit: n.outLimit}
+	return err
 }
 
 // antenna geometry methods
@@ -319,7 +151,14 @@ func (n *NecppCtx) Delete() error {
 //
 // All co-ordinates are in meters.
 func (n *NecppCtx) Wire(tagId int, segmentCount int, xw1 float64, yw1 float64, zw1 float64, xw2 float64, yw2 float64, zw2 float64, rad float64, rdel float64, rrad float64) error {
-	return n.errWrap(C.nec_wire(n.necContext, C.int(tagId), C.int(segmentCount), C.double(xw1), C.double(yw1), C.double(zw1), C.double(xw2), C.double(yw2), C.double(zw2), C.double(rad), C.double(rdel), C.double(rrad)))
+	if n.necContext == nil {
+		return ErrClosed
+	}
+	if err := n.errWrap(C.nec_wire(n.necContext, C.int(tagId), C.int(segmentCount), C.double(xw1), C.double(yw1), C.double(zw1), C.double(xw2), C.double(yw2), C.double(zw2), C.double(rad), C.double(rdel), C.double(rrad))); err != nil {
+		return err
+	}
+	n.wire(WireSpec{Tag: tagId, Segments: segmentCount, X1: xw1, Y1: yw1, Z1: zw1, X2: xw2, Y2: yw2, Z2: zw2, Radius: rad, RDel: rdel, RRad: rrad})
+	return nil
 }
 
 // SpCard makes a Surface Patch (SP) card.
@@ -339,7 +178,14 @@ func (n *NecppCtx) Wire(tagId int, segmentCount int, xw1 float64, yw1 float64, z
 //
 // All co-ordinates are in meters, except for arbitrary patches where the angles// are in degrees.
 func (n *NecppCtx) SpCard(ns PatchType, x1 float64, y1 float64, z1 float64, x2 float64, y2 float64, z2 float64) error {
-	return n.errWrap(C.nec_sp_card(n.necContext, C.int(ns), C.double(x1), C.double(y1), C.double(z1), C.double(x2), C.double(y2), C.double(z2)))
+	if n.necContext == nil {
+		return ErrClosed
+	}
+	if err := n.errWrap(C.nec_sp_card(n.necContext, C.int(ns), C.double(x1), C.double(y1), C.double(z1), C.double(x2), C.double(y2), C.double(z2))); err != nil {
+		return err
+	}
+	n.spCard(ns, x1, y1, z1, x2, y2, z2)
+	return nil
 }
 
 // ScCard makes a Surface Patch Continuation (SC) card.
@@ -355,7 +201,14 @@ func (n *NecppCtx) SpCard(ns PatchType, x1 float64, y1 float64, z1 float64, x2 f
 //
 // All co-ordinates are in meters.
 func (n *NecppCtx) ScCard(i2 int, x3 float64, y3 float64, z3 float64, x4 float64, y4 float64, z4 float64) error {
-	return n.errWrap(C.nec_sc_card(n.necContext, C.int(i2), C.double(x3), C.double(y3), C.double(z3), C.double(x4), C.double(y4), C.double(z4)))
+	if n.necContext == nil {
+		return ErrClosed
+	}
+	if err := n.errWrap(C.nec_sc_card(n.necContext, C.int(i2), C.double(x3), C.double(y3), C.double(z3), C.double(x4), C.double(y4), C.double(z4))); err != nil {
+		return err
+	}
+	n.scCard(x3, y3, z3, x4, y4, z4)
+	return nil
 }
 
 // GmCard makes a GM card for Coordinate Transformation
@@ -376,7 +229,14 @@ func (n *NecppCtx) ScCard(i2 int, x3 float64, y3 float64, z3 float64, x4 float64
 //             the sequence of segments is moved by the card.  If ITS is zero
 //             the entire structure is moved.
 func (n *NecppCtx) GmCard(itsi int, nrpt int, rox float64, roy float64, roz float64, xs float64, ys float64, zs float64, its int) error {
-	return n.errWrap(C.nec_gm_card(n.necContext, C.int(itsi), C.int(nrpt), C.double(rox), C.double(roy), C.double(roz), C.double(xs), C.double(ys), C.double(zs), C.int(its)))
+	if n.necContext == nil {
+		return ErrClosed
+	}
+	if err := n.errWrap(C.nec_gm_card(n.necContext, C.int(itsi), C.int(nrpt), C.double(rox), C.double(roy), C.double(roz), C.double(xs), C.double(ys), C.double(zs), C.int(its))); err != nil {
+		return err
+	}
+	n.geom.move(itsi, nrpt, rox, roy, roz, xs, ys, zs, its)
+	return nil
 }
 
 // GxCard creates a GX card for Reflection in coordinate Planes.
@@ -413,13 +273,20 @@ from 101 to 200; and the second reflection, along the Y-axis, will produce tags
 rom 201 to 400, as a result of the increment being doubled to 200.
 */
 func (n *NecppCtx) GxCard(i1 int, i2 int) error {
-	return n.errWrap(C.nec_gx_card(n.necContext, C.int(i1), C.int(i2)))
+	if n.necContext == nil {
+		return ErrClosed
+	}
+	if err := n.errWrap(C.nec_gx_card(n.necContext, C.int(i1), C.int(i2))); err != nil {
+		return err
+	}
+	n.geom.reflect(i1, i2)
+	return nil
 }
 
 // GeometryComplete indicates the antenna geometry is complete - makes a GE
 // card. See GeoGroundPlaneFlag for details on that parameter.
 func (n *NecppCtx) GeometryComplete(gpflag GeoGroundPlaneFlag) error {
-	return n.errWrap(C.nec_geometry_complete(n.necContext, C.int(gpflag)))
+	return n.run(func() C.long { return C.nec_geometry_complete(n.necContext, C.int(gpflag)) })
 }
 
 // antenna environment methods
@@ -432,6 +299,9 @@ func (n *NecppCtx) GeometryComplete(gpflag GeoGroundPlaneFlag) error {
 // 	permeability - The magnetic permeability of the medium (in henries per
 // 		meter)
 func (n *NecppCtx) MediumParameters(permittivity float64, permeability float64) error {
+	if n.necContext == nil {
+		return ErrClosed
+	}
 	return n.errWrap(C.nec_medium_parameters(n.necContext, C.double(permittivity), C.double(permeability)))
 }
 
@@ -456,7 +326,18 @@ func (n *NecppCtx) MediumParameters(permittivity float64, permeability float64)
 // 	antenna. Use zero in the case of a perfect ground. If SIG is input as a
 // 	negative number, the complex dielectric constant Ec = Er -j sigma/omega
 // 	epsilon is set to EPSR - |SIG|.
+// 	tmp3, tmp4 - with a ground screen (nradl > 0), the radius of the screen
+// 	and the radius of its wires, in meters. Otherwise, the relative
+// 	dielectric constant and conductivity of a second ground medium.
+// 	tmp5, tmp6 - with no ground screen, the distance from the origin to the
+// 	change to the second medium (the cliff), and how far below the first
+// 	medium the second one is, in meters.
+//
+// SetGround() is an easier way of filling all of this in.
 func (n *NecppCtx) GnCard(iperf GroundTypeFlag, nradl int, epse float64, sig float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	if n.necContext == nil {
+		return ErrClosed
+	}
 	return n.errWrap(C.nec_gn_card(n.necContext, C.int(iperf), C.int(nradl), C.double(epse), C.double(sig), C.double(tmp3), C.double(tmp4), C.double(tmp5), C.double(tmp6)))
 }
 
@@ -467,13 +348,24 @@ func (n *NecppCtx) GnCard(iperf GroundTypeFlag, nradl int, epse float64, sig flo
 // 	logarithmic range of frequencies.
 // 	inNfreq - the number of frequencies
 // 	inFreqMhz - the starting frequency in MHz.
-// 	inDelFreq - the frequency step in MHz (for inIfreq == Linear)
+// 	inDelFreq - the frequency step in MHz (for inIfreq == Linear), or the
+// 	multiplication factor for each step (for inIfrq == Logarithmic)
 func (n *NecppCtx) FrCard(inIfrq FrequencyRange, inNfrq int, inFreqMhz float64, inDelFreq float64) error {
-	return n.errWrap(C.nec_fr_card(n.necContext, C.int(inIfrq), C.int(inNfrq), C.double(inFreqMhz), C.double(inDelFreq)))
+	if n.necContext == nil {
+		return ErrClosed
+	}
+	if err := n.errWrap(C.nec_fr_card(n.necContext, C.int(inIfrq), C.int(inNfrq), C.double(inFreqMhz), C.double(inDelFreq))); err != nil {
+		return err
+	}
+	n.frCard(inIfrq, inNfrq, inFreqMhz, inDelFreq)
+	return nil
 }
 
 // EkCard controls the use of the external thin-wire kernel approximation.
 func (n *NecppCtx) EkCard(itmp1 WireKernel) error {
+	if n.necContext == nil {
+		return ErrClosed
+	}
 	return n.errWrap(C.nec_ek_card(n.necContext, C.int(itmp1)))
 }
 
@@ -500,7 +392,14 @@ func (n *NecppCtx) EkCard(itmp1 WireKernel) error {
 //	tmp2 IND., HENRY, OR (A) HY/LENGTH OR (B) REACT. OR (C) Set to 0.0
 //	tmp3 CAP,. FARAD, OR (A,B) BLANK (set to 0.0)
 func (n *NecppCtx) LdCard(ldtype int, ldtag int, ldtagf int, ldtagt int, tmp1 float64, tmp2 float64, tmp3 float64) error {
-	return n.errWrap(C.nec_ld_card(n.necContext, C.int(ldtype), C.int(ldtag), C.int(ldtagf), C.int(ldtagt), C.double(tmp1), C.double(tmp2), C.double(tmp3)))
+	if n.necContext == nil {
+		return ErrClosed
+	}
+	if err := n.errWrap(C.nec_ld_card(n.necContext, C.int(ldtype), C.int(ldtag), C.int(ldtagf), C.int(ldtagt), C.double(tmp1), C.double(tmp2), C.double(tmp3))); err != nil {
+		return err
+	}
+	n.ldCard(ldtype, ldtag, ldtagf, ldtagt, tmp1, tmp2, tmp3)
+	return nil
 }
 
 // ExCard applies a source of excitation to the antenna, making an EX card.
@@ -526,7 +425,16 @@ func (n *NecppCtx) LdCard(ldtype int, ldtag int, ldtagf int, ldtagt int, tmp1 fl
 // Simpler versions of the function are provided for common uses. These are
 // ExcitationVoltage, ExcitationCurrent, and ExcitationPlanewave.
 func (n *NecppCtx) ExCard(extype Excitation, i2 int, i3 int, i4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
-	return n.errWrap(C.nec_ex_card(n.necContext, C.int(extype), C.int(i2), C.int(i3), C.int(i4), C.double(tmp1), C.double(tmp2), C.double(tmp3), C.double(tmp4), C.double(tmp5), C.double(tmp6)))
+	if n.necContext == nil {
+		return ErrClosed
+	}
+	if err := n.errWrap(C.nec_ex_card(n.necContext, C.int(extype), C.int(i2), C.int(i3), C.int(i4), C.double(tmp1), C.double(tmp2), C.double(tmp3), C.double(tmp4), C.double(tmp5), C.double(tmp6))); err != nil {
+		return err
+	}
+	if extype == VoltageApplied || extype == VoltageSlope {
+		n.feed(i2, i3, complex(tmp1, tmp2))
+	}
+	return nil
 }
 
 // ExcitationVoltage makes a voltage source excitation source for the antenna.
@@ -550,7 +458,14 @@ func (n *NecppCtx) ExCard(extype Excitation, i2 int, i3 int, i4 int, tmp1 float6
 // voltage sources.  If the excitation types are mixed, the program will use the
 // last excitation type encountered.
 func (n *NecppCtx) ExcitationVoltage(tag int, segment int, voltageExcitation complex128) error {
-	return n.errWrap(C.nec_excitation_voltage(n.necContext, C.int(tag), C.int(segment), C.double(real(voltageExcitation)), C.double(imag(voltageExcitation))))
+	if n.necContext == nil {
+		return ErrClosed
+	}
+	if err := n.errWrap(C.nec_excitation_voltage(n.necContext, C.int(tag), C.int(segment), C.double(real(voltageExcitation)), C.double(imag(voltageExcitation)))); err != nil {
+		return err
+	}
+	n.feed(tag, segment, voltageExcitation)
+	return nil
 }
 
 // ExcitationCurrent makes a current source excitation for the antenna. It is
@@ -572,6 +487,9 @@ func (n *NecppCtx) ExcitationVoltage(tag int, segment int, voltageExcitation com
 // voltage sources.  If the excitation types are mixed, the program will use the
 // last excitation type encountered.
 func (n *NecppCtx) ExcitationCurrent(x float64, y float64, z float64, a float64, beta float64, moment float64) error {
+	if n.necContext == nil {
+		return ErrClosed
+	}
 	return n.errWrap(C.nec_excitation_current(n.necContext, C.double(x), C.double(y), C.double(z), C.double(a), C.double(beta), C.double(moment)))
 }
 
@@ -597,16 +515,48 @@ func (n *NecppCtx) ExcitationCurrent(x float64, y float64, z float64, a float64,
 // voltage sources.  If the excitation types are mixed, the program will use the
 // last excitation type encountered.
 func (n *NecppCtx) ExcitationPlanewave(nTheta int, nPhi int, theta float64, phi float64, eta float64, dTheta float64, dPhi float64, polRatio float64) error {
+	if n.necContext == nil {
+		return ErrClosed
+	}
 	return n.errWrap(C.nec_excitation_planewave(n.necContext, C.int(nTheta), C.int(nPhi), C.double(theta), C.double(phi), C.double(eta), C.double(dTheta), C.double(dPhi), C.double(polRatio)))
 }
 
-// TlCard, presumably, makes an NEC2 TL Card.
+// TlCard makes a TL card, which connects two segments with a transmission
+// line. The line isn't part of the structure, so it neither radiates nor
+// couples to the antenna.
+//
+// Parameters:
+// 	itmp1, itmp2 - the tag and segment number of the segment at end 1
+// 	itmp3, itmp4 - the tag and segment number of the segment at end 2
+// 	tmp1 - the line's characteristic impedance in ohms. A negative value
+// 	gives a line with a half twist (crossed line).
+// 	tmp2 - the length of the line in meters, taken as having a velocity
+// 	factor of 1. Zero uses the straight distance between the two segments.
+// 	tmp3, tmp4 - the real and imaginary parts of a shunt admittance across
+// 	end 1, in mhos
+// 	tmp5, tmp6 - the real and imaginary parts of a shunt admittance across
+// 	end 2, in mhos
 func (n *NecppCtx) TlCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	if n.necContext == nil {
+		return ErrClosed
+	}
 	return n.errWrap(C.nec_tl_card(n.necContext, C.int(itmp1), C.int(itmp2), C.int(itmp3), C.int(itmp4), C.double(tmp1), C.double(tmp2), C.double(tmp3), C.double(tmp4), C.double(tmp5), C.double(tmp6)))
 }
 
-// NtCard, presumably, makes an NEC2 NT Card.
+// NtCard makes an NT card, which connects two segments with a two port
+// network given by its short circuit admittance parameters, in mhos. The
+// values are the same at every frequency.
+//
+// Parameters:
+// 	itmp1, itmp2 - the tag and segment number of the segment at port 1
+// 	itmp3, itmp4 - the tag and segment number of the segment at port 2
+// 	tmp1, tmp2 - the real and imaginary parts of Y11
+// 	tmp3, tmp4 - the real and imaginary parts of Y12 (which is also Y21)
+// 	tmp5, tmp6 - the real and imaginary parts of Y22
 func (n *NecppCtx) NtCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	if n.necContext == nil {
+		return ErrClosed
+	}
 	return n.errWrap(C.nec_nt_card(n.necContext, C.int(itmp1), C.int(itmp2), C.int(itmp3), C.int(itmp4), C.double(tmp1), C.double(tmp2), C.double(tmp3), C.double(tmp4), C.double(tmp5), C.double(tmp6)))
 }
 
@@ -617,11 +567,26 @@ func (n *NecppCtx) NtCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float
 // Parameter:
 // 	itmp1 - an ExecutionOption flag, per the ExecutionOption consts.
 func (n *NecppCtx) XqCard(itmp1 ExecutionOption) error {
-	return n.errWrap(C.nec_xq_card(n.necContext, C.int(itmp1)))
+	if err := n.run(func() C.long { return C.nec_xq_card(n.necContext, C.int(itmp1)) }); err != nil {
+		return err
+	}
+	n.xqCard(itmp1)
+	return nil
 }
 
-// GdCard, presumably, makes a GD card.
+// GdCard makes a GD card, which gives the parameters of a second ground
+// medium when the GN card is taken up by a radial ground screen.
+//
+// Parameters:
+// 	tmp1 - the relative dielectric constant of the second medium
+// 	tmp2 - the conductivity of the second medium, in mhos/meter
+// 	tmp3 - the distance from the origin to the change to the second medium
+// 	(the cliff), in meters
+// 	tmp4 - how far below the first medium the second one is, in meters
 func (n *NecppCtx) GdCard(tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64) error {
+	if n.necContext == nil {
+		return ErrClosed
+	}
 	return n.errWrap(C.nec_gd_card(n.necContext, C.double(tmp1), C.double(tmp2), C.double(tmp3), C.double(tmp4)))
 }
 
@@ -690,7 +655,13 @@ func (n *NecppCtx) GdCard(tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64
 // When a ground plane has been specified, field points should not be requested
 // below the ground (theta greater than 90 degrees or Z less than zero.)
 func (n *NecppCtx) RpCard(calcMode RpCalcMode, nTheta int, nPhi int, outputFormat RpOutputFormat, normalization RpNormalization, d RpGain, a RpAveraging, theta0 float64, phi0 float64, deltaTheta float64, deltaPhi float64, radialDistance float64, gainNorm float64) error {
-	return n.errWrap(C.nec_rp_card(n.necContext, C.int(calcMode), C.int(nTheta), C.int(nPhi), C.int(outputFormat), C.int(normalization), C.int(d), C.int(a), C.double(theta0), C.double(phi0), C.double(deltaTheta), C.double(deltaPhi), C.double(radialDistance), C.double(gainNorm)))
+	if err := n.run(func() C.long {
+		return C.nec_rp_card(n.necContext, C.int(calcMode), C.int(nTheta), C.int(nPhi), C.int(outputFormat), C.int(normalization), C.int(d), C.int(a), C.double(theta0), C.double(phi0), C.double(deltaTheta), C.double(deltaPhi), C.double(radialDistance), C.double(gainNorm))
+	}); err != nil {
+		return err
+	}
+	n.recordPatterns(patternGrid{nTheta: nTheta, nPhi: nPhi, theta0: theta0, phi0: phi0, dTheta: deltaTheta, dPhi: deltaPhi})
+	return nil
 }
 
 // PtCard makes a PT Card for printing of currents. This methods documentation
@@ -711,36 +682,66 @@ func (n *NecppCtx) RpCard(calcMode RpCalcMode, nTheta int, nPhi int, outputForma
 //
 // IPTAGT - Equal to n specifies the nth segment of the set of segments having tag numbers of IPTAG. Currents are printed for segments having tag number IPTAG starting at the m th segment in the set and ending at the nth segment. If IPTAG is zero or blank, then IPTAGF and IPTAGT refer to absoulte segment numbers. In IPTAGT is left blank, it is set to IPTAGF.
 func (n *NecppCtx) PtCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	if n.necContext == nil {
+		return ErrClosed
+	}
 	return n.errWrap(C.nec_pt_card(n.necContext, C.int(itmp1), C.int(itmp2), C.int(itmp3), C.int(itmp4)))
 }
 
 // PqCard makes a PQ Card. Needs documentation from the NEC2 user manual.
 func (n *NecppCtx) PqCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	if n.necContext == nil {
+		return ErrClosed
+	}
 	return n.errWrap(C.nec_pq_card(n.necContext, C.int(itmp1), C.int(itmp2), C.int(itmp3), C.int(itmp4)))
 }
 
 // KhCard makes a KH Card. Needs documentation from the NEC2 user manual.
 func (n *NecppCtx) KhCard(tmp1 float64) error {
+	if n.necContext == nil {
+		return ErrClosed
+	}
 	return n.errWrap(C.nec_kh_card(n.necContext, C.double(tmp1)))
 }
 
-// NeCard makes a NE Card. Needs documentation from the NEC2 user manual.
+// NeCard makes a NE Card, which asks for the near electric field to be
+// calculated over a grid of points. The results can be had from NearField().
+//
+// Parameters:
+// 	itmp1 - the kind of grid: 0 (RectangularGrid) or 1 (SphericalGrid)
+// 	itmp2, itmp3, itmp4 - the number of points along X, Y and Z, or along
+// 	R, phi and theta for a spherical grid
+// 	tmp1, tmp2, tmp3 - the coordinates of the first point, in meters or
+// 	degrees
+// 	tmp4, tmp5, tmp6 - the steps between points along each coordinate
 func (n *NecppCtx) NeCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
-	return n.errWrap(C.nec_ne_card(n.necContext, C.int(itmp1), C.int(itmp2), C.int(itmp3), C.int(itmp4), C.double(tmp1), C.double(tmp2), C.double(tmp3), C.double(tmp4), C.double(tmp5), C.double(tmp6)))
+	return n.run(func() C.long {
+		return C.nec_ne_card(n.necContext, C.int(itmp1), C.int(itmp2), C.int(itmp3), C.int(itmp4), C.double(tmp1), C.double(tmp2), C.double(tmp3), C.double(tmp4), C.double(tmp5), C.double(tmp6))
+	})
 }
 
-// NhCard makes a NH Card. Needs documentation from the NEC2 user manual.
+// NhCard makes a NH Card, which asks for the near magnetic field to be
+// calculated over a grid of points. The parameters are the same as NeCard()'s,
+// and the results can be had from NearField().
 func (n *NecppCtx) NhCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
-	return n.errWrap(C.nec_nh_card(n.necContext, C.int(itmp1), C.int(itmp2), C.int(itmp3), C.int(itmp4), C.double(tmp1), C.double(tmp2), C.double(tmp3), C.double(tmp4), C.double(tmp5), C.double(tmp6)))
+	return n.run(func() C.long {
+		return C.nec_nh_card(n.necContext, C.int(itmp1), C.int(itmp2), C.int(itmp3), C.int(itmp4), C.double(tmp1), C.double(tmp2), C.double(tmp3), C.double(tmp4), C.double(tmp5), C.double(tmp6))
+	})
 }
 
 // CpCard makes a CP Card. Needs documentation from the NEC2 user manual.
 func (n *NecppCtx) CpCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	if n.necContext == nil {
+		return ErrClosed
+	}
 	return n.errWrap(C.nec_cp_card(n.necContext, C.int(itmp1), C.int(itmp2), C.int(itmp3), C.int(itmp4)))
 }
 
 // PlCard makes a PL Card. Needs documentation from the NEC2 user manual.
 func (n *NecppCtx) PlCard(ploutputFilename string, itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	if n.necContext == nil {
+		return ErrClosed
+	}
 	return n.errWrap(C.nec_pl_card(n.necContext, C.CString(ploutputFilename), C.int(itmp1), C.int(itmp2), C.int(itmp3), C.int(itmp4)))
 }
 
@@ -761,6 +762,9 @@ func (n *NecppCtx) PlCard(ploutputFilename string, itmp1 int, itmp2 int, itmp3 i
 // This function requires a previous RpCard() method to have been called
 // (with the gain normalization set to TotalNormalized).
 func (n *NecppCtx) Gain(freqIndex int, thetaIndex int, phiIndex int) (float64, error) {
+	if n.necContext == nil {
+		return GainErrno, ErrClosed
+	}
 	return n.gainErrWrap(C.nec_gain(n.necContext, C.int(freqIndex), C.int(thetaIndex), C.int(phiIndex)))
 }
 
@@ -777,6 +781,9 @@ func (n *NecppCtx) Gain(freqIndex int, thetaIndex int, phiIndex int) (float64, e
 // This function requires a previous RpCard() method to have been called
 // (with the gain normalization set to TotalNormalized).
 func (n *NecppCtx) GainMax(freqIndex int) (float64, error) {
+	if n.necContext == nil {
+		return GainErrno, ErrClosed
+	}
 	return n.gainErrWrap(C.nec_gain_max(n.necContext, C.int(freqIndex)))
 }
 
@@ -793,6 +800,9 @@ func (n *NecppCtx) GainMax(freqIndex int) (float64, error) {
 // This function requires a previous RpCard() method to have been called
 // (with the gain normalization set to TotalNormalized).
 func (n *NecppCtx) GainMin(freqIndex int) (float64, error) {
+	if n.necContext == nil {
+		return GainErrno, ErrClosed
+	}
 	return n.gainErrWrap(C.nec_gain_min(n.necContext, C.int(freqIndex)))
 }
 
@@ -809,6 +819,9 @@ func (n *NecppCtx) GainMin(freqIndex int) (float64, error) {
 // This function requires a previous RpCard() method to have been called
 // (with the gain normalization set to TotalNormalized).
 func (n *NecppCtx) GainMean(freqIndex int) (float64, error) {
+	if n.necContext == nil {
+		return GainErrno, ErrClosed
+	}
 	return n.gainErrWrap(C.nec_gain_mean(n.necContext, C.int(freqIndex)))
 }
 
@@ -825,46 +838,79 @@ func (n *NecppCtx) GainMean(freqIndex int) (float64, error) {
 // This function requires a previous RpCard() method to have been called
 // (with the gain normalization set to TotalNormalized).
 func (n *NecppCtx) GainSd(freqIndex int) (float64, error) {
+	if n.necContext == nil {
+		return GainErrno, ErrClosed
+	}
 	return n.gainErrWrap(C.nec_gain_sd(n.necContext, C.int(freqIndex)))
 }
 
 func (n *NecppCtx) GainRhcpMax(freqIndex int) (float64, error) {
+	if n.necContext == nil {
+		return GainErrno, ErrClosed
+	}
 	return n.gainErrWrap(C.nec_gain_rhcp_max(n.necContext, C.int(freqIndex)))
 }
 
 func (n *NecppCtx) GainRhcpMin(freqIndex int) (float64, error) {
+	if n.necContext == nil {
+		return GainErrno, ErrClosed
+	}
 	return n.gainErrWrap(C.nec_gain_rhcp_min(n.necContext, C.int(freqIndex)))
 }
 
 func (n *NecppCtx) GainRhcpMean(freqIndex int) (float64, error) {
+	if n.necContext == nil {
+		return GainErrno, ErrClosed
+	}
 	return n.gainErrWrap(C.nec_gain_rhcp_mean(n.necContext, C.int(freqIndex)))
 }
 
 func (n *NecppCtx) GainRhcpSd(freqIndex int) (float64, error) {
+	if n.necContext == nil {
+		return GainErrno, ErrClosed
+	}
 	return n.gainErrWrap(C.nec_gain_rhcp_sd(n.necContext, C.int(freqIndex)))
 }
 
 func (n *NecppCtx) GainLhcpMax(freqIndex int) (float64, error) {
+	if n.necContext == nil {
+		return GainErrno, ErrClosed
+	}
 	return n.gainErrWrap(C.nec_gain_lhcp_max(n.necContext, C.int(freqIndex)))
 }
 
 func (n *NecppCtx) GainLhcpMin(freqIndex int) (float64, error) {
+	if n.necContext == nil {
+		return GainErrno, ErrClosed
+	}
 	return n.gainErrWrap(C.nec_gain_lhcp_min(n.necContext, C.int(freqIndex)))
 }
 
 func (n *NecppCtx) GainLhcpMean(freqIndex int) (float64, error) {
+	if n.necContext == nil {
+		return GainErrno, ErrClosed
+	}
 	return n.gainErrWrap(C.nec_gain_lhcp_mean(n.necContext, C.int(freqIndex)))
 }
 
 func (n *NecppCtx) GainLhcpSd(freqIndex int) (float64, error) {
+	if n.necContext == nil {
+		return GainErrno, ErrClosed
+	}
 	return n.gainErrWrap(C.nec_gain_lhcp_sd(n.necContext, C.int(freqIndex)))
 }
 
 func (n *NecppCtx) impedanceReal(freqIndex int) (float64, error) {
+	if n.necContext == nil {
+		return GainErrno, ErrClosed
+	}
 	return n.gainErrWrap(C.nec_impedance_real(n.necContext, C.int(freqIndex)))
 }
 
 func (n *NecppCtx) impedanceImag(freqIndex int) (float64, error) {
+	if n.necContext == nil {
+		return GainErrno, ErrClosed
+	}
 	return n.gainErrWrap(C.nec_impedance_imag(n.necContext, C.int(freqIndex)))
 }
 
@@ -872,6 +918,9 @@ func (n *NecppCtx) impedanceImag(freqIndex int) (float64, error) {
 // and takes the place of two separate C library functions that returned the
 // real and imaginary portions of the impedance, respectively.
 func (n *NecppCtx) Impedance(freqIndex int) (complex128, error) {
+	if n.necContext == nil {
+		return complex(GainErrno, GainErrno), ErrClosed
+	}
 	r, rerr := n.impedanceReal(freqIndex)
 	i, ierr := n.impedanceImag(freqIndex)
 	ret := complex(r, i)
@@ -889,3 +938,24 @@ func (n *NecppCtx) Impedance(freqIndex int) (complex128, error) {
 	}
 	return ret, nil
 }
+
+// ImpedanceSweep gets the impedance of the antenna at each of the frequencies
+// requested by FrCard(). A simulation must have been run over the sweep first,
+// with either XqCard() or RpCard().
+func (n *NecppCtx) ImpedanceSweep() ([]ImpedancePoint, error) {
+	return n.impedanceSweep(n.Impedance)
+}
+
+// RadiationPattern gets the gain at every point of a radiation pattern. The
+// freqIndex is the same as the one used with Gain(): the index of the pattern
+// in the order they were calculated. Only the total gain is available from
+// libnecpp, so the pattern returned is not Polarized.
+func (n *NecppCtx) RadiationPattern(freqIndex int) (*RadiationPattern, error) {
+	return n.radiationPattern(freqIndex, n.Gain)
+}
+
+// RadiationPatterns gets all of the radiation patterns calculated so far with
+// RpCard() or XqCard().
+func (n *NecppCtx) RadiationPatterns() ([]*RadiationPattern, error) {
+	return n.radiationPatterns(n.Gain)
+}
@@ -0,0 +1,202 @@
+//go:build !cgo
+// +build !cgo
+
+package necpp
+
+// Without cgo there's no libnecpp to call, so NecppCtx is only here for the
+// code built on it to compile. New() returns ErrNoLibnecpp, and so does every
+// method that would need libnecpp; FakeEngine can be used in its place.
+
+// NecppCtx is the nec context. Built without cgo, it can't be created.
+type NecppCtx struct {
+	recorder
+}
+
+// New returns ErrNoLibnecpp, as the package was built without cgo.
+func New() (*NecppCtx, error) {
+	return nil, ErrNoLibnecpp
+}
+
+func (n *NecppCtx) Delete() error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) Reset() error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) Wire(tagId int, segmentCount int, xw1 float64, yw1 float64, zw1 float64, xw2 float64, yw2 float64, zw2 float64, rad float64, rdel float64, rrad float64) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) SpCard(ns PatchType, x1 float64, y1 float64, z1 float64, x2 float64, y2 float64, z2 float64) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) ScCard(i2 int, x3 float64, y3 float64, z3 float64, x4 float64, y4 float64, z4 float64) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) GmCard(itsi int, nrpt int, rox float64, roy float64, roz float64, xs float64, ys float64, zs float64, its int) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) GxCard(i1 int, i2 int) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) GeometryComplete(gpflag GeoGroundPlaneFlag) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) MediumParameters(permittivity float64, permeability float64) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) GnCard(iperf GroundTypeFlag, nradl int, epse float64, sig float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) FrCard(inIfrq FrequencyRange, inNfrq int, inFreqMhz float64, inDelFreq float64) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) EkCard(itmp1 WireKernel) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) LdCard(ldtype int, ldtag int, ldtagf int, ldtagt int, tmp1 float64, tmp2 float64, tmp3 float64) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) ExCard(extype Excitation, i2 int, i3 int, i4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) ExcitationVoltage(tag int, segment int, voltageExcitation complex128) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) ExcitationCurrent(x float64, y float64, z float64, a float64, beta float64, moment float64) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) ExcitationPlanewave(nTheta int, nPhi int, theta float64, phi float64, eta float64, dTheta float64, dPhi float64, polRatio float64) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) TlCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) NtCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) XqCard(itmp1 ExecutionOption) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) GdCard(tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) RpCard(calcMode RpCalcMode, nTheta int, nPhi int, outputFormat RpOutputFormat, normalization RpNormalization, d RpGain, a RpAveraging, theta0 float64, phi0 float64, deltaTheta float64, deltaPhi float64, radialDistance float64, gainNorm float64) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) PtCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) PqCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) KhCard(tmp1 float64) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) NeCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) NhCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) CpCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) PlCard(ploutputFilename string, itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	return ErrNoLibnecpp
+}
+
+func (n *NecppCtx) Gain(freqIndex int, thetaIndex int, phiIndex int) (float64, error) {
+	return GainErrno, ErrNoLibnecpp
+}
+
+func (n *NecppCtx) GainMax(freqIndex int) (float64, error) {
+	return GainErrno, ErrNoLibnecpp
+}
+
+func (n *NecppCtx) GainMin(freqIndex int) (float64, error) {
+	return GainErrno, ErrNoLibnecpp
+}
+
+func (n *NecppCtx) GainMean(freqIndex int) (float64, error) {
+	return GainErrno, ErrNoLibnecpp
+}
+
+func (n *NecppCtx) GainSd(freqIndex int) (float64, error) {
+	return GainErrno, ErrNoLibnecpp
+}
+
+func (n *NecppCtx) GainRhcpMax(freqIndex int) (float64, error) {
+	return GainErrno, ErrNoLibnecpp
+}
+
+func (n *NecppCtx) GainRhcpMin(freqIndex int) (float64, error) {
+	return GainErrno, ErrNoLibnecpp
+}
+
+func (n *NecppCtx) GainRhcpMean(freqIndex int) (float64, error) {
+	return GainErrno, ErrNoLibnecpp
+}
+
+func (n *NecppCtx) GainRhcpSd(freqIndex int) (float64, error) {
+	return GainErrno, ErrNoLibnecpp
+}
+
+func (n *NecppCtx) GainLhcpMax(freqIndex int) (float64, error) {
+	return GainErrno, ErrNoLibnecpp
+}
+
+func (n *NecppCtx) GainLhcpMin(freqIndex int) (float64, error) {
+	return GainErrno, ErrNoLibnecpp
+}
+
+func (n *NecppCtx) GainLhcpMean(freqIndex int) (float64, error) {
+	return GainErrno, ErrNoLibnecpp
+}
+
+func (n *NecppCtx) GainLhcpSd(freqIndex int) (float64, error) {
+	return GainErrno, ErrNoLibnecpp
+}
+
+func (n *NecppCtx) Impedance(freqIndex int) (complex128, error) {
+	return complex(GainErrno, GainErrno), ErrNoLibnecpp
+}
+
+func (n *NecppCtx) ImpedanceSweep() ([]ImpedancePoint, error) {
+	return nil, ErrNoLibnecpp
+}
+
+func (n *NecppCtx) RadiationPattern(freqIndex int) (*RadiationPattern, error) {
+	return nil, ErrNoLibnecpp
+}
+
+func (n *NecppCtx) RadiationPatterns() ([]*RadiationPattern, error) {
+	return nil, ErrNoLibnecpp
+}
@@ -0,0 +1,55 @@
+package necpp
+
+import (
+	"errors"
+	"log"
+	"runtime"
+	"sync"
+)
+
+// ErrClosed is returned by NecppCtx's methods once it's been deleted.
+var ErrClosed = errors.New("the nec context has been deleted")
+
+var (
+	leakMu     sync.Mutex
+	leakLogger *log.Logger
+)
+
+// LogLeaks sets a logger to report contexts that are garbage collected without
+// having been deleted, along with where they were made with New(). Such
+// contexts are freed when they're reported. Pass nil to stop reporting them,
+// which is the default. It only affects contexts made after it's called.
+//
+// Garbage collection is what finds the leaks, so they may be reported long
+// after the context was last used, or not at all if the program exits first.
+func LogLeaks(l *log.Logger) {
+	leakMu.Lock()
+	defer leakMu.Unlock()
+	leakLogger = l
+}
+
+// watchLeaks sets a finalizer on a new context to report and free it if it's
+// never deleted, when LogLeaks() has been given a logger.
+func watchLeaks(n *NecppCtx) {
+	leakMu.Lock()
+	l := leakLogger
+	leakMu.Unlock()
+	if l == nil {
+		return
+	}
+	// the caller of New()
+	_, file, line, ok := runtime.Caller(2)
+	if !ok {
+		file, line = "an unknown place", 0
+	}
+	runtime.SetFinalizer(n, func(n *NecppCtx) {
+		l.Printf("necpp: a context made at %s:%d was never deleted", file, line)
+		n.Delete()
+	})
+}
+
+// Close frees the nec_context struct, the same as Delete(), so that NecppCtx is
+// an io.Closer. Closing a context more than once does nothing.
+func (n *NecppCtx) Close() error {
+	return n.Delete()
+}
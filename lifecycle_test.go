@@ -0,0 +1,194 @@
+//go:build cgo
+// +build cgo
+
+package necpp
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+var _ io.Closer = (*NecppCtx)(nil)
+
+func TestClose(t *testing.T) {
+	n, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Close(); err != nil {
+		t.Errorf("closing twice gave %v", err)
+	}
+	if err := n.Delete(); err != nil {
+		t.Errorf("deleting after closing gave %v", err)
+	}
+	if err := n.Wire(0, 9, 0, 0, 2, 0, 0, 7, 0.1, 1, 1); err != ErrClosed {
+		t.Errorf("adding a wire after closing gave %v, should have been %v", err, ErrClosed)
+	}
+	if err := n.XqCard(NoPattern); err != ErrClosed {
+		t.Errorf("running after closing gave %v, should have been %v", err, ErrClosed)
+	}
+	if g, err := n.GainMax(0); err != ErrClosed || g != GainErrno {
+		t.Errorf("gain after closing was %g, %v", g, err)
+	}
+	if _, err := n.Impedance(0); err != ErrClosed {
+		t.Errorf("impedance after closing gave %v, should have been %v", err, ErrClosed)
+	}
+}
+
+func TestLogLeaks(t *testing.T) {
+	var buf bytes.Buffer
+	done := make(chan bool, 1)
+	LogLeaks(log.New(writerFunc(func(p []byte) (int, error) {
+		buf.Write(p)
+		select {
+		case done <- true:
+		default:
+		}
+		return len(p), nil
+	}), "", 0))
+	defer LogLeaks(nil)
+
+	if _, err := New(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := New(); err != nil {
+		t.Fatal(err)
+	} else {
+		n.Delete()
+	}
+	for i := 0; i < 20; i++ {
+		runtime.GC()
+		select {
+		case <-done:
+			if s := buf.String(); !strings.Contains(s, "lifecycle_test.go") {
+				t.Errorf("leak was logged as %q", s)
+			}
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	t.Errorf("the leaked context wasn't logged")
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func TestReset(t *testing.T) {
+	n, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Delete()
+	var buf bytes.Buffer
+	n.SetOutput(&buf)
+	n.Wire(0, 9, 0, 0, 2, 0, 0, 7, 0.1, 1, 1)
+	n.GeometryComplete(CurrentExpansionModified)
+	n.FrCard(Linear, 1, 30, 0)
+	if err := n.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if g := n.Geometry(); len(g.Wires) != 0 {
+		t.Errorf("reset left %d wires", len(g.Wires))
+	}
+	if f := n.Frequencies(); f != nil {
+		t.Errorf("reset left frequencies %v", f)
+	}
+	if n.stdout != &buf {
+		t.Errorf("reset lost the output writer")
+	}
+	// a new geometry can be built
+	if err := n.Wire(0, 9, 0, 0, 2, 0, 0, 7, 0.1, 1, 1); err != nil {
+		t.Error(err)
+	}
+	if err := n.GeometryComplete(CurrentExpansionModified); err != nil {
+		t.Error(err)
+	}
+
+	n.Delete()
+	if err := n.Reset(); err != ErrClosed {
+		t.Errorf("resetting a deleted context gave %v, should have been %v", err, ErrClosed)
+	}
+}
+
+func TestContextPool(t *testing.T) {
+	p := NewContextPool(2)
+	var got []*NecppCtx
+	for i := 0; i < 4; i++ {
+		n, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := n.Wire(0, 9, 0, 0, 2, 0, 0, 7, 0.1, 1, 1); err != nil {
+			t.Error(err)
+		}
+		got = append(got, n)
+	}
+	for i, n := range got {
+		for _, m := range got[:i] {
+			if n == m {
+				t.Errorf("the same context was handed out twice")
+			}
+		}
+		p.Put(n)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range got {
+		if n.necContext != nil {
+			t.Errorf("a context given back wasn't freed once the pool was closed")
+		}
+	}
+	if _, err := p.Get(); err != ErrPoolClosed {
+		t.Errorf("getting from a closed pool gave %v, should have been %v", err, ErrPoolClosed)
+	}
+}
+
+// buildGeometry is the part of the simple antenna's deck that's built for
+// each model in the benchmarks.
+func buildGeometry(b *testing.B, n *NecppCtx) {
+	if err := n.Wire(0, 9, 0, 0, 2, 0, 0, 7, 0.1, 1, 1); err != nil {
+		b.Fatal(err)
+	}
+	if err := n.GeometryComplete(CurrentExpansionModified); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func BenchmarkNewDelete(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		n, err := New()
+		if err != nil {
+			b.Fatal(err)
+		}
+		n.SetOutput(ioutil.Discard)
+		buildGeometry(b, n)
+		n.Delete()
+	}
+}
+
+func BenchmarkContextPool(b *testing.B) {
+	p := NewContextPool(4)
+	defer p.Close()
+	for i := 0; i < b.N; i++ {
+		n, err := p.Get()
+		if err != nil {
+			b.Fatal(err)
+		}
+		n.SetOutput(ioutil.Discard)
+		buildGeometry(b, n)
+		p.Put(n)
+	}
+}
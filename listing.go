@@ -0,0 +1,420 @@
+package necpp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Listing is what can be read back out of a NEC2 output listing, as printed by
+// nec2c, nec++ or the original NEC2. Each kind of result is kept in the order
+// it was printed, so the indexes are the same as for the NecppCtx methods that
+// return them: with an FR card sweeping several frequencies, index 0 is the
+// first frequency, 1 the second, and so on.
+//
+// • Wires - the wires of the structure specification, as given by GW cards.
+// Wires made by GM and GX cards aren't listed by NEC, so they aren't here.
+//
+// • Inputs - the antenna input parameters, one table per frequency, with a row
+// for each voltage source.
+//
+// • Currents - the currents and locations of the segments, one table per
+// solution.
+//
+// • Patterns - the radiation patterns. The gains are in dBi, and the vertical
+// and horizontal gains are filled in if the pattern gave them. For a plane
+// wave excitation these are NEC's scattering cross sections, sigma/lambda^2 in
+// dB, instead.
+//
+// • NearFields - the near electric and magnetic fields.
+//
+// • Power - the power budgets.
+//
+// The rest of the listing isn't read: the comments and data cards NEC echoes
+// back at the start, and the run parameters it prints before each set of
+// results, such as the frequency, the antenna environment, the structure
+// impedance loading and the matrix timing. Those all come from the model that
+// was run, which the caller already has, and the frequency is given with each
+// result that depends on it.
+type Listing struct {
+	Wires      []WireSpec
+	Inputs     [][]InputParameters
+	Currents   [][]SegmentCurrent
+	Patterns   []*RadiationPattern
+	NearFields []*NearField
+	Power      []PowerBudget
+}
+
+// InputParameters are the antenna input parameters for one voltage source at
+// one frequency. Segment is the absolute segment number of the source.
+type InputParameters struct {
+	FreqMHz    float64
+	Tag        int
+	Segment    int
+	Voltage    complex128 // volts
+	Current    complex128 // amps
+	Impedance  complex128 // ohms
+	Admittance complex128 // mhos
+	Power      float64    // watts
+}
+
+// PowerBudget is NEC's accounting of where the input power went at one
+// frequency. The powers are in watts, and the efficiency is a percentage.
+type PowerBudget struct {
+	FreqMHz       float64
+	InputPower    float64
+	RadiatedPower float64
+	StructureLoss float64
+	NetworkLoss   float64
+	Efficiency    float64
+}
+
+// ErrNoPowerBudget is returned by InputPower() when there's no power budget in
+// libnecpp's output.
+var ErrNoPowerBudget = errors.New("no power budget found in libnecpp's output")
+
+// ParseOutput reads a NEC2 output listing. Parts of the listing it doesn't
+// know about are skipped over, so a listing with no results in it gives an
+// empty Listing rather than an error.
+func ParseOutput(r io.Reader) (*Listing, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return parseListing(string(b))
+}
+
+// ParseOutputFile reads a NEC2 output listing from a file.
+func ParseOutputFile(name string) (*Listing, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseOutput(f)
+}
+
+// Listing returns everything that's been read out of what libnecpp has printed
+// for this context so far. The results are read as each run prints them, so
+// they're all here even when SetOutputLimit() has had the start of Output()
+// dropped.
+func (r *recorder) Listing() (*Listing, error) {
+	if r.parseErr != nil {
+		return nil, r.parseErr
+	}
+	l := &Listing{
+		Wires:      append([]WireSpec(nil), r.results.Wires...),
+		Inputs:     append([][]InputParameters(nil), r.results.Inputs...),
+		Patterns:   append([]*RadiationPattern(nil), r.results.Patterns...),
+		NearFields: append([]*NearField(nil), r.results.NearFields...),
+		Power:      append([]PowerBudget(nil), r.results.Power...),
+	}
+	for _, t := range r.currents {
+		l.Currents = append(l.Currents, t.currents)
+	}
+	return l, nil
+}
+
+func parseListing(out string) (*Listing, error) {
+	l, tables, err := parseResults(out)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tables {
+		l.Currents = append(l.Currents, t.currents)
+	}
+	return l, nil
+}
+
+// parseResults reads everything but the currents into a Listing, and returns
+// the current tables separately, along with the frequencies they're at.
+func parseResults(out string) (*Listing, []currentTable, error) {
+	l := new(Listing)
+	var err error
+	if l.Wires, err = parseWires(out); err != nil {
+		return nil, nil, err
+	}
+	if l.Inputs, err = parseInputs(out); err != nil {
+		return nil, nil, err
+	}
+	tables, err := parseCurrents(out)
+	if err != nil {
+		return nil, nil, err
+	}
+	if l.Patterns, err = parsePatterns(out); err != nil {
+		return nil, nil, err
+	}
+	if l.NearFields, err = parseNearFields(out); err != nil {
+		return nil, nil, err
+	}
+	if l.Power, err = parsePower(out); err != nil {
+		return nil, nil, err
+	}
+	return l, tables, nil
+}
+
+// ImpedanceSweep returns the input impedance of the first source at each
+// frequency in the listing, the same as NecppCtx.ImpedanceSweep() gives.
+func (l *Listing) ImpedanceSweep() []ImpedancePoint {
+	var sweep []ImpedancePoint
+	for _, in := range l.Inputs {
+		if len(in) > 0 {
+			sweep = append(sweep, ImpedancePoint{FreqMHz: in[0].FreqMHz, Impedance: in[0].Impedance})
+		}
+	}
+	return sweep
+}
+
+// newScanner returns a scanner over the lines of a listing, with room for the
+// long lines some NEC versions print.
+func newScanner(out string) *bufio.Scanner {
+	sc := bufio.NewScanner(strings.NewReader(out))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	return sc
+}
+
+var freqLine = regexp.MustCompile(`FREQUENCY\s*[=:]\s*([-+0-9.Ee]+)\s*MHZ`)
+
+// frequency picks the frequency out of a "FREQUENCY=" line, if line is one.
+func frequency(line string) (float64, bool) {
+	m := freqLine.FindStringSubmatch(line)
+	if m == nil {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(m[1], 64)
+	return f, err == nil
+}
+
+// lastFrequency finds the last frequency given in out.
+func lastFrequency(out string) (float64, bool) {
+	i := strings.LastIndex(out, "FREQUENCY")
+	for ; i >= 0; i = strings.LastIndex(out[:i], "FREQUENCY") {
+		line := out[i:]
+		if j := strings.IndexByte(line, '\n'); j >= 0 {
+			line = line[:j]
+		}
+		if f, ok := frequency(line); ok {
+			return f, true
+		}
+	}
+	return 0, false
+}
+
+// parseWires reads the wires out of the structure specification.
+func parseWires(out string) ([]WireSpec, error) {
+	var wires []WireSpec
+	in := false
+	sc := newScanner(out)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.Contains(line, "STRUCTURE SPECIFICATION"):
+			in = true
+			continue
+		case strings.Contains(line, "TOTAL SEGMENTS USED"), strings.Contains(line, "SEGMENTATION DATA"):
+			in = false
+			continue
+		}
+		if !in {
+			continue
+		}
+		vals, ok := parseFloats(strings.Fields(line))
+		if !ok || len(vals) != 12 {
+			continue
+		}
+		wires = append(wires, WireSpec{
+			Tag:      int(vals[11]),
+			Segments: int(vals[8]),
+			X1:       vals[1],
+			Y1:       vals[2],
+			Z1:       vals[3],
+			X2:       vals[4],
+			Y2:       vals[5],
+			Z2:       vals[6],
+			Radius:   vals[7],
+		})
+	}
+	return wires, sc.Err()
+}
+
+// parseInputs reads the antenna input parameter tables.
+func parseInputs(out string) ([][]InputParameters, error) {
+	var tables [][]InputParameters
+	var cur []InputParameters
+	in := false
+	freq := 0.0
+	sc := newScanner(out)
+	for sc.Scan() {
+		line := sc.Text()
+		if f, ok := frequency(line); ok {
+			freq = f
+			continue
+		}
+		if strings.Contains(line, "ANTENNA INPUT PARAMETERS") {
+			in, cur = true, nil
+			continue
+		}
+		if !in {
+			continue
+		}
+		vals, ok := parseFloats(strings.Fields(line))
+		if !ok || len(vals) != 11 {
+			if len(cur) > 0 {
+				tables = append(tables, cur)
+				in, cur = false, nil
+			}
+			continue
+		}
+		cur = append(cur, InputParameters{
+			FreqMHz:    freq,
+			Tag:        int(vals[0]),
+			Segment:    int(vals[1]),
+			Voltage:    complex(vals[2], vals[3]),
+			Current:    complex(vals[4], vals[5]),
+			Impedance:  complex(vals[6], vals[7]),
+			Admittance: complex(vals[8], vals[9]),
+			Power:      vals[10],
+		})
+	}
+	if len(cur) > 0 {
+		tables = append(tables, cur)
+	}
+	return tables, sc.Err()
+}
+
+// parsePatterns reads the radiation pattern tables. Each row starts with
+// theta, phi and three gains; the gains are vertical, horizontal and total, or
+// major, minor and total, depending on the RP card.
+func parsePatterns(out string) ([]*RadiationPattern, error) {
+	var patterns []*RadiationPattern
+	var cur *RadiationPattern
+	in, vertHoriz := false, false
+	freq := 0.0
+	finish := func() {
+		if cur != nil && len(cur.Points) > 0 {
+			cur.NTheta, cur.NPhi = patternShape(cur.Points)
+			patterns = append(patterns, cur)
+		}
+		cur, in = nil, false
+	}
+	sc := newScanner(out)
+	for sc.Scan() {
+		line := sc.Text()
+		if f, ok := frequency(line); ok {
+			freq = f
+			continue
+		}
+		if strings.Contains(line, "RADIATION PATTERNS") {
+			finish()
+			in, vertHoriz = true, false
+			cur = &RadiationPattern{FreqMHz: freq}
+			continue
+		}
+		if !in {
+			continue
+		}
+		tok := strings.Fields(line)
+		if indexOf(tok, "VERTC") >= 0 || indexOf(tok, "VERT.") >= 0 {
+			vertHoriz = true
+			continue
+		}
+		if len(tok) < 5 {
+			if len(cur.Points) > 0 {
+				finish()
+			}
+			continue
+		}
+		vals, ok := parseFloats(tok[:5])
+		if !ok {
+			if len(cur.Points) > 0 {
+				finish()
+			}
+			continue
+		}
+		p := PatternPoint{Theta: vals[0], Phi: vals[1], Total: vals[4]}
+		if vertHoriz {
+			p.Vertical, p.Horizontal = vals[2], vals[3]
+			cur.Polarized = true
+		}
+		cur.Points = append(cur.Points, p)
+	}
+	finish()
+	return patterns, sc.Err()
+}
+
+// patternShape works out the grid of a pattern from its points, given that
+// theta changes fastest.
+func patternShape(pts []PatternPoint) (int, int) {
+	nTheta := 0
+	for _, p := range pts {
+		if p.Phi != pts[0].Phi {
+			break
+		}
+		nTheta++
+	}
+	if len(pts)%nTheta != 0 {
+		return len(pts), 1
+	}
+	return nTheta, len(pts) / nTheta
+}
+
+var budgetLine = regexp.MustCompile(`^\s*([A-Z][A-Z ]*[A-Z])\s*=\s*([-+0-9.Ee]+)`)
+
+// parsePower reads the power budgets.
+func parsePower(out string) ([]PowerBudget, error) {
+	var budgets []PowerBudget
+	var cur *PowerBudget
+	freq := 0.0
+	sc := newScanner(out)
+	for sc.Scan() {
+		line := sc.Text()
+		if f, ok := frequency(line); ok {
+			freq = f
+			continue
+		}
+		if strings.Contains(line, "POWER BUDGET") {
+			if cur != nil {
+				budgets = append(budgets, *cur)
+			}
+			cur = &PowerBudget{FreqMHz: freq}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		m := budgetLine.FindStringSubmatch(line)
+		if m == nil {
+			if strings.TrimSpace(line) != "" {
+				budgets = append(budgets, *cur)
+				cur = nil
+			}
+			continue
+		}
+		v, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("can't read the power budget line %q", strings.TrimSpace(line))
+		}
+		switch m[1] {
+		case "INPUT POWER":
+			cur.InputPower = v
+		case "RADIATED POWER":
+			cur.RadiatedPower = v
+		case "STRUCTURE LOSS":
+			cur.StructureLoss = v
+		case "NETWORK LOSS":
+			cur.NetworkLoss = v
+		case "EFFICIENCY":
+			cur.Efficiency = v
+		}
+	}
+	if cur != nil {
+		budgets = append(budgets, *cur)
+	}
+	return budgets, sc.Err()
+}
@@ -0,0 +1,99 @@
+package necpp
+
+import (
+	"math"
+	"math/cmplx"
+	"strings"
+	"testing"
+)
+
+const listingOutput = `
+                               -------- STRUCTURE SPECIFICATION --------
+                                     COORDINATES MUST BE INPUT IN
+                                     METERS OR BE SCALED TO METERS
+                                     BEFORE STRUCTURE INPUT IS ENDED
+
+  WIRE                                                                               SEG FIRST  LAST  TAG
+   No:        X1         Y1         Z1         X2         Y2         Z2       RADIUS   No:   SEG   SEG  No:
+     1     0.0000     0.0000    -0.2500     0.0000     0.0000     0.2500     0.0010    11     1    11    1
+
+     TOTAL SEGMENTS USED: 11   SEGMENTS IN A SYMMETRIC CELL: 11   SYMMETRY FLAG: 0
+
+                               --------- FREQUENCY --------
+                                FREQUENCY= 2.9979E+02 MHZ
+                                WAVELENGTH= 1.0000E+00 METERS
+
+                        --------- ANTENNA INPUT PARAMETERS ---------
+  TAG   SEG       VOLTAGE (VOLTS)         CURRENT (AMPS)         IMPEDANCE (OHMS)        ADMITTANCE (MHOS)     POWER
+  NO.   NO.     REAL      IMAGINARY     REAL      IMAGINARY     REAL      IMAGINARY    REAL       IMAGINARY   (WATTS)
+    1     6  1.0000E+00  0.0000E+00  1.0705E-02 -6.1534E-03  7.0223E+01  4.0366E+01  1.0705E-02 -6.1534E-03  5.3524E-03
+
+                           -------- CURRENTS AND LOCATION --------
+                                  DISTANCES IN WAVELENGTHS
+
+   SEG.  TAG    COORD. OF SEG. CENTER     SEG.            - - - CURRENT (AMPS) - - -
+   No.   No.     X         Y         Z      LENGTH     REAL      IMAGINARY    MAGN        PHASE
+     6     1    0.0000    0.0000    0.0000   0.04545  1.0705E-02 -6.1534E-03  1.2348E-02  -29.892
+
+                               ---------- POWER BUDGET ---------
+                               INPUT POWER   =  5.3524E-03 WATTS
+                               RADIATED POWER=  5.3524E-03 WATTS
+                               STRUCTURE LOSS=  0.0000E+00 WATTS
+                               NETWORK LOSS  =  0.0000E+00 WATTS
+                               EFFICIENCY    =  100.00 PERCENT
+
+                             ---------- RADIATION PATTERNS -----------
+
+  ---- ANGLES -----     ----- POWER GAINS -----       ---- POLARIZATION ----   ---- E(THETA) ----    ----- E(PHI) ------
+  THETA      PHI       VERTC    HORIZ    TOTAL       AXIAL      TILT  SENSE   MAGNITUDE    PHASE    MAGNITUDE     PHASE
+ DEGREES   DEGREES        DB       DB       DB       RATIO   DEGREES            VOLTS/M   DEGREES     VOLTS/M   DEGREES
+    0.00      0.00   -999.99  -999.99  -999.99     0.00000     0.00 LINEAR  0.0000E+00     0.00  0.0000E+00     0.00
+   90.00      0.00      2.13  -999.99      2.13     0.00000     0.00 LINEAR  1.0000E+00   -10.00  0.0000E+00     0.00
+    0.00     90.00   -999.99  -999.99  -999.99     0.00000     0.00 LINEAR  0.0000E+00     0.00  0.0000E+00     0.00
+   90.00     90.00      2.13  -999.99      2.13     0.00000     0.00 LINEAR  1.0000E+00   -10.00  0.0000E+00     0.00
+
+                              ----- NEAR ELECTRIC FIELDS -----
+                     ------- LOCATION -------     ------- EX ------    ------- EY ------    ------- EZ ------
+                      X         Y         Z       MAGNITUDE   PHASE    MAGNITUDE   PHASE    MAGNITUDE   PHASE
+                    METERS    METERS    METERS     VOLTS/M  DEGREES     VOLTS/M  DEGREES     VOLTS/M  DEGREES
+                     1.0000    0.0000    0.0000  1.0000E-01    10.00  0.0000E+00     0.00  2.0000E+00   -20.00
+`
+
+func TestParseOutput(t *testing.T) {
+	l, err := ParseOutput(strings.NewReader(listingOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Wires) != 1 || l.Wires[0].Tag != 1 || l.Wires[0].Segments != 11 || l.Wires[0].Z2 != 0.25 {
+		t.Errorf("wires were %+v", l.Wires)
+	}
+	if len(l.Inputs) != 1 || len(l.Inputs[0]) != 1 {
+		t.Fatalf("inputs were %+v", l.Inputs)
+	}
+	in := l.Inputs[0][0]
+	if in.Tag != 1 || in.Segment != 6 || cmplx.Abs(in.Impedance-complex(70.223, 40.366)) > 1e-9 || in.Power != 5.3524e-3 {
+		t.Errorf("input parameters were %+v", in)
+	}
+	if sw := l.ImpedanceSweep(); len(sw) != 1 || math.Abs(sw[0].FreqMHz-299.79) > 1e-9 {
+		t.Errorf("impedance sweep was %+v", sw)
+	}
+	if len(l.Currents) != 1 || len(l.Currents[0]) != 1 || l.Currents[0][0].Segment != 6 {
+		t.Errorf("currents were %+v", l.Currents)
+	}
+	if len(l.Power) != 1 || l.Power[0].InputPower != 5.3524e-3 || l.Power[0].Efficiency != 100 {
+		t.Errorf("power budgets were %+v", l.Power)
+	}
+	if len(l.Patterns) != 1 {
+		t.Fatalf("found %d patterns, should have been 1", len(l.Patterns))
+	}
+	p := l.Patterns[0]
+	if p.NTheta != 2 || p.NPhi != 2 || !p.Polarized {
+		t.Errorf("pattern was %d by %d, polarized %v", p.NTheta, p.NPhi, p.Polarized)
+	}
+	if pt := p.Point(1, 1); pt.Theta != 90 || pt.Phi != 90 || pt.Total != 2.13 || pt.Vertical != 2.13 {
+		t.Errorf("point (1, 1) was %+v", pt)
+	}
+	if len(l.NearFields) != 1 || len(l.NearFields[0].Points) != 1 {
+		t.Errorf("near fields were %+v", l.NearFields)
+	}
+}
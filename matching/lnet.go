@@ -0,0 +1,188 @@
+package matching
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// lSolution is an L network worked out as a series reactance x and a shunt
+// susceptance b. With shuntAtLoad the shunt part is next to the load;
+// otherwise it's next to the source.
+type lSolution struct {
+	shuntAtLoad bool
+	x           float64
+	b           float64
+}
+
+// lMatch works out the L networks that match zl to the resistance r0. There are
+// up to four, two with the shunt part at each end.
+func lMatch(zl complex128, r0 float64) []lSolution {
+	rl, xl := real(zl), imag(zl)
+	var sols []lSolution
+	if rl <= 0 || r0 <= 0 {
+		return nil
+	}
+	// shunt next to the load, for loads inside the r = r0 circle
+	if d := rl*rl + xl*xl - r0*rl; d >= 0 {
+		for _, sign := range []float64{1, -1} {
+			b := (xl + sign*math.Sqrt(rl/r0)*math.Sqrt(d)) / (rl*rl + xl*xl)
+			if b == 0 {
+				continue
+			}
+			x := 1/b + xl*r0/rl - r0/(b*rl)
+			sols = append(sols, lSolution{shuntAtLoad: true, x: x, b: b})
+		}
+	}
+	// shunt next to the source, for loads inside the g = 1/r0 circle
+	if rl < r0 {
+		for _, sign := range []float64{1, -1} {
+			x := sign*math.Sqrt(rl*(r0-rl)) - xl
+			b := sign * math.Sqrt((r0-rl)/rl) / r0
+			sols = append(sols, lSolution{x: x, b: b})
+		}
+	}
+	return sols
+}
+
+// series and shunt turn a reactance or susceptance into a component. A zero
+// reactance or susceptance needs no component at all.
+func series(x float64, freqMHz float64) []Component {
+	w := 2 * math.Pi * freqMHz * 1e6
+	switch {
+	case x > 0:
+		return []Component{{Kind: SeriesInductor, Value: x / w}}
+	case x < 0:
+		return []Component{{Kind: SeriesCapacitor, Value: -1 / (w * x)}}
+	}
+	return nil
+}
+
+func shunt(b float64, freqMHz float64) []Component {
+	w := 2 * math.Pi * freqMHz * 1e6
+	switch {
+	case b > 0:
+		return []Component{{Kind: ShuntCapacitor, Value: b / w}}
+	case b < 0:
+		return []Component{{Kind: ShuntInductor, Value: -1 / (w * b)}}
+	}
+	return nil
+}
+
+func (s lSolution) network(freqMHz float64) Network {
+	nw := Network{}
+	if s.shuntAtLoad {
+		nw.Name = "L network, shunt at load"
+		nw.Components = append(series(s.x, freqMHz), shunt(s.b, freqMHz)...)
+	} else {
+		nw.Name = "L network, shunt at source"
+		nw.Components = append(shunt(s.b, freqMHz), series(s.x, freqMHz)...)
+	}
+	return nw
+}
+
+// LNetworks designs the L networks that match the load impedance zl to the
+// resistance r0 at freqMHz. There are up to four, with the shunt component at
+// one end or the other and either a low pass (series inductor, shunt
+// capacitor) or high pass arrangement.
+func LNetworks(zl complex128, r0 float64, freqMHz float64) ([]Network, error) {
+	if err := checkDesign(zl, r0, freqMHz); err != nil {
+		return nil, err
+	}
+	var out []Network
+	for _, s := range lMatch(zl, r0) {
+		if nw := s.network(freqMHz); nw.matches(zl, r0, freqMHz) {
+			out = append(out, nw)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no L network matches %v to %g ohms", zl, r0)
+	}
+	return out, nil
+}
+
+// lowPass picks the solution closest to a low pass arrangement: a series
+// inductor and shunt capacitor, if there is one.
+func lowPass(sols []lSolution, shuntAtLoad bool) (lSolution, bool) {
+	best, found := lSolution{}, false
+	for _, s := range sols {
+		if s.shuntAtLoad != shuntAtLoad {
+			continue
+		}
+		if !found || (s.b > 0 && best.b <= 0) || ((s.b > 0) == (best.b > 0) && s.x > best.x) {
+			best, found = s, true
+		}
+	}
+	return best, found
+}
+
+// PiNetwork designs a Pi network (shunt, series, shunt) matching the load
+// impedance zl to the resistance r0 at freqMHz, with a loaded Q of q. Higher Qs
+// give narrower bandwidth, but better harmonic suppression; q must be high
+// enough that the network can make the match at all. Low pass designs are
+// preferred where there's a choice.
+//
+// The network is designed as two L networks back to back, each matching its
+// end down to a virtual resistance in the middle.
+func PiNetwork(zl complex128, r0 float64, q float64, freqMHz float64) (Network, error) {
+	if err := checkDesign(zl, r0, freqMHz); err != nil {
+		return Network{}, err
+	}
+	rMax, rMin := math.Max(r0, real(zl)), math.Min(r0, real(zl))
+	rv := rMax / (q*q + 1)
+	if rv >= rMin {
+		return Network{}, fmt.Errorf("a Q of at least %.3g is needed for a Pi network", math.Sqrt(rMax/rMin-1))
+	}
+	src, ok1 := lowPass(lMatch(complex(rv, 0), r0), false)
+	load, ok2 := lowPass(lMatch(zl, rv), true)
+	if !ok1 || !ok2 {
+		return Network{}, errors.New("no Pi network found")
+	}
+	var cs []Component
+	cs = append(cs, shunt(src.b, freqMHz)...)
+	cs = append(cs, series(src.x+load.x, freqMHz)...)
+	cs = append(cs, shunt(load.b, freqMHz)...)
+	nw := Network{Name: fmt.Sprintf("Pi network, Q %g", q), Components: cs}
+	if !nw.matches(zl, r0, freqMHz) {
+		return Network{}, errors.New("no Pi network found")
+	}
+	return nw, nil
+}
+
+// TNetwork designs a T network (series, shunt, series) matching the load
+// impedance zl to the resistance r0 at freqMHz, with a loaded Q of q, in the
+// same way as PiNetwork() but with a virtual resistance higher than both ends.
+func TNetwork(zl complex128, r0 float64, q float64, freqMHz float64) (Network, error) {
+	if err := checkDesign(zl, r0, freqMHz); err != nil {
+		return Network{}, err
+	}
+	rMax, rMin := math.Max(r0, real(zl)), math.Min(r0, real(zl))
+	rv := rMin * (q*q + 1)
+	if rv <= rMax {
+		return Network{}, fmt.Errorf("a Q of at least %.3g is needed for a T network", math.Sqrt(rMax/rMin-1))
+	}
+	src, ok1 := lowPass(lMatch(complex(rv, 0), r0), true)
+	load, ok2 := lowPass(lMatch(zl, rv), false)
+	if !ok1 || !ok2 {
+		return Network{}, errors.New("no T network found")
+	}
+	var cs []Component
+	cs = append(cs, series(src.x, freqMHz)...)
+	cs = append(cs, shunt(src.b+load.b, freqMHz)...)
+	cs = append(cs, series(load.x, freqMHz)...)
+	nw := Network{Name: fmt.Sprintf("T network, Q %g", q), Components: cs}
+	if !nw.matches(zl, r0, freqMHz) {
+		return Network{}, errors.New("no T network found")
+	}
+	return nw, nil
+}
+
+func checkDesign(zl complex128, r0 float64, freqMHz float64) error {
+	if real(zl) <= 0 {
+		return fmt.Errorf("can't match a load with no resistance (%v)", zl)
+	}
+	if r0 <= 0 || freqMHz <= 0 {
+		return errors.New("the target resistance and frequency must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+/*
+Package matching designs impedance matching networks for the feed impedances
+go-libnecpp works out: L, Pi and T networks of lumped components, single and
+double stub matches, and quarter wave transformers.
+
+Each design is checked at its design frequency, and can then be run across an
+impedance sweep to see how wide the match is, or put back into the model with
+Insert() so NEC can take it into account.
+
+Networks are always described from the source (the transmitter or feedline)
+end to the load (the antenna) end.
+*/
+package matching
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	"github.com/ctdk/go-libnecpp"
+)
+
+// Kind is the kind of a component in a matching network.
+//
+// • SeriesInductor, SeriesCapacitor - a lumped component in series with the
+// line.
+//
+// • ShuntInductor, ShuntCapacitor - a lumped component across the line.
+//
+// • Line - a length of transmission line in series.
+//
+// • OpenStub, ShortedStub - a length of transmission line across the line,
+// open or shorted at the far end.
+type Kind int
+
+const (
+	SeriesInductor Kind = iota
+	SeriesCapacitor
+	ShuntInductor
+	ShuntCapacitor
+	Line
+	OpenStub
+	ShortedStub
+)
+
+// Component is one part of a matching network. Value is in henries for
+// inductors, farads for capacitors, and meters (physical length) for lines and
+// stubs. Z0 and VelocityFactor are only used by lines and stubs; a
+// VelocityFactor of zero is taken as 1.
+type Component struct {
+	Kind           Kind
+	Value          float64
+	Z0             float64
+	VelocityFactor float64
+}
+
+func (c Component) String() string {
+	switch c.Kind {
+	case SeriesInductor:
+		return fmt.Sprintf("series %.4g uH", c.Value*1e6)
+	case SeriesCapacitor:
+		return fmt.Sprintf("series %.4g pF", c.Value*1e12)
+	case ShuntInductor:
+		return fmt.Sprintf("shunt %.4g uH", c.Value*1e6)
+	case ShuntCapacitor:
+		return fmt.Sprintf("shunt %.4g pF", c.Value*1e12)
+	case Line:
+		return fmt.Sprintf("%.4g m of %g ohm line", c.Value, c.Z0)
+	case OpenStub:
+		return fmt.Sprintf("open stub, %.4g m of %g ohm line", c.Value, c.Z0)
+	case ShortedStub:
+		return fmt.Sprintf("shorted stub, %.4g m of %g ohm line", c.Value, c.Z0)
+	}
+	return fmt.Sprintf("Kind(%d)", int(c.Kind))
+}
+
+func (c Component) isShunt() bool {
+	return c.Kind == ShuntInductor || c.Kind == ShuntCapacitor || c.Kind == OpenStub || c.Kind == ShortedStub
+}
+
+// beta returns the phase constant of a line or stub, in radians per meter.
+func (c Component) beta(freqMHz float64) float64 {
+	vf := c.VelocityFactor
+	if vf <= 0 {
+		vf = 1
+	}
+	return 2 * math.Pi * freqMHz / (necpp.SpeedOfLight * vf)
+}
+
+// immittance returns the impedance of a series component, or the admittance of
+// a shunt one.
+func (c Component) immittance(freqMHz float64) complex128 {
+	w := 2 * math.Pi * freqMHz * 1e6
+	switch c.Kind {
+	case SeriesInductor:
+		return complex(0, w*c.Value)
+	case SeriesCapacitor:
+		return complex(0, -1/(w*c.Value))
+	case ShuntInductor:
+		return complex(0, -1/(w*c.Value))
+	case ShuntCapacitor:
+		return complex(0, w*c.Value)
+	case OpenStub:
+		return complex(0, math.Tan(c.beta(freqMHz)*c.Value)/c.Z0)
+	case ShortedStub:
+		return complex(0, -1/(c.Z0*math.Tan(c.beta(freqMHz)*c.Value)))
+	}
+	return 0
+}
+
+// ABCD returns the component's chain matrix at a frequency.
+func (c Component) ABCD(freqMHz float64) [2][2]complex128 {
+	switch c.Kind {
+	case Line:
+		bl := c.beta(freqMHz) * c.Value
+		cs, sn := complex(math.Cos(bl), 0), complex(0, math.Sin(bl))
+		z0 := complex(c.Z0, 0)
+		return [2][2]complex128{{cs, z0 * sn}, {sn / z0, cs}}
+	case ShuntInductor, ShuntCapacitor, OpenStub, ShortedStub:
+		return [2][2]complex128{{1, 0}, {c.immittance(freqMHz), 1}}
+	}
+	return [2][2]complex128{{1, c.immittance(freqMHz)}, {0, 1}}
+}
+
+// Network is a matching network: a chain of components from the source end to
+// the load end.
+type Network struct {
+	Name       string
+	Components []Component
+}
+
+func (nw Network) String() string {
+	s := nw.Name + ":"
+	for i, c := range nw.Components {
+		if i > 0 {
+			s += ","
+		}
+		s += " " + c.String()
+	}
+	return s
+}
+
+// ABCD returns the chain matrix of the whole network at a frequency.
+func (nw Network) ABCD(freqMHz float64) [2][2]complex128 {
+	m := [2][2]complex128{{1, 0}, {0, 1}}
+	for _, c := range nw.Components {
+		m = mul2(m, c.ABCD(freqMHz))
+	}
+	return m
+}
+
+func mul2(a [2][2]complex128, b [2][2]complex128) [2][2]complex128 {
+	return [2][2]complex128{
+		{a[0][0]*b[0][0] + a[0][1]*b[1][0], a[0][0]*b[0][1] + a[0][1]*b[1][1]},
+		{a[1][0]*b[0][0] + a[1][1]*b[1][0], a[1][0]*b[0][1] + a[1][1]*b[1][1]},
+	}
+}
+
+// InputImpedance returns the impedance looking into the source end of the
+// network with zl on the load end.
+func (nw Network) InputImpedance(zl complex128, freqMHz float64) complex128 {
+	m := nw.ABCD(freqMHz)
+	return (m[0][0]*zl + m[0][1]) / (m[1][0]*zl + m[1][1])
+}
+
+// MatchedPoint is the impedance seen through a matching network at one
+// frequency, and its VSWR.
+type MatchedPoint struct {
+	FreqMHz   float64
+	Impedance complex128
+	VSWR      float64
+}
+
+// Sweep runs the network across an impedance sweep of the antenna, giving the
+// impedance seen at the source end at each frequency and its VSWR against z0.
+func (nw Network) Sweep(sweep []necpp.ImpedancePoint, z0 float64) []MatchedPoint {
+	out := make([]MatchedPoint, len(sweep))
+	for i, p := range sweep {
+		z := nw.InputImpedance(p.Impedance, p.FreqMHz)
+		out[i] = MatchedPoint{FreqMHz: p.FreqMHz, Impedance: z, VSWR: necpp.VSWR(z, z0)}
+	}
+	return out
+}
+
+// matches checks that a network designed at freqMHz really does turn zl into
+// r0, to guard against the corner cases of the design formulas.
+func (nw Network) matches(zl complex128, r0 float64, freqMHz float64) bool {
+	z := nw.InputImpedance(zl, freqMHz)
+	return !cmplx.IsNaN(z) && cmplx.Abs(z-complex(r0, 0)) <= 1e-6*r0
+}
+
+// Insert puts the network into a model at its design frequency, between the
+// antenna's feed and a spare segment, and returns the segment the voltage
+// source should then go on. spare should be on a short wire well away from the
+// antenna, added before GeometryComplete(); it's not needed (and the feed
+// itself is returned) if the network can be put on as a load.
+//
+// How the network goes in depends on what's in it:
+//
+// • Only series components - a series RLC load (LdCard) on the feed segment.
+//
+// • A single line, with nothing but shunt components at its ends - a TL card
+// from the spare segment to the feed, with the shunt components as its end
+// admittances.
+//
+// • Anything else - an NT card from the spare segment to the feed, with the
+// network's admittance parameters.
+//
+// LD loads and TL lines are right at every frequency of a sweep, but NT cards
+// and the end admittances of TL cards are fixed at their values at freqMHz.
+// TL cards have no velocity factor, so lines are put in at their electrical
+// length.
+func (nw Network) Insert(n necpp.Engine, feed necpp.Port, spare necpp.Port, freqMHz float64) (necpp.Port, error) {
+	if len(nw.Components) == 0 {
+		return feed, errors.New("the network is empty")
+	}
+	if l, c, ok := nw.seriesLC(); ok {
+		return feed, n.LdCard(0, feed.Tag, feed.Segment, feed.Segment, 0, l, c)
+	}
+	if line, y1, y2, ok := nw.singleLine(freqMHz); ok {
+		vf := line.VelocityFactor
+		if vf <= 0 {
+			vf = 1
+		}
+		return spare, n.TlCard(spare.Tag, spare.Segment, feed.Tag, feed.Segment, line.Z0, line.Value/vf, real(y1), imag(y1), real(y2), imag(y2))
+	}
+	return spare, n.ConnectTwoPort(spare, feed, nw.TwoPort(freqMHz))
+}
+
+// TwoPort returns the network as a two port at a frequency, with port 1 at the
+// source end.
+func (nw Network) TwoPort(freqMHz float64) necpp.TwoPort {
+	return necpp.NewABCDTwoPort(nw.ABCD(freqMHz))
+}
+
+// seriesLC combines a network of only series inductors and capacitors into one
+// inductance and one capacitance. A capacitance of zero means there's no
+// capacitor, the way LdCard() takes it.
+func (nw Network) seriesLC() (float64, float64, bool) {
+	l, invC := 0.0, 0.0
+	for _, c := range nw.Components {
+		switch c.Kind {
+		case SeriesInductor:
+			l += c.Value
+		case SeriesCapacitor:
+			invC += 1 / c.Value
+		default:
+			return 0, 0, false
+		}
+	}
+	if invC == 0 {
+		return l, 0, true
+	}
+	return l, 1 / invC, true
+}
+
+// singleLine picks apart a network that is one line with shunt components at
+// either end, returning the line and the total shunt admittance at each end.
+func (nw Network) singleLine(freqMHz float64) (Component, complex128, complex128, bool) {
+	idx := -1
+	for i, c := range nw.Components {
+		if c.Kind == Line {
+			if idx >= 0 {
+				return Component{}, 0, 0, false
+			}
+			idx = i
+		} else if !c.isShunt() {
+			return Component{}, 0, 0, false
+		}
+	}
+	if idx < 0 {
+		return Component{}, 0, 0, false
+	}
+	var y1, y2 complex128
+	for i, c := range nw.Components {
+		if i < idx {
+			y1 += c.immittance(freqMHz)
+		} else if i > idx {
+			y2 += c.immittance(freqMHz)
+		}
+	}
+	return nw.Components[idx], y1, y2, true
+}
@@ -0,0 +1,174 @@
+package matching
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/ctdk/go-libnecpp"
+)
+
+const testFreq = 14.2
+
+func checkMatch(t *testing.T, nw Network, zl complex128, r0 float64) {
+	if z := nw.InputImpedance(zl, testFreq); cmplx.Abs(z-complex(r0, 0)) > 1e-6*r0 {
+		t.Errorf("%s gave %v, should have been %g ohms", nw, z, r0)
+	}
+}
+
+func TestLumped(t *testing.T) {
+	for _, zl := range []complex128{complex(23, -14), complex(200, 80), complex(50, 100)} {
+		nws, err := LNetworks(zl, 50, testFreq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, nw := range nws {
+			checkMatch(t, nw, zl, 50)
+		}
+		for _, design := range []func(complex128, float64, float64, float64) (Network, error){PiNetwork, TNetwork} {
+			nw, err := design(zl, 50, 5, testFreq)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(nw.Components) != 3 {
+				t.Errorf("%s should have had three components", nw)
+			}
+			checkMatch(t, nw, zl, 50)
+		}
+	}
+	if _, err := PiNetwork(complex(5, 0), 50, 1, testFreq); err == nil {
+		t.Errorf("a Q of 1 can't match 5 ohms to 50, and should have been an error")
+	}
+}
+
+func TestLines(t *testing.T) {
+	zl := complex(23, -14)
+	for _, shorted := range []bool{false, true} {
+		nws, err := SingleStubs(zl, 50, testFreq, 0.66, shorted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(nws) != 2 {
+			t.Errorf("there should have been two single stub matches, got %d", len(nws))
+		}
+		for _, nw := range nws {
+			checkMatch(t, nw, zl, 50)
+		}
+	}
+	nws, err := DoubleStubs(zl, 50, testFreq, 1, 0.125, 0.1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, nw := range nws {
+		checkMatch(t, nw, zl, 50)
+	}
+	nws, err = QuarterWave(zl, 50, testFreq, 0.8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nws) != 2 {
+		t.Errorf("there should have been two quarter wave transformers, got %d", len(nws))
+	}
+	for _, nw := range nws {
+		checkMatch(t, nw, zl, 50)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	zl := complex(23, -14)
+	nws, err := LNetworks(zl, 50, testFreq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sweep := []necpp.ImpedancePoint{{FreqMHz: 14.0, Impedance: zl}, {FreqMHz: testFreq, Impedance: zl}}
+	m := nws[0].Sweep(sweep, 50)
+	if math.Abs(m[1].VSWR-1) > 1e-6 {
+		t.Errorf("VSWR at the design frequency was %g, should have been 1", m[1].VSWR)
+	}
+	if m[0].VSWR <= 1 {
+		t.Errorf("VSWR off the design frequency should have been above 1, was %g", m[0].VSWR)
+	}
+}
+
+func TestTwoPort(t *testing.T) {
+	zl := complex(23, -14)
+	nw, err := PiNetwork(zl, 50, 5, testFreq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// with the load across port 2, Z parameters give the input impedance too
+	z, err := nw.TwoPort(testFreq).Z()
+	if err != nil {
+		t.Fatal(err)
+	}
+	zin := z[0][0] - z[0][1]*z[1][0]/(z[1][1]+zl)
+	if cmplx.Abs(zin-50) > 1e-6 {
+		t.Errorf("the network's Z parameters gave an input impedance of %v, should have been 50 ohms", zin)
+	}
+}
+
+// checkCall checks that a recorded card had the method and arguments it
+// should have, with the floating point ones allowed a little rounding.
+func checkCall(t *testing.T, c necpp.Call, method string, args ...interface{}) {
+	if c.Method != method || len(c.Args) != len(args) {
+		t.Errorf("the card was %s%v, should have been %s%v", c.Method, c.Args, method, args)
+		return
+	}
+	for i, a := range args {
+		if f, ok := a.(float64); ok {
+			if g, ok := c.Args[i].(float64); !ok || math.Abs(g-f) > 1e-9*math.Max(1, math.Abs(f)) {
+				t.Errorf("%s argument %d was %v, should have been %g", method, i, c.Args[i], f)
+			}
+		} else if c.Args[i] != a {
+			t.Errorf("%s argument %d was %v, should have been %v", method, i, c.Args[i], a)
+		}
+	}
+}
+
+func TestInsert(t *testing.T) {
+	feed := necpp.Port{Tag: 1, Segment: 5}
+	spare := necpp.Port{Tag: 9, Segment: 1}
+	w := 2 * math.Pi * testFreq * 1e6
+
+	// series components only: one LD card on the feed
+	f := new(necpp.FakeEngine)
+	nw := Network{Components: []Component{{Kind: SeriesInductor, Value: 1e-6}, {Kind: SeriesCapacitor, Value: 200e-12}, {Kind: SeriesCapacitor, Value: 200e-12}}}
+	p, err := nw.Insert(f, feed, spare, testFreq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != feed || len(f.Calls) != 1 {
+		t.Fatalf("inserting a series network made %d cards and gave %v, should have been one card and the feed", len(f.Calls), p)
+	}
+	checkCall(t, f.Calls[0], "LdCard", 0, 1, 5, 5, 0.0, 1e-6, 100e-12)
+
+	// one line with shunt components at its ends: a TL card
+	f = new(necpp.FakeEngine)
+	nw = Network{Components: []Component{{Kind: ShuntCapacitor, Value: 50e-12}, {Kind: Line, Value: 5, Z0: 75, VelocityFactor: 0.66}, {Kind: ShuntInductor, Value: 2e-6}}}
+	if p, err = nw.Insert(f, feed, spare, testFreq); err != nil {
+		t.Fatal(err)
+	}
+	if p != spare || len(f.Calls) != 1 {
+		t.Fatalf("inserting a line made %d cards and gave %v, should have been one card and the spare segment", len(f.Calls), p)
+	}
+	checkCall(t, f.Calls[0], "TlCard", 9, 1, 1, 5, 75.0, 5/0.66, 0.0, w*50e-12, 0.0, -1/(w*2e-6))
+
+	// anything else: an NT card with the network's Y parameters
+	f = new(necpp.FakeEngine)
+	nw = Network{Components: []Component{{Kind: SeriesInductor, Value: 1e-6}, {Kind: ShuntCapacitor, Value: 100e-12}}}
+	if p, err = nw.Insert(f, feed, spare, testFreq); err != nil {
+		t.Fatal(err)
+	}
+	if p != spare || len(f.Calls) != 1 {
+		t.Fatalf("inserting an L network made %d cards and gave %v, should have been one card and the spare segment", len(f.Calls), p)
+	}
+	y, err := nw.TwoPort(testFreq).Y()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCall(t, f.Calls[0], "NtCard", 9, 1, 1, 5, real(y[0][0]), imag(y[0][0]), real(y[0][1]), imag(y[0][1]), real(y[1][1]), imag(y[1][1]))
+
+	if _, err := (Network{}).Insert(new(necpp.FakeEngine), feed, spare, testFreq); err == nil {
+		t.Errorf("inserting an empty network should have been an error")
+	}
+}
@@ -0,0 +1,196 @@
+package matching
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	"github.com/ctdk/go-libnecpp"
+)
+
+// wavelength returns the wavelength in a line with velocity factor vf, in
+// meters.
+func wavelength(freqMHz float64, vf float64) float64 {
+	if vf <= 0 {
+		vf = 1
+	}
+	return necpp.SpeedOfLight * vf / freqMHz
+}
+
+// stubLength returns the length, in wavelengths, of an open or shorted stub of
+// characteristic impedance z0 with a susceptance of b.
+func stubLength(b float64, z0 float64, shorted bool) float64 {
+	var l float64
+	if shorted {
+		if b == 0 {
+			return 0.25
+		}
+		l = -math.Atan(1/(b*z0)) / (2 * math.Pi)
+	} else {
+		l = math.Atan(b*z0) / (2 * math.Pi)
+	}
+	if l < 0 {
+		l += 0.5
+	}
+	return l
+}
+
+func stub(b float64, z0 float64, freqMHz float64, vf float64, shorted bool) Component {
+	c := Component{Kind: OpenStub, Z0: z0, VelocityFactor: vf}
+	if shorted {
+		c.Kind = ShortedStub
+	}
+	c.Value = stubLength(b, z0, shorted) * wavelength(freqMHz, vf)
+	return c
+}
+
+func line(wavelengths float64, z0 float64, freqMHz float64, vf float64) Component {
+	return Component{Kind: Line, Value: wavelengths * wavelength(freqMHz, vf), Z0: z0, VelocityFactor: vf}
+}
+
+// SingleStubs designs the single stub matches for the load impedance zl on a
+// line of characteristic impedance z0 at freqMHz: a stub across the line at
+// the distance from the load where the line's conductance is 1/z0, cancelling
+// out the susceptance there. There are two solutions, the first and second such
+// distance along the line. vf is the velocity factor of the line and the stub,
+// which are both made of the same line; shorted chooses shorted stubs rather
+// than open ones.
+func SingleStubs(zl complex128, z0 float64, freqMHz float64, vf float64, shorted bool) ([]Network, error) {
+	if err := checkDesign(zl, z0, freqMHz); err != nil {
+		return nil, err
+	}
+	rl, xl := real(zl), imag(zl)
+	// t is tan(beta d) for the stub's distance d from the load
+	var ts []float64
+	if rl == z0 {
+		ts = []float64{-xl / (2 * z0)}
+	} else {
+		root := math.Sqrt(rl * ((z0-rl)*(z0-rl) + xl*xl) / z0)
+		ts = []float64{(xl + root) / (rl - z0), (xl - root) / (rl - z0)}
+	}
+	var out []Network
+	for _, t := range ts {
+		d := math.Atan(t) / (2 * math.Pi)
+		if d < 0 {
+			d += 0.5
+		}
+		// the susceptance the stub has to cancel out
+		b := (rl*rl*t - (z0-xl*t)*(xl+z0*t)) / (z0 * (rl*rl + (xl+z0*t)*(xl+z0*t)))
+		nw := Network{
+			Name:       fmt.Sprintf("single stub, %.4g wavelengths from the load", d),
+			Components: []Component{stub(-b, z0, freqMHz, vf, shorted)},
+		}
+		if d > 0 {
+			nw.Components = append(nw.Components, line(d, z0, freqMHz, vf))
+		}
+		if nw.matches(zl, z0, freqMHz) {
+			out = append(out, nw)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no single stub match for %v on %g ohm line", zl, z0)
+	}
+	return out, nil
+}
+
+// DoubleStubs designs the double stub matches for the load impedance zl on a
+// line of characteristic impedance z0 at freqMHz. The first stub is offset
+// wavelengths from the load, and the second stub spacing wavelengths further
+// on towards the source; 1/8 and 3/8 of a wavelength are the usual spacings.
+// Not every load can be matched with a given spacing and offset, in which case
+// changing the offset usually helps. vf and shorted are as for SingleStubs().
+func DoubleStubs(zl complex128, z0 float64, freqMHz float64, vf float64, spacing float64, offset float64, shorted bool) ([]Network, error) {
+	if err := checkDesign(zl, z0, freqMHz); err != nil {
+		return nil, err
+	}
+	if spacing <= 0 || math.Mod(spacing, 0.5) == 0 || offset < 0 {
+		return nil, errors.New("the stub spacing must be positive and not a multiple of half a wavelength, and the offset can't be negative")
+	}
+	var toLoad Network
+	if offset > 0 {
+		toLoad.Components = []Component{line(offset, z0, freqMHz, vf)}
+	}
+	yl := 1 / toLoad.InputImpedance(zl, freqMHz)
+	gl, bl := real(yl), imag(yl)
+	y0 := 1 / z0
+	t := math.Tan(2 * math.Pi * spacing)
+	d := gl*y0*(1+t*t) - gl*gl*t*t
+	if d < 0 {
+		return nil, fmt.Errorf("%v can't be matched with stubs %g wavelengths apart at %g wavelengths from the load", zl, spacing, offset)
+	}
+	var out []Network
+	for _, sign := range []float64{1, -1} {
+		b1 := -bl + (y0+sign*math.Sqrt(d))/t
+		b2 := (sign*y0*math.Sqrt(d) + gl*y0) / (gl * t)
+		nw := Network{
+			Name: fmt.Sprintf("double stub, %.4g wavelengths apart", spacing),
+			Components: []Component{
+				stub(b2, z0, freqMHz, vf, shorted),
+				line(spacing, z0, freqMHz, vf),
+				stub(b1, z0, freqMHz, vf, shorted),
+			},
+		}
+		nw.Components = append(nw.Components, toLoad.Components...)
+		if nw.matches(zl, z0, freqMHz) {
+			out = append(out, nw)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no double stub match for %v on %g ohm line", zl, z0)
+	}
+	return out, nil
+}
+
+// QuarterWave designs quarter wave transformers matching the load impedance zl
+// to the resistance r0 at freqMHz. A complex load first needs a length of r0
+// ohm line to get to where the impedance is purely resistive, which happens
+// twice every half wavelength, giving two solutions; a resistive load needs just
+// the transformer. vf is the velocity factor of both lines.
+func QuarterWave(zl complex128, r0 float64, freqMHz float64, vf float64) ([]Network, error) {
+	if err := checkDesign(zl, r0, freqMHz); err != nil {
+		return nil, err
+	}
+	type point struct {
+		d float64 // wavelengths from the load
+		r float64
+	}
+	var points []point
+	g := (zl - complex(r0, 0)) / (zl + complex(r0, 0))
+	mag := cmplx.Abs(g)
+	if mag < 1e-12 {
+		return nil, errors.New("the load is already matched")
+	}
+	if math.Abs(imag(zl)) < 1e-9*cmplx.Abs(zl) {
+		points = append(points, point{0, real(zl)})
+	} else {
+		// the reflection coefficient turns through -2 beta d along the
+		// line, so it's real and positive (a voltage maximum) at d1 and
+		// real and negative a quarter wave further on
+		d1 := cmplx.Phase(g) / (4 * math.Pi)
+		if d1 < 0 {
+			d1 += 0.5
+		}
+		d2 := math.Mod(d1+0.25, 0.5)
+		points = append(points, point{d1, r0 * (1 + mag) / (1 - mag)}, point{d2, r0 * (1 - mag) / (1 + mag)})
+	}
+	var out []Network
+	for _, p := range points {
+		zt := math.Sqrt(r0 * p.r)
+		nw := Network{
+			Name:       fmt.Sprintf("quarter wave transformer of %.4g ohms", zt),
+			Components: []Component{line(0.25, zt, freqMHz, vf)},
+		}
+		if p.d > 0 {
+			nw.Name += fmt.Sprintf(", %.4g wavelengths from the load", p.d)
+			nw.Components = append(nw.Components, line(p.d, r0, freqMHz, vf))
+		}
+		if nw.matches(zl, r0, freqMHz) {
+			out = append(out, nw)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no quarter wave transformer matches %v to %g ohms", zl, r0)
+	}
+	return out, nil
+}
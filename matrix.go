@@ -0,0 +1,133 @@
+package necpp
+
+import (
+	"errors"
+	"math/cmplx"
+)
+
+// Small dense complex matrix helpers, used for converting between the
+// different ways of describing multi-port networks. The matrices involved are
+// only ever a handful of ports across, so nothing clever is done here.
+
+var errSingularMatrix = errors.New("matrix is singular")
+
+func identityMatrix(n int) [][]complex128 {
+	m := newMatrix(n, n)
+	for i := 0; i < n; i++ {
+		m[i][i] = 1
+	}
+	return m
+}
+
+func newMatrix(rows int, cols int) [][]complex128 {
+	m := make([][]complex128, rows)
+	for i := range m {
+		m[i] = make([]complex128, cols)
+	}
+	return m
+}
+
+func copyMatrix(a [][]complex128) [][]complex128 {
+	m := make([][]complex128, len(a))
+	for i := range a {
+		m[i] = make([]complex128, len(a[i]))
+		copy(m[i], a[i])
+	}
+	return m
+}
+
+func matrixMul(a [][]complex128, b [][]complex128) [][]complex128 {
+	m := newMatrix(len(a), len(b[0]))
+	for i := range a {
+		for j := range b[0] {
+			var sum complex128
+			for k := range b {
+				sum += a[i][k] * b[k][j]
+			}
+			m[i][j] = sum
+		}
+	}
+	return m
+}
+
+// matrixAddDiag returns a + d*I.
+func matrixAddDiag(a [][]complex128, d complex128) [][]complex128 {
+	m := copyMatrix(a)
+	for i := range m {
+		m[i][i] += d
+	}
+	return m
+}
+
+// matrixInverse inverts a square matrix by Gauss-Jordan elimination with
+// partial pivoting.
+func matrixInverse(a [][]complex128) ([][]complex128, error) {
+	n := len(a)
+	w := copyMatrix(a)
+	inv := identityMatrix(n)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if cmplx.Abs(w[r][col]) > cmplx.Abs(w[pivot][col]) {
+				pivot = r
+			}
+		}
+		if cmplx.Abs(w[pivot][col]) == 0 {
+			return nil, errSingularMatrix
+		}
+		w[col], w[pivot] = w[pivot], w[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+		p := w[col][col]
+		for j := 0; j < n; j++ {
+			w[col][j] /= p
+			inv[col][j] /= p
+		}
+		for r := 0; r < n; r++ {
+			if r == col || w[r][col] == 0 {
+				continue
+			}
+			f := w[r][col]
+			for j := 0; j < n; j++ {
+				w[r][j] -= f * w[col][j]
+				inv[r][j] -= f * inv[col][j]
+			}
+		}
+	}
+	return inv, nil
+}
+
+// zToS converts an impedance matrix to a scattering matrix with the same real
+// reference impedance z0 on every port: S = (Z - z0 I)(Z + z0 I)^-1.
+func zToS(z [][]complex128, z0 float64) ([][]complex128, error) {
+	inv, err := matrixInverse(matrixAddDiag(z, complex(z0, 0)))
+	if err != nil {
+		return nil, err
+	}
+	return matrixMul(matrixAddDiag(z, complex(-z0, 0)), inv), nil
+}
+
+// sToZ converts a scattering matrix back to an impedance matrix:
+// Z = z0 (I + S)(I - S)^-1.
+func sToZ(s [][]complex128, z0 float64) ([][]complex128, error) {
+	iMinusS := identityMatrix(len(s))
+	for i := range s {
+		for j := range s[i] {
+			iMinusS[i][j] -= s[i][j]
+		}
+	}
+	inv, err := matrixInverse(iMinusS)
+	if err != nil {
+		return nil, err
+	}
+	z := matrixMul(matrixAddDiag(s, 1), inv)
+	scaleMatrix(z, complex(z0, 0))
+	return z, nil
+}
+
+func scaleMatrix(a [][]complex128, f complex128) {
+	for i := range a {
+		for j := range a[i] {
+			a[i][j] *= f
+		}
+	}
+}
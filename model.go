@@ -0,0 +1,461 @@
+package necpp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Model is a whole antenna model held in Go, rather than sent straight to
+// libnecpp card by card. It can be built up, looked over, copied with Clone()
+// and changed, and run as many times as needed with Run(), which makes a
+// NecppCtx, plays the model into it and returns the results.
+//
+// The cards go into the NecppCtx in the order of the fields: the Geometry
+// cards in order, then the GE card with GroundPlane, the EK card if
+// ExtendedKernel is set, the Ground, the Frequency, the Loads, Lines and
+// Networks, the excitation (the Feeds, or the PlaneWave if there are no Feeds),
+// and finally the Requests in order. A model with no Requests just runs an XQ
+// card, which is enough to get the impedance.
+//
+// Output is where libnecpp's printed report goes, as for SetOutput(). A model's
+// report is always caught, since the Result's Output and Listing come from it,
+// so models run on separate goroutines take turns at the cards that print; run
+// them with RunWorker() to have them run side by side.
+//
+// Isolated makes Run() run the model in a worker process, as RunWorker() does,
+// so that libnecpp crashing on it returns a *CrashError instead of taking the
+// whole program down with it. The program's main() must call
+// ServeWorkerIfRequested() for this to work; see Worker.
+type Model struct {
+	Geometry       []GeometryCard
+	GroundPlane    GeoGroundPlaneFlag
+	ExtendedKernel bool
+	Ground         *Ground
+	Frequency      FrequencySweep
+
+	Loads    []Load
+	Lines    []TransmissionLine
+	Networks []NetworkConnection
+
+	Feeds     []Feed
+	PlaneWave *PlaneWave
+
+	Requests []Request
+
+	Output   io.Writer
+	Isolated bool
+}
+
+// GeometryCard is one step of building up a model's structure: a WireSpec, a
+// PatchSpec, a Move or a Reflection.
+type GeometryCard interface {
+	applyGeometry(n Engine) error
+}
+
+// Move copies or moves part of the structure, the way a GM card does. The
+// parameters are the same as GmCard()'s, with the angles in degrees and the
+// translation in meters.
+type Move struct {
+	TagIncrement int
+	Copies       int
+	RotX         float64
+	RotY         float64
+	RotZ         float64
+	DX           float64
+	DY           float64
+	DZ           float64
+	FromTag      int // the first tag moved; zero moves the whole structure
+}
+
+// Reflection reflects the structure in the coordinate planes, the way a GX
+// card does. X reflects along the X axis (in the YZ plane), and so on.
+type Reflection struct {
+	TagIncrement int
+	X            bool
+	Y            bool
+	Z            bool
+}
+
+// FrequencySweep is the frequencies a model is run at, as given to FrCard().
+// A zero Count is taken as one frequency.
+type FrequencySweep struct {
+	Range    FrequencyRange
+	Count    int
+	StartMHz float64
+	StepMHz  float64 // added for Linear, multiplied by for Logarithmic
+}
+
+// TransmissionLine is a TL card between two segments. Length is in meters,
+// with a velocity factor of 1; zero uses the distance between the segments. Y1
+// and Y2 are shunt admittances across each end, in mhos.
+type TransmissionLine struct {
+	From   Port
+	To     Port
+	Z0     float64
+	Length float64
+	Y1     complex128
+	Y2     complex128
+}
+
+// NetworkConnection is a two port network between two segments, put in with
+// ConnectTwoPort().
+type NetworkConnection struct {
+	From    Port
+	To      Port
+	Network TwoPort
+}
+
+// Request is something a model is run to find out: a PatternRequest, a
+// NearFieldRequest, or an Execute.
+type Request interface {
+	applyRequest(n Engine) error
+}
+
+// PatternRequest asks for a radiation pattern over a grid of directions. The
+// pattern is power gain, and Mode should be the ground's CalcMode() when there
+// is a ground with a radial screen or second medium. Distance is the radial
+// distance of the field points, in meters; zero leaves out the radiated field
+// strength.
+type PatternRequest struct {
+	Directions
+	Mode     RpCalcMode
+	Distance float64
+}
+
+// NearFieldRequest asks for the near electric field, or the near magnetic
+// field if Magnetic is set, over a grid. N is the number of points along each
+// coordinate, and Start and Step the first point and the steps between points,
+// as for NeCard().
+type NearFieldRequest struct {
+	Magnetic bool
+	Grid     NearFieldGrid
+	N        [3]int
+	Start    [3]float64
+	Step     [3]float64
+}
+
+// Execute is an XQ card, which runs the model without asking for anything in
+// particular, or with one of the fixed pattern cuts.
+type Execute ExecutionOption
+
+// Result is what running a Model found out. Impedance is the input impedance
+// of the first feed at each frequency, and is only there for models with
+// Feeds. Patterns holds every radiation pattern, in the order they were
+// calculated. The Listing holds everything else libnecpp printed, including
+// any currents, near fields and power budgets, and Output is the printed
+// report itself.
+type Result struct {
+	Frequencies []float64
+	Impedance   []ImpedancePoint
+	Patterns    []*RadiationPattern
+	Geometry    *Geometry
+	Listing     *Listing
+	Output      string
+}
+
+// NewModel returns an empty model, ready to be built up.
+func NewModel() *Model {
+	return &Model{}
+}
+
+// AddWire adds a straight wire to the model's geometry, and returns the model
+// so calls can be chained.
+func (m *Model) AddWire(tag int, segments int, x1 float64, y1 float64, z1 float64, x2 float64, y2 float64, z2 float64, radius float64) *Model {
+	m.Geometry = append(m.Geometry, WireSpec{Tag: tag, Segments: segments, X1: x1, Y1: y1, Z1: z1, X2: x2, Y2: y2, Z2: z2, Radius: radius, RDel: 1, RRad: 1})
+	return m
+}
+
+// Add adds geometry cards to the model.
+func (m *Model) Add(cards ...GeometryCard) *Model {
+	m.Geometry = append(m.Geometry, cards...)
+	return m
+}
+
+// AddFeed adds a voltage source.
+func (m *Model) AddFeed(p Port, voltage complex128) *Model {
+	m.Feeds = append(m.Feeds, Feed{Port: p, Voltage: voltage})
+	return m
+}
+
+// AddLoad adds a load.
+func (m *Model) AddLoad(l Load) *Model {
+	m.Loads = append(m.Loads, l)
+	return m
+}
+
+// SetFrequency sets a linear frequency sweep, of count frequencies from
+// startMHz in steps of stepMHz.
+func (m *Model) SetFrequency(startMHz float64, stepMHz float64, count int) *Model {
+	m.Frequency = FrequencySweep{Range: Linear, Count: count, StartMHz: startMHz, StepMHz: stepMHz}
+	return m
+}
+
+// AddRequest adds requests to the end of the model.
+func (m *Model) AddRequest(r ...Request) *Model {
+	m.Requests = append(m.Requests, r...)
+	return m
+}
+
+// Clone returns a deep copy of the model, which can be changed without
+// affecting the original. The Output writer is shared.
+func (m *Model) Clone() *Model {
+	c := *m
+	c.Geometry = make([]GeometryCard, len(m.Geometry))
+	for i, g := range m.Geometry {
+		if p, ok := g.(PatchSpec); ok {
+			p.Corners = append([][3]float64(nil), p.Corners...)
+			g = p
+		}
+		c.Geometry[i] = g
+	}
+	if m.Ground != nil {
+		g := *m.Ground
+		if g.Screen != nil {
+			s := *g.Screen
+			g.Screen = &s
+		}
+		if g.SecondMedium != nil {
+			s := *g.SecondMedium
+			g.SecondMedium = &s
+		}
+		c.Ground = &g
+	}
+	if m.PlaneWave != nil {
+		w := *m.PlaneWave
+		c.PlaneWave = &w
+	}
+	c.Loads = append([]Load(nil), m.Loads...)
+	c.Lines = append([]TransmissionLine(nil), m.Lines...)
+	c.Networks = append([]NetworkConnection(nil), m.Networks...)
+	c.Feeds = append([]Feed(nil), m.Feeds...)
+	c.Requests = append([]Request(nil), m.Requests...)
+	return &c
+}
+
+// check looks for the mistakes that would otherwise only show up as an error
+// from libnecpp, or a crash.
+func (m *Model) check() error {
+	if len(m.Geometry) == 0 {
+		return errors.New("the model has no geometry")
+	}
+	if m.Frequency.StartMHz <= 0 {
+		return errors.New("the model has no frequency")
+	}
+	if len(m.Feeds) == 0 && m.PlaneWave == nil {
+		return errors.New("the model has no excitation")
+	}
+	return nil
+}
+
+// Run runs the model and returns its results. A new NecppCtx is made for the
+// run and deleted afterwards, so the model can be run again, or changed and
+// run again, as often as needed. ctx is checked between cards; libnecpp can't
+// be interrupted partway through a card, but RunWorker() can be. An Isolated
+// model is run with RunWorker().
+func (m *Model) Run(ctx context.Context) (*Result, error) {
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	if m.Isolated {
+		return m.RunWorker(ctx)
+	}
+	n, err := New()
+	if err != nil {
+		return nil, err
+	}
+	defer n.Delete()
+	return m.RunEngine(ctx, n)
+}
+
+// RunEngine runs the model on an Engine that's already been made, such as a
+// FakeEngine, and returns its results. The engine should be fresh, with no
+// cards given to it yet, and it's left for the caller to delete.
+func (m *Model) RunEngine(ctx context.Context, e Engine) (*Result, error) {
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	e.SetOutput(m.Output)
+	if err := m.apply(ctx, e); err != nil {
+		return nil, err
+	}
+	return m.result(e)
+}
+
+// apply plays the model into n, card by card, checking ctx before each
+// geometry card, load, line, network, feed and request.
+func (m *Model) apply(ctx context.Context, n Engine) error {
+	for _, g := range m.Geometry {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := g.applyGeometry(n); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := n.GeometryComplete(m.GroundPlane); err != nil {
+		return err
+	}
+	if m.ExtendedKernel {
+		if err := n.EkCard(ExtendedThinWire); err != nil {
+			return err
+		}
+	}
+	if m.Ground != nil {
+		if err := n.SetGround(*m.Ground); err != nil {
+			return err
+		}
+	}
+	f := m.Frequency
+	if err := n.FrCard(f.Range, f.Count, f.StartMHz, f.StepMHz); err != nil {
+		return err
+	}
+	for _, l := range m.Loads {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := n.LdCard(l.Type, l.Tag, l.From, l.To, l.R, l.L, l.C); err != nil {
+			return err
+		}
+	}
+	for _, t := range m.Lines {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := n.TlCard(t.From.Tag, t.From.Segment, t.To.Tag, t.To.Segment, t.Z0, t.Length, real(t.Y1), imag(t.Y1), real(t.Y2), imag(t.Y2)); err != nil {
+			return err
+		}
+	}
+	for _, c := range m.Networks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := n.ConnectTwoPort(c.From, c.To, c.Network); err != nil {
+			return err
+		}
+	}
+	if len(m.Feeds) > 0 {
+		for _, fd := range m.Feeds {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := n.ExcitationVoltage(fd.Tag, fd.Segment, fd.Voltage); err != nil {
+				return err
+			}
+		}
+	} else if err := m.PlaneWave.excite(n); err != nil {
+		return err
+	}
+
+	requests := m.Requests
+	if len(requests) == 0 {
+		requests = []Request{Execute(NoPattern)}
+	}
+	for i, r := range requests {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := r.applyRequest(n); err != nil {
+			return fmt.Errorf("request %d: %s", i+1, err.Error())
+		}
+	}
+	return nil
+}
+
+// result gathers up the results of a run.
+func (m *Model) result(n Engine) (*Result, error) {
+	res := &Result{
+		Frequencies: n.Frequencies(),
+		Geometry:    n.Geometry(),
+		Output:      n.Output(),
+	}
+	var err error
+	if len(m.Feeds) > 0 {
+		if res.Impedance, err = n.ImpedanceSweep(); err != nil {
+			return nil, err
+		}
+	}
+	if res.Patterns, err = n.RadiationPatterns(); err != nil {
+		return nil, err
+	}
+	if res.Listing, err = n.Listing(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (w WireSpec) applyGeometry(n Engine) error {
+	rdel, rrad := w.RDel, w.RRad
+	if rdel == 0 {
+		rdel = 1
+	}
+	if rrad == 0 {
+		rrad = 1
+	}
+	return n.Wire(w.Tag, w.Segments, w.X1, w.Y1, w.Z1, w.X2, w.Y2, w.Z2, w.Radius, rdel, rrad)
+}
+
+func (p PatchSpec) applyGeometry(n Engine) error {
+	if p.Shape == Arbitrary {
+		if len(p.Corners) < 1 {
+			return errors.New("an arbitrary patch needs its center")
+		}
+		c := p.Corners[0]
+		return n.SpCard(Arbitrary, c[0], c[1], c[2], p.Elevation, p.Azimuth, p.Area)
+	}
+	need := 3
+	if p.Shape == Quadrilateral {
+		need = 4
+	}
+	if len(p.Corners) < need {
+		return fmt.Errorf("a patch of shape %d needs %d corners", int(p.Shape), need)
+	}
+	c := p.Corners
+	if err := n.SpCard(p.Shape, c[0][0], c[0][1], c[0][2], c[1][0], c[1][1], c[1][2]); err != nil {
+		return err
+	}
+	var c4 [3]float64
+	if p.Shape == Quadrilateral {
+		c4 = c[3]
+	}
+	return n.ScCard(int(p.Shape), c[2][0], c[2][1], c[2][2], c4[0], c4[1], c4[2])
+}
+
+func (mv Move) applyGeometry(n Engine) error {
+	return n.GmCard(mv.TagIncrement, mv.Copies, mv.RotX, mv.RotY, mv.RotZ, mv.DX, mv.DY, mv.DZ, mv.FromTag)
+}
+
+func (r Reflection) applyGeometry(n Engine) error {
+	i2 := 0
+	if r.X {
+		i2 += 100
+	}
+	if r.Y {
+		i2 += 10
+	}
+	if r.Z {
+		i2++
+	}
+	return n.GxCard(r.TagIncrement, i2)
+}
+
+func (r PatternRequest) applyRequest(n Engine) error {
+	nTheta, nPhi := r.counts()
+	return n.RpCard(r.Mode, nTheta, nPhi, MajorMinor, NoNormalization, PowerGain, NoAvg, r.Theta0, r.Phi0, r.DTheta, r.DPhi, r.Distance, 0)
+}
+
+func (r NearFieldRequest) applyRequest(n Engine) error {
+	card := n.NeCard
+	if r.Magnetic {
+		card = n.NhCard
+	}
+	return card(int(r.Grid), r.N[0], r.N[1], r.N[2], r.Start[0], r.Start[1], r.Start[2], r.Step[0], r.Step[1], r.Step[2])
+}
+
+func (e Execute) applyRequest(n Engine) error {
+	return n.XqCard(ExecutionOption(e))
+}
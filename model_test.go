@@ -0,0 +1,82 @@
+package necpp
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+)
+
+func simpleModel() *Model {
+	m := NewModel().
+		AddWire(0, 9, 0, 0, 2, 0, 0, 7, 0.1).
+		SetFrequency(30, 0, 1).
+		AddFeed(Port{Segment: 5}, 1).
+		AddRequest(PatternRequest{Directions: Directions{NTheta: 90, NPhi: 1, Theta0: 0, Phi0: 90, DTheta: 1}})
+	m.GroundPlane = CurrentExpansionModified
+	m.Ground = &Ground{Type: Perfect}
+	m.Output = ioutil.Discard
+	return m
+}
+
+func TestModelClone(t *testing.T) {
+	m := simpleModel()
+	m.Add(PatchSpec{Shape: Triangular, Corners: [][3]float64{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}})
+	m.Ground.Screen = &GroundScreen{Radials: 60, Radius: 10, WireRadius: 0.001}
+
+	c := m.Clone()
+	c.Geometry[1].(PatchSpec).Corners[2] = [3]float64{5, 5, 5}
+	c.Ground.Screen.Radials = 120
+	c.Feeds[0].Voltage = 2
+	c.AddLoad(Load{Tag: 0, From: 5, To: 5, R: 50})
+
+	if m.Geometry[1].(PatchSpec).Corners[2] != [3]float64{0, 1, 0} {
+		t.Errorf("changing the clone's patch changed the original's")
+	}
+	if m.Ground.Screen.Radials != 60 {
+		t.Errorf("changing the clone's ground changed the original's")
+	}
+	if m.Feeds[0].Voltage != 1 || len(m.Loads) != 0 {
+		t.Errorf("changing the clone's feeds and loads changed the original's")
+	}
+}
+
+func TestModelCheck(t *testing.T) {
+	m := simpleModel()
+	m.Feeds = nil
+	if _, err := m.Run(context.Background()); err == nil {
+		t.Errorf("a model with no excitation should have been an error")
+	}
+	m = simpleModel()
+	m.Frequency = FrequencySweep{}
+	if _, err := m.Run(context.Background()); err == nil {
+		t.Errorf("a model with no frequency should have been an error")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := simpleModel().RunEngine(ctx, new(FakeEngine)); err != context.Canceled {
+		t.Errorf("running with a cancelled context gave %v, should have been %v", err, context.Canceled)
+	}
+}
+
+// cancelEngine cancels its context once the first wire has been added.
+type cancelEngine struct {
+	*FakeEngine
+	cancel context.CancelFunc
+}
+
+func (c cancelEngine) Wire(tagId int, segmentCount int, xw1 float64, yw1 float64, zw1 float64, xw2 float64, yw2 float64, zw2 float64, rad float64, rdel float64, rrad float64) error {
+	defer c.cancel()
+	return c.FakeEngine.Wire(tagId, segmentCount, xw1, yw1, zw1, xw2, yw2, zw2, rad, rdel, rrad)
+}
+
+func TestModelCancelBetweenCards(t *testing.T) {
+	m := simpleModel().AddWire(1, 9, 1, 0, 2, 1, 0, 7, 0.1)
+	ctx, cancel := context.WithCancel(context.Background())
+	f := new(FakeEngine)
+	if _, err := m.RunEngine(ctx, cancelEngine{FakeEngine: f, cancel: cancel}); err != context.Canceled {
+		t.Errorf("cancelling partway through the geometry gave %v, should have been %v", err, context.Canceled)
+	}
+	if len(f.Calls) != 1 || f.Calls[0].Method != "Wire" {
+		t.Errorf("%d cards were made after the first wire, should have been none", len(f.Calls)-1)
+	}
+}
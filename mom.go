@@ -0,0 +1,629 @@
+package necpp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// The numerical side of Solver: a method of moments solution of the thin wire
+// electric field integral equation (Pocklington's equation, in its mixed
+// potential form) for straight wire segments.
+//
+// The current is expanded in triangle functions, each peaking at a node where
+// segments meet and falling to zero at the far ends of those segments, and the
+// equation is tested with the same functions (Galerkin's method). The wires
+// are taken to be thin tubes, using the reduced kernel: the current flows along
+// the axis of each segment and the field is matched on its surface. The 1/R
+// part of the kernel is integrated exactly along each source segment, and the
+// rest numerically. A perfect ground is handled with images.
+//
+// Pulse basis functions, point matched against Pocklington's equation, can be
+// used instead. Each pulse is the same shape as a triangle, running from the
+// middle of one segment, through a node, to the middle of the next, but
+// carries the same current all the way; the charge that leaves behind sits at
+// the middles of the segments. The equation is matched at the nodes, with the
+// derivatives of the scalar potential taken as differences between the middles
+// of the segments either side, as in Harrington's wire program. That's much
+// cruder, and needs more segments for the same accuracy, but it's the method
+// the textbooks start with, and a check on the other.
+
+const (
+	lightSpeed = SpeedOfLight * 1e6 // meters per second
+	mu0        = 4e-7 * math.Pi     // henries per meter
+	eps0       = 1 / (mu0 * lightSpeed * lightSpeed)
+)
+
+// momSegment is a wire segment, with its direction and length worked out.
+type momSegment struct {
+	Segment
+	dir    [3]float64
+	length float64
+}
+
+// momHalf is one segment's part of a basis function. The current rises
+// linearly along the segment from zero at one end to one at the other (end 0
+// is the segment's Start, end 1 its End), and sign is +1 if it flows from Start
+// to End.
+type momHalf struct {
+	seg  int
+	end  int
+	sign float64
+}
+
+// momBasis is a triangle basis function: two halves meeting at a node, or a
+// single half for a wire ending on a perfect ground, where the other half is
+// its image.
+type momBasis []momHalf
+
+// momMedium is the medium the antenna is in, at one frequency.
+type momMedium struct {
+	omega float64
+	mu    float64
+	eps   float64
+	k     float64
+	eta   float64
+}
+
+func newMomMedium(freqMHz float64, eps float64, mu float64) momMedium {
+	omega := 2 * math.Pi * freqMHz * 1e6
+	return momMedium{omega: omega, mu: mu, eps: eps, k: omega * math.Sqrt(mu*eps), eta: math.Sqrt(mu / eps)}
+}
+
+// momSystem is a structure broken up into segments and basis functions.
+type momSystem struct {
+	segs   []momSegment
+	bases  []momBasis
+	ground bool
+	pulse  bool // pulse basis functions and point matching, not triangles and Galerkin
+}
+
+// newMomSystem works out the basis functions for a set of segments: one for
+// each pair of segments meeting at a node (k-1 of them at a junction of k
+// segments), and one for each segment touching a perfect ground.
+func newMomSystem(segs []Segment, ground bool) (*momSystem, error) {
+	if len(segs) == 0 {
+		return nil, errors.New("the structure has no wire segments")
+	}
+	s := &momSystem{ground: ground}
+	minLen := math.Inf(1)
+	for _, sg := range segs {
+		l := sg.Length()
+		if l == 0 {
+			return nil, fmt.Errorf("segment %d has no length", sg.Number)
+		}
+		if sg.Radius <= 0 {
+			return nil, fmt.Errorf("segment %d has no radius", sg.Number)
+		}
+		var d [3]float64
+		for i := range d {
+			d[i] = (sg.End[i] - sg.Start[i]) / l
+		}
+		s.segs = append(s.segs, momSegment{Segment: sg, dir: d, length: l})
+		minLen = math.Min(minLen, l)
+	}
+	tol := 1e-3 * minLen
+
+	// group the segment ends into nodes
+	type segEnd struct {
+		seg int
+		end int
+	}
+	var nodes [][3]float64
+	var touching [][]segEnd
+	for i, sg := range s.segs {
+		for end, p := range [2][3]float64{sg.Start, sg.End} {
+			if ground && p[2] < -tol {
+				return nil, fmt.Errorf("segment %d is below the ground", sg.Number)
+			}
+			found := -1
+			for j, n := range nodes {
+				if dist(n, p) < tol {
+					found = j
+					break
+				}
+			}
+			if found < 0 {
+				nodes = append(nodes, p)
+				touching = append(touching, nil)
+				found = len(nodes) - 1
+			}
+			touching[found] = append(touching[found], segEnd{i, end})
+		}
+	}
+
+	// current flowing into the node along a segment is with the segment's
+	// direction if the node is at its End
+	into := func(e segEnd) momHalf {
+		if e.end == 1 {
+			return momHalf{seg: e.seg, end: 1, sign: 1}
+		}
+		return momHalf{seg: e.seg, end: 0, sign: -1}
+	}
+	for j, ends := range touching {
+		if ground && math.Abs(nodes[j][2]) < tol {
+			for _, e := range ends {
+				h := into(e)
+				h.sign = -h.sign // flowing out of the ground, into the wire
+				s.bases = append(s.bases, momBasis{h})
+			}
+			continue
+		}
+		for _, e := range ends[1:] {
+			out := into(e)
+			out.sign = -out.sign
+			s.bases = append(s.bases, momBasis{into(ends[0]), out})
+		}
+	}
+	if len(s.bases) == 0 {
+		return nil, errors.New("the structure has no connected segments for current to flow on")
+	}
+	return s, nil
+}
+
+// momSolution is the current on a structure at one frequency.
+type momSolution struct {
+	freqMHz float64
+	sys     *momSystem
+	med     momMedium
+	ends    [][2]complex128 // the current at each end of each segment, Start to End
+	center  []complex128    // the current at the middle of each segment
+	sources []momSource
+	loads   []complex128 // the load impedance on each segment
+}
+
+// momSource is a voltage source on a segment, and the current it drives.
+type momSource struct {
+	seg     int
+	tag     int
+	voltage complex128
+	current complex128
+}
+
+// impedance is the input impedance at a source.
+func (src momSource) impedance() complex128 {
+	return src.voltage / src.current
+}
+
+// inputPower is the power fed in by all of the sources, in watts.
+func (sol *momSolution) inputPower() float64 {
+	p := 0.0
+	for _, src := range sol.sources {
+		p += 0.5 * real(src.voltage*cmplx.Conj(src.current))
+	}
+	return p
+}
+
+// loss is the power lost in the loads, in watts.
+func (sol *momSolution) loss() float64 {
+	p := 0.0
+	for i, z := range sol.loads {
+		a := cmplx.Abs(sol.center[i])
+		p += 0.5 * a * a * real(z)
+	}
+	return p
+}
+
+// solve fills the impedance matrix, applies the loads and sources (both
+// lumped at the middle of their segments), and solves for the current.
+func (s *momSystem) solve(med momMedium, loads []complex128, sources []momSource) (*momSolution, error) {
+	n := len(s.bases)
+	blocks := s.segmentBlocks(med)
+	z := newMatrix(n, n)
+	for m, bm := range s.bases {
+		for k, bk := range s.bases {
+			var sum complex128
+			for _, hm := range bm {
+				for _, hk := range bk {
+					sum += complex(hm.sign*hk.sign, 0) * blocks[hm.seg][hk.seg][hm.end][hk.end]
+					if hm.seg == hk.seg {
+						sum += complex(0.25*hm.sign*hk.sign, 0) * loads[hm.seg]
+					}
+				}
+			}
+			z[m][k] = sum
+		}
+	}
+	v := make([]complex128, n)
+	for m, bm := range s.bases {
+		for _, h := range bm {
+			for _, src := range sources {
+				if src.seg == h.seg {
+					v[m] += complex(0.5*h.sign, 0) * src.voltage
+				}
+			}
+		}
+	}
+	c, err := solveLinear(z, v)
+	if err != nil {
+		return nil, err
+	}
+
+	sol := &momSolution{sys: s, med: med, loads: loads}
+	sol.ends = make([][2]complex128, len(s.segs))
+	for b, bm := range s.bases {
+		for _, h := range bm {
+			sol.ends[h.seg][h.end] += complex(h.sign, 0) * c[b]
+		}
+	}
+	sol.center = make([]complex128, len(s.segs))
+	for i, e := range sol.ends {
+		sol.center[i] = (e[0] + e[1]) / 2
+	}
+	for _, src := range sources {
+		src.current = sol.center[src.seg]
+		if src.current == 0 {
+			return nil, fmt.Errorf("no current flows at the source on segment %d", s.segs[src.seg].Number)
+		}
+		sol.sources = append(sol.sources, src)
+	}
+	return sol, nil
+}
+
+// segmentBlocks works out the interaction between the halves of basis
+// functions on every pair of segments, including the images in the ground.
+// blocks[p][q][a][b] is the field tested with the half on segment p peaking at
+// end a, due to the current on segment q peaking at end b (both flowing from
+// Start to End).
+func (s *momSystem) segmentBlocks(med momMedium) [][][2][2]complex128 {
+	ns := len(s.segs)
+	blocks := make([][][2][2]complex128, ns)
+	for p := range blocks {
+		blocks[p] = make([][2][2]complex128, ns)
+	}
+	block := s.block
+	if s.pulse {
+		block = s.pulseBlock
+	}
+	for p := 0; p < ns; p++ {
+		// point matching isn't symmetric, so every pair is worked out
+		from := p
+		if s.pulse {
+			from = 0
+		}
+		for q := from; q < ns; q++ {
+			sq := &s.segs[q]
+			b := block(med, &s.segs[p], sq.Start, sq.End, sq.dir, sq.Radius, 1)
+			if s.ground {
+				b2 := block(med, &s.segs[p], image(sq.Start), image(sq.End), image(sq.dir), sq.Radius, -1)
+				for a := 0; a < 2; a++ {
+					for c := 0; c < 2; c++ {
+						b[a][c] += b2[a][c]
+					}
+				}
+			}
+			blocks[p][q] = b
+			if s.pulse {
+				continue
+			}
+			for a := 0; a < 2; a++ {
+				for c := 0; c < 2; c++ {
+					blocks[q][p][c][a] = b[a][c]
+				}
+			}
+		}
+	}
+	return blocks
+}
+
+// image reflects a point or a direction in the ground.
+func image(v [3]float64) [3]float64 {
+	return [3]float64{v[0], v[1], -v[2]}
+}
+
+// block works out one segment pair's part of the impedance matrix, for a
+// source segment from qa to qb carrying sign times the current.
+func (s *momSystem) block(med momMedium, p *momSegment, qa [3]float64, qb [3]float64, qdir [3]float64, radius float64, sign float64) [2][2]complex128 {
+	lq := dist(qa, qb)
+	mid := lerp(qa, qb, 0.5)
+	near := dist(p.Center(), mid) < 2*math.Max(p.length, lq)
+	pi := s.kernelIntegrals(med, p, qa, qb, radius, near)
+
+	dot := p.dir[0]*qdir[0] + p.dir[1]*qdir[1] + p.dir[2]*qdir[2]
+	vector := complex(0, med.omega*med.mu/(4*math.Pi)*dot*sign)
+	scalar := complex(0, -sign/(4*math.Pi*med.omega*med.eps*p.length*lq))
+	total := pi[0][0] + pi[0][1] + pi[1][0] + pi[1][1]
+	slope := [2]float64{-1, 1}
+	var b [2][2]complex128
+	for a := 0; a < 2; a++ {
+		for c := 0; c < 2; c++ {
+			b[a][c] = vector*pi[a][c] + scalar*complex(slope[a]*slope[c], 0)*total
+		}
+	}
+	return b
+}
+
+// pulseBlock is block for pulse basis functions. The half of a pulse at end a
+// of segment p runs from that end to the middle of the segment, and the field
+// is matched at the end, over half the segment's length, with the potential
+// taken at the two ends of the half.
+//
+// The charge a half leaves at the middle of its segment is spread evenly along
+// the segment, as Harrington has it. The charge at the node is cancelled by the
+// other half of the pulse, or by its image, so it's just taken as a point.
+func (s *momSystem) pulseBlock(med momMedium, p *momSegment, qa [3]float64, qb [3]float64, qdir [3]float64, radius float64, sign float64) [2][2]complex128 {
+	lq := dist(qa, qb)
+	qm, pm := lerp(qa, qb, 0.5), p.Center()
+	tests := [2][2][3]float64{{p.Start, pm}, {pm, p.End}}
+	nodes := [2][3]float64{p.Start, p.End}
+
+	dot := p.dir[0]*qdir[0] + p.dir[1]*qdir[1] + p.dir[2]*qdir[2]
+	vector := complex(0, med.omega*med.mu/(4*math.Pi)*dot*sign*p.length/2)
+	scalar := complex(0, -sign/(4*math.Pi*med.omega*med.eps))
+	// the potential of the charge at the middle of the source segment, for
+	// current flowing away from it, and of the charges at its ends, for
+	// current flowing into them
+	middle := func(r [3]float64) complex128 {
+		return -lineIntegral(med, r, qa, qb, radius) / complex(lq, 0)
+	}
+	ends := [2][3]float64{qa, qb}
+	var b [2][2]complex128
+	for c := 0; c < 2; c++ {
+		phi := func(r [3]float64) complex128 {
+			// current from the middle to end 1, or from end 0 to the middle
+			if c == 1 {
+				return scalar * (greens(med, dist(r, ends[1]), radius) + middle(r))
+			}
+			return scalar * (-middle(r) - greens(med, dist(r, ends[0]), radius))
+		}
+		from, to := qa, qm
+		if c == 1 {
+			from, to = qm, qb
+		}
+		for a := 0; a < 2; a++ {
+			b[a][c] = vector*lineIntegral(med, nodes[a], from, to, radius) + phi(tests[a][1]) - phi(tests[a][0])
+		}
+	}
+	return b
+}
+
+// greens is the reduced kernel, exp(-jkR)/R, for points a distance d apart
+// along a wire of the given radius.
+func greens(med momMedium, d float64, radius float64) complex128 {
+	r := math.Sqrt(d*d + radius*radius)
+	return cmplx.Exp(complex(0, -med.k*r)) / complex(r, 0)
+}
+
+// lineIntegral works out the integral of the reduced kernel at r over the
+// line from qa to qb. As in kernelIntegrals, the 1/R part is done exactly.
+func lineIntegral(med momMedium, r [3]float64, qa [3]float64, qb [3]float64, radius float64) complex128 {
+	lq := dist(qa, qb)
+	var d [3]float64
+	s0 := 0.0
+	for i := range d {
+		d[i] = r[i] - qa[i]
+		s0 += d[i] * (qb[i] - qa[i]) / lq
+	}
+	rho2 := d[0]*d[0] + d[1]*d[1] + d[2]*d[2] - s0*s0
+	if rho2 < 0 {
+		rho2 = 0
+	}
+	rho2 += radius * radius
+	rho := math.Sqrt(rho2)
+	sum := complex(math.Asinh((lq-s0)/rho)-math.Asinh(-s0/rho), 0)
+	k := complex(0, -med.k)
+	for _, h := range gauss8 {
+		R := math.Sqrt(dist2(r, lerp(qa, qb, h.x)) + radius*radius)
+		sum += (cmplx.Exp(k*complex(R, 0)) - 1) / complex(R, 0) * complex(h.w*lq, 0)
+	}
+	return sum
+}
+
+// kernelIntegrals works out the double integrals over segment p and the
+// source segment from qa to qb of la(t) lb(t') exp(-jkR)/R, where la and lb
+// are the linear functions rising to one at end a of p and end b of q.
+func (s *momSystem) kernelIntegrals(med momMedium, p *momSegment, qa [3]float64, qb [3]float64, radius float64, near bool) [2][2]complex128 {
+	lq := dist(qa, qb)
+	var u [3]float64
+	for i := range u {
+		u[i] = (qb[i] - qa[i]) / lq
+	}
+	outer := gauss8
+	pieces := 1
+	if near {
+		pieces = 4
+	}
+	a2 := radius * radius
+	k := complex(0, -med.k)
+
+	var out [2][2]complex128
+	for piece := 0; piece < pieces; piece++ {
+		for _, g := range outer {
+			t := (float64(piece) + g.x) / float64(pieces)
+			w := g.w * p.length / float64(pieces)
+			r := lerp(p.Start, p.End, t)
+
+			// the 1/R part, exactly
+			var d [3]float64
+			for i := range d {
+				d[i] = r[i] - qa[i]
+			}
+			s0 := d[0]*u[0] + d[1]*u[1] + d[2]*u[2]
+			rho2 := d[0]*d[0] + d[1]*d[1] + d[2]*d[2] - s0*s0
+			if rho2 < 0 {
+				rho2 = 0
+			}
+			rho2 += a2
+			rho := math.Sqrt(rho2)
+			x1, x2 := -s0, lq-s0
+			j0 := math.Asinh(x2/rho) - math.Asinh(x1/rho)
+			j1 := math.Sqrt(x2*x2+rho2) - math.Sqrt(x1*x1+rho2)
+			in1 := complex((j1+s0*j0)/lq, 0)
+			in0 := complex(j0, 0) - in1
+
+			// the rest, numerically
+			for _, h := range gauss8 {
+				rp := lerp(qa, qb, h.x)
+				R := math.Sqrt(dist2(r, rp) + a2)
+				f := (cmplx.Exp(k*complex(R, 0)) - 1) / complex(R, 0) * complex(h.w*lq, 0)
+				in0 += f * complex(1-h.x, 0)
+				in1 += f * complex(h.x, 0)
+			}
+
+			la := [2]float64{1 - t, t}
+			for a := 0; a < 2; a++ {
+				out[a][0] += complex(w*la[a], 0) * in0
+				out[a][1] += complex(w*la[a], 0) * in1
+			}
+		}
+	}
+	return out
+}
+
+func dist2(a [3]float64, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
+
+// radiationVector works out the integral over the structure (and its image) of
+// the current times exp(jk rhat.r'), which gives the far field in the
+// direction rhat.
+func (sol *momSolution) radiationVector(rhat [3]float64) [3]complex128 {
+	var n [3]complex128
+	add := func(start [3]float64, dir [3]float64, length float64, i0 complex128, i1 complex128) {
+		phase := sol.med.k * (rhat[0]*start[0] + rhat[1]*start[1] + rhat[2]*start[2])
+		beta := sol.med.k * length * (rhat[0]*dir[0] + rhat[1]*dir[1] + rhat[2]*dir[2])
+		f0, f1 := linearPhaseIntegrals(beta)
+		c := cmplx.Exp(complex(0, phase)) * complex(length, 0) * (i0*f0 + i1*f1)
+		for i := range n {
+			n[i] += c * complex(dir[i], 0)
+		}
+	}
+	for i, sg := range sol.sys.segs {
+		e := sol.ends[i]
+		add(sg.Start, sg.dir, sg.length, e[0], e[1])
+		if sol.sys.ground {
+			add(image(sg.Start), image(sg.dir), sg.length, -e[0], -e[1])
+		}
+	}
+	return n
+}
+
+// linearPhaseIntegrals returns the integrals from 0 to 1 of (1-t)exp(j beta t)
+// and t exp(j beta t).
+func linearPhaseIntegrals(beta float64) (complex128, complex128) {
+	if math.Abs(beta) < 0.1 {
+		// the series, to avoid losing precision
+		var total, f1 complex128
+		term := complex(1, 0) // (j beta)^n / n!
+		for n := 0; n < 10; n++ {
+			total += term / complex(float64(n+1), 0)
+			f1 += term / complex(float64(n+2), 0)
+			term *= complex(0, beta) / complex(float64(n+1), 0)
+		}
+		return total - f1, f1
+	}
+	e := cmplx.Exp(complex(0, beta))
+	jb := complex(0, beta)
+	total := (e - 1) / jb
+	f1 := e/jb + (e-1)/complex(beta*beta, 0)
+	return total - f1, f1
+}
+
+// farField returns the theta and phi components of the far field radiation
+// vector in a direction, in degrees. Below a perfect ground there's no field.
+func (sol *momSolution) farField(theta float64, phi float64) (complex128, complex128) {
+	if sol.sys.ground && theta > 90 {
+		return 0, 0
+	}
+	th, ph := theta*math.Pi/180, phi*math.Pi/180
+	st, ct := math.Sincos(th)
+	sp, cp := math.Sincos(ph)
+	n := sol.radiationVector([3]float64{st * cp, st * sp, ct})
+	thetaHat := [3]float64{ct * cp, ct * sp, -st}
+	phiHat := [3]float64{-sp, cp, 0}
+	var eTheta, ePhi complex128
+	for i := range n {
+		eTheta += n[i] * complex(thetaHat[i], 0)
+		ePhi += n[i] * complex(phiHat[i], 0)
+	}
+	return eTheta, ePhi
+}
+
+// gain turns a component of the radiation vector into a power gain (as a
+// ratio) for power fed in.
+func (sol *momSolution) gain(n complex128, power float64) float64 {
+	if power <= 0 {
+		return 0
+	}
+	a := cmplx.Abs(n)
+	m := sol.med
+	return m.omega * m.omega * m.mu * m.mu * a * a / (8 * math.Pi * m.eta * power)
+}
+
+// fieldStrength is the magnitude of a component of the radiation vector as an
+// electric field at a distance, in volts per meter.
+func (sol *momSolution) fieldStrength(n complex128, distance float64) complex128 {
+	m := sol.med
+	return complex(0, -m.omega*m.mu/(4*math.Pi*distance)) * cmplx.Exp(complex(0, -m.k*distance)) * n
+}
+
+// gaussPoint is a point of a quadrature rule over [0, 1].
+type gaussPoint struct {
+	x float64
+	w float64
+}
+
+var gauss8 = gaussLegendre(8)
+
+// gaussLegendre works out the n point Gauss-Legendre rule over [0, 1].
+func gaussLegendre(n int) []gaussPoint {
+	pts := make([]gaussPoint, n)
+	for i := 0; i < n; i++ {
+		x := math.Cos(math.Pi * (float64(i) + 0.75) / (float64(n) + 0.5))
+		var dp float64
+		for iter := 0; iter < 100; iter++ {
+			p0, p1 := 1.0, x
+			for j := 2; j <= n; j++ {
+				p0, p1 = p1, ((2*float64(j)-1)*x*p1-(float64(j)-1)*p0)/float64(j)
+			}
+			dp = float64(n) * (x*p1 - p0) / (x*x - 1)
+			dx := p1 / dp
+			x -= dx
+			if math.Abs(dx) < 1e-15 {
+				break
+			}
+		}
+		pts[i] = gaussPoint{x: (1 - x) / 2, w: 1 / ((1 - x*x) * dp * dp)}
+	}
+	return pts
+}
+
+// solveLinear solves a x = b by Gaussian elimination with partial pivoting.
+// a and b are overwritten.
+func solveLinear(a [][]complex128, b []complex128) ([]complex128, error) {
+	n := len(a)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if cmplx.Abs(a[r][col]) > cmplx.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		if a[pivot][col] == 0 {
+			return nil, errSingularMatrix
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+		for r := col + 1; r < n; r++ {
+			f := a[r][col] / a[col][col]
+			if f == 0 {
+				continue
+			}
+			for j := col; j < n; j++ {
+				a[r][j] -= f * a[col][j]
+			}
+			b[r] -= f * b[col]
+		}
+	}
+	x := make([]complex128, n)
+	for r := n - 1; r >= 0; r-- {
+		sum := b[r]
+		for j := r + 1; j < n; j++ {
+			sum -= a[r][j] * x[j]
+		}
+		x[r] = sum / a[r][r]
+	}
+	return x, nil
+}
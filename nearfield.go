@@ -0,0 +1,238 @@
+package necpp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrNoNearField is returned by NearField() when no near fields have been
+// calculated, or none could be found in libnecpp's output.
+var ErrNoNearField = errors.New("no near fields calculated")
+
+// NearFieldGrid is the kind of grid near fields are calculated on, given as
+// the first parameter of NeCard() and NhCard().
+//
+// • RectangularGrid - the points are given by their X, Y and Z coordinates.
+//
+// • SphericalGrid - the points are given by their distance R from the origin,
+// and the angles phi and theta in degrees.
+type NearFieldGrid int
+
+const (
+	RectangularGrid NearFieldGrid = iota
+	SphericalGrid
+)
+
+// NearFieldPoint is the near field at a single point. Position is always in
+// rectangular coordinates, in meters; Coords are the coordinates as they were
+// given on the NE or NH card, which are (R, phi, theta) for a SphericalGrid.
+// E is (Ex, Ey, Ez) in volts per meter, and H is (Hx, Hy, Hz) in amps per
+// meter. Either of them may be all zeroes if only the other one was asked for.
+type NearFieldPoint struct {
+	Position [3]float64
+	Coords   [3]float64
+	E        [3]complex128
+	H        [3]complex128
+}
+
+// NearField is the near field calculated over a grid at one frequency by an
+// NE card, an NH card, or both. N holds the number of points along each
+// coordinate of the grid, in the same order as Coords; the points are ordered
+// with the first coordinate changing fastest and the last slowest, the way NEC
+// works through them.
+type NearField struct {
+	FreqMHz  float64
+	Grid     NearFieldGrid
+	N        [3]int
+	Electric bool
+	Magnetic bool
+	Points   []NearFieldPoint
+}
+
+// Point returns the point at index i, j and k along the first, second and
+// third coordinates of the grid.
+func (nf *NearField) Point(i int, j int, k int) NearFieldPoint {
+	return nf.Points[(k*nf.N[1]+j)*nf.N[0]+i]
+}
+
+// NearField returns the near fields calculated by the NE and NH cards. The
+// index counts the calculations in order, so with an FR card sweeping several
+// frequencies followed by an NE card, index 0 is the first frequency, 1 the
+// second, and so on. Electric and magnetic fields calculated over the same
+// grid at the same frequency are combined into one result.
+//
+// libnecpp only prints its near field results, so these are taken from its
+// output, which a NecppCtx only catches once SetOutput() has been called. The
+// output is read as each run prints it.
+func (r *recorder) NearField(freqIndex int) (*NearField, error) {
+	if r.parseErr != nil {
+		return nil, r.parseErr
+	}
+	fields := r.results.NearFields
+	if len(fields) == 0 {
+		return nil, ErrNoNearField
+	}
+	if freqIndex < 0 || freqIndex >= len(fields) {
+		return nil, fmt.Errorf("near field index %d out of range; there are %d", freqIndex, len(fields))
+	}
+	return fields[freqIndex], nil
+}
+
+// parseNearFields picks the near field tables out of NEC's printed output.
+func parseNearFields(out string) ([]*NearField, error) {
+	var fields []*NearField
+	var cur *NearField // the table being read
+	var magnetic bool
+	var order [3]int // which column each of the grid's coordinates is in
+	freq := 0.0
+
+	sc := newScanner(out)
+	for sc.Scan() {
+		line := sc.Text()
+		if f, ok := frequency(line); ok {
+			freq = f
+			continue
+		}
+		if strings.Contains(line, "NEAR ELECTRIC FIELDS") || strings.Contains(line, "NEAR MAGNETIC FIELDS") {
+			if cur != nil {
+				fields = mergeNearField(fields, cur, magnetic)
+			}
+			magnetic = strings.Contains(line, "MAGNETIC")
+			cur = &NearField{FreqMHz: freq}
+			order = [3]int{0, 1, 2}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		tok := strings.Fields(line)
+		vals, ok := parseFloats(tok)
+		if !ok || len(vals) != 9 {
+			if len(cur.Points) > 0 {
+				// the end of the table
+				fields = mergeNearField(fields, cur, magnetic)
+				cur = nil
+				continue
+			}
+			// one of the header lines, which give the coordinates
+			if idx := indexOf(tok, "THETA"); idx >= 0 {
+				cur.Grid = SphericalGrid
+				order = [3]int{indexOf(tok, "R"), indexOf(tok, "PHI"), idx}
+				if order[0] < 0 || order[1] < 0 || order[0] > 2 || order[1] > 2 || order[2] > 2 {
+					return nil, fmt.Errorf("can't make sense of the near field header %q", strings.TrimSpace(line))
+				}
+			}
+			continue
+		}
+		var p NearFieldPoint
+		for i := range p.Coords {
+			p.Coords[i] = vals[order[i]]
+		}
+		p.Position = p.Coords
+		if cur.Grid == SphericalGrid {
+			r, ph, th := p.Coords[0], p.Coords[1]*math.Pi/180, p.Coords[2]*math.Pi/180
+			p.Position = [3]float64{r * math.Sin(th) * math.Cos(ph), r * math.Sin(th) * math.Sin(ph), r * math.Cos(th)}
+		}
+		var f [3]complex128
+		for i := range f {
+			f[i] = cmplx.Rect(vals[3+2*i], vals[4+2*i]*math.Pi/180)
+		}
+		if magnetic {
+			p.H = f
+		} else {
+			p.E = f
+		}
+		cur.Points = append(cur.Points, p)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if cur != nil {
+		fields = mergeNearField(fields, cur, magnetic)
+	}
+	return fields, nil
+}
+
+// mergeNearField finishes off a table that's been read, and adds it to the
+// list.
+func mergeNearField(fields []*NearField, nf *NearField, magnetic bool) []*NearField {
+	if len(nf.Points) == 0 {
+		return fields
+	}
+	nf.Electric, nf.Magnetic = !magnetic, magnetic
+	for i := range nf.N {
+		nf.N[i] = distinct(nf.Points, i)
+	}
+	return appendNearField(fields, nf)
+}
+
+// appendNearField adds a finished table to the list, folding a magnetic table
+// into the electric one just before it if they're over the same points at the
+// same frequency.
+func appendNearField(fields []*NearField, nf *NearField) []*NearField {
+	if nf.Magnetic && !nf.Electric && len(fields) > 0 {
+		prev := fields[len(fields)-1]
+		if prev.Electric && !prev.Magnetic && prev.FreqMHz == nf.FreqMHz && samePoints(prev, nf) {
+			for i := range prev.Points {
+				prev.Points[i].H = nf.Points[i].H
+			}
+			prev.Magnetic = true
+			return fields
+		}
+	}
+	return append(fields, nf)
+}
+
+// distinct counts the different values of coordinate c over the points.
+func distinct(pts []NearFieldPoint, c int) int {
+	vals := make([]float64, len(pts))
+	for i, p := range pts {
+		vals[i] = p.Coords[c]
+	}
+	sort.Float64s(vals)
+	n := 0
+	for i, v := range vals {
+		if i == 0 || v != vals[i-1] {
+			n++
+		}
+	}
+	return n
+}
+
+func samePoints(a *NearField, b *NearField) bool {
+	if len(a.Points) != len(b.Points) || a.Grid != b.Grid {
+		return false
+	}
+	for i := range a.Points {
+		if a.Points[i].Coords != b.Points[i].Coords {
+			return false
+		}
+	}
+	return true
+}
+
+func parseFloats(tok []string) ([]float64, bool) {
+	vals := make([]float64, len(tok))
+	for i, t := range tok {
+		v, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return nil, false
+		}
+		vals[i] = v
+	}
+	return vals, len(vals) > 0
+}
+
+func indexOf(tok []string, s string) int {
+	for i, t := range tok {
+		if t == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,76 @@
+package necpp
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+const nearFieldOutput = `
+                               --------- FREQUENCY --------
+                                FREQUENCY= 2.9900E+02 MHZ
+                                WAVELENGTH= 1.0027E+00 METERS
+
+
+                             -------- NEAR ELECTRIC FIELDS --------
+     ------- LOCATION -------     ------- EX ------    ------- EY ------    ------- EZ ------
+      X         Y         Z       MAGNITUDE   PHASE    MAGNITUDE   PHASE    MAGNITUDE   PHASE
+    METERS    METERS    METERS     VOLTS/M  DEGREES    VOLTS/M  DEGREES    VOLTS/M  DEGREES
+    1.0000    0.0000    0.0000  1.0000E+00    0.00  0.0000E+00    0.00  2.0000E+00   90.00
+    2.0000    0.0000    0.0000  5.0000E-01    0.00  0.0000E+00    0.00  1.0000E+00   90.00
+    1.0000    0.0000    1.0000  2.5000E-01    0.00  0.0000E+00    0.00  5.0000E-01  -90.00
+    2.0000    0.0000    1.0000  1.2500E-01    0.00  0.0000E+00    0.00  2.5000E-01  180.00
+
+
+                             -------- NEAR MAGNETIC FIELDS ---------
+     ------- LOCATION -------     ------- HX ------    ------- HY ------    ------- HZ ------
+      X         Y         Z       MAGNITUDE   PHASE    MAGNITUDE   PHASE    MAGNITUDE   PHASE
+    METERS    METERS    METERS      AMPS/M  DEGREES     AMPS/M  DEGREES     AMPS/M  DEGREES
+    1.0000    0.0000    0.0000  0.0000E+00    0.00  3.0000E-03   45.00  0.0000E+00    0.00
+    2.0000    0.0000    0.0000  0.0000E+00    0.00  1.5000E-03   45.00  0.0000E+00    0.00
+    1.0000    0.0000    1.0000  0.0000E+00    0.00  7.5000E-04   45.00  0.0000E+00    0.00
+    2.0000    0.0000    1.0000  0.0000E+00    0.00  3.7500E-04   45.00  0.0000E+00    0.00
+
+
+                             -------- NEAR ELECTRIC FIELDS --------
+     ------- LOCATION -------     ------- EX ------    ------- EY ------    ------- EZ ------
+      R         PHI       THETA     MAGNITUDE   PHASE    MAGNITUDE   PHASE    MAGNITUDE   PHASE
+    METERS    DEGREES    DEGREES    VOLTS/M    DEGREES   VOLTS/M   DEGREES    VOLTS/M   DEGREES
+    2.0000    90.0000   90.0000  1.0000E+00    0.00  0.0000E+00    0.00  0.0000E+00    0.00
+`
+
+func TestParseNearFields(t *testing.T) {
+	fields, err := parseNearFields(nearFieldOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 2 {
+		t.Fatalf("there were %d near fields, should have been 2 (with the E and H fields combined)", len(fields))
+	}
+	nf := fields[0]
+	if nf.FreqMHz != 299 || !nf.Electric || !nf.Magnetic || nf.Grid != RectangularGrid {
+		t.Errorf("first near field was %g MHz, electric %v, magnetic %v, grid %d", nf.FreqMHz, nf.Electric, nf.Magnetic, nf.Grid)
+	}
+	if nf.N != [3]int{2, 1, 2} {
+		t.Errorf("grid size was %v, should have been [2 1 2]", nf.N)
+	}
+	p := nf.Point(1, 0, 1)
+	if p.Position != [3]float64{2, 0, 1} {
+		t.Errorf("point (1, 0, 1) was at %v", p.Position)
+	}
+	if cmplx.Abs(p.E[2]+0.25) > 1e-9 {
+		t.Errorf("Ez at (2, 0, 1) was %v, should have been -0.25", p.E[2])
+	}
+	if cmplx.Abs(p.H[1]-cmplx.Rect(3.75e-4, math.Pi/4)) > 1e-12 {
+		t.Errorf("Hy at (2, 0, 1) was %v", p.H[1])
+	}
+
+	sph := fields[1]
+	if sph.Grid != SphericalGrid || sph.Magnetic {
+		t.Errorf("second near field should have been electric only on a spherical grid")
+	}
+	pos := sph.Points[0].Position
+	if math.Abs(pos[0]) > 1e-9 || math.Abs(pos[1]-2) > 1e-9 || math.Abs(pos[2]) > 1e-9 {
+		t.Errorf("spherical point was at %v, should have been (0, 2, 0)", pos)
+	}
+}
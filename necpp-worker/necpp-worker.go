@@ -0,0 +1,23 @@
+/*
+necpp-worker is a worker process for go-libnecpp's Worker, for programs that
+would rather not have themselves started again to run their models. Set
+Worker.Path to wherever it's installed.
+
+It does nothing useful when run by hand: the work is all done by
+necpp.ServeWorkerIfRequested(), which only does anything when the program has
+been started as a worker.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ctdk/go-libnecpp"
+)
+
+func main() {
+	necpp.ServeWorkerIfRequested()
+	fmt.Fprintln(os.Stderr, "necpp-worker runs models for go-libnecpp's Worker, and isn't meant to be run by hand.")
+	os.Exit(2)
+}
@@ -0,0 +1,92 @@
+package necpp
+
+// patternGrid is the set of field points a radiation pattern was requested
+// over, as given to RpCard().
+type patternGrid struct {
+	nTheta  int
+	nPhi    int
+	theta0  float64
+	phi0    float64
+	dTheta  float64
+	dPhi    float64
+	freqMHz float64
+}
+
+// PatternPoint is the gain of a radiation pattern in one direction. All of the
+// gains are in dBi; NEC uses -999.99 for no gain at all.
+type PatternPoint struct {
+	Theta float64 // degrees
+	Phi   float64 // degrees
+	Total float64
+
+	// The gain of each polarization. These are only filled in if the
+	// pattern they belong to is Polarized.
+	Vertical   float64
+	Horizontal float64
+	RHCP       float64
+	LHCP       float64
+}
+
+// RadiationPattern holds the gain of an antenna over a grid of directions, as
+// requested by an RP card. As with RpCard(), theta is stepped faster than phi,
+// so the point at theta index t and phi index p is Points[p*NTheta+t].
+type RadiationPattern struct {
+	FreqMHz   float64
+	NTheta    int
+	NPhi      int
+	Polarized bool // whether the polarization gains in Points are valid
+	Points    []PatternPoint
+}
+
+func newRadiationPattern(g patternGrid) *RadiationPattern {
+	nTheta, nPhi := g.nTheta, g.nPhi
+	if nTheta < 1 {
+		nTheta = 1
+	}
+	if nPhi < 1 {
+		nPhi = 1
+	}
+	p := &RadiationPattern{
+		FreqMHz: g.freqMHz,
+		NTheta:  nTheta,
+		NPhi:    nPhi,
+		Points:  make([]PatternPoint, nTheta*nPhi),
+	}
+	for i := range p.Points {
+		p.Points[i].Theta = g.theta0 + float64(i%nTheta)*g.dTheta
+		p.Points[i].Phi = g.phi0 + float64(i/nTheta)*g.dPhi
+	}
+	return p
+}
+
+// Point returns the point of the pattern at theta index t and phi index p.
+func (r *RadiationPattern) Point(t int, p int) PatternPoint {
+	return r.Points[p*r.NTheta+t]
+}
+
+// MaxGain returns the point with the highest total gain in the pattern.
+func (r *RadiationPattern) MaxGain() PatternPoint {
+	var best PatternPoint
+	for i, pt := range r.Points {
+		if i == 0 || pt.Total > best.Total {
+			best = pt
+		}
+	}
+	return best
+}
+
+// SegmentCurrent is the current on a single segment of the antenna, as
+// printed in the "currents and location" part of NEC's output. The position
+// and length of the segment are in meters.
+//
+// libnecpp's C interface has no way of getting at the segment currents, so
+// these have to come from NEC's printed output.
+type SegmentCurrent struct {
+	Segment int // absolute segment number
+	Tag     int
+	X       float64
+	Y       float64
+	Z       float64
+	Length  float64
+	Current complex128 // amps
+}
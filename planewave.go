@@ -0,0 +1,268 @@
+package necpp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// Directions is a grid of directions, in degrees, stepped the same way as the
+// field points of RpCard(): NTheta values of theta from Theta0 in steps of
+// DTheta, for each of NPhi values of phi from Phi0 in steps of DPhi, with theta
+// changing fastest. Counts below 1 are taken as 1.
+type Directions struct {
+	NTheta int
+	NPhi   int
+	Theta0 float64
+	Phi0   float64
+	DTheta float64
+	DPhi   float64
+}
+
+func (d Directions) counts() (int, int) {
+	nTheta, nPhi := d.NTheta, d.NPhi
+	if nTheta < 1 {
+		nTheta = 1
+	}
+	if nPhi < 1 {
+		nPhi = 1
+	}
+	return nTheta, nPhi
+}
+
+// at returns the theta and phi of the i'th direction.
+func (d Directions) at(i int) (float64, float64) {
+	nTheta, _ := d.counts()
+	return d.Theta0 + float64(i%nTheta)*d.DTheta, d.Phi0 + float64(i/nTheta)*d.DPhi
+}
+
+// PlaneWave is a plane wave arriving from each of a grid of directions in turn:
+// theta and phi give the direction it comes from, so it travels towards the
+// origin. Polarization is one of IncidentLinear, IncidentRightHand or
+// IncidentLeftHand. Eta is the angle, in degrees, between the theta unit vector
+// and the electric field (or the major axis of the ellipse, for elliptic
+// polarization), and AxialRatio is the ratio of the ellipse's minor axis to its
+// major axis. The field strength is 1 V/m along the major axis.
+type PlaneWave struct {
+	Directions
+	Polarization Excitation
+	Eta          float64
+	AxialRatio   float64
+}
+
+// excite puts the plane wave on with an EX card.
+func (w PlaneWave) excite(n Engine) error {
+	switch w.Polarization {
+	case IncidentLinear, IncidentRightHand, IncidentLeftHand:
+	default:
+		return fmt.Errorf("%d isn't a plane wave excitation", int(w.Polarization))
+	}
+	nTheta, nPhi := w.counts()
+	return n.ExCard(w.Polarization, nTheta, nPhi, 0, w.Theta0, w.Phi0, w.Eta, w.DTheta, w.DPhi, w.AxialRatio)
+}
+
+// ReceivePoint is the current induced on the receiving segment by a plane wave
+// arriving from one direction.
+type ReceivePoint struct {
+	Theta   float64 // degrees
+	Phi     float64 // degrees
+	Current complex128
+}
+
+// ReceivePattern is an antenna's receiving pattern at one frequency: the
+// current induced at its load by a plane wave from each direction of the
+// PlaneWave's grid, in the same order.
+type ReceivePattern struct {
+	FreqMHz float64
+	NTheta  int
+	NPhi    int
+	Points  []ReceivePoint
+}
+
+// Point returns the point of the pattern at theta index t and phi index p.
+func (r *ReceivePattern) Point(t int, p int) ReceivePoint {
+	return r.Points[p*r.NTheta+t]
+}
+
+// MaxCurrent returns the direction inducing the largest current.
+func (r *ReceivePattern) MaxCurrent() ReceivePoint {
+	var best ReceivePoint
+	for i, pt := range r.Points {
+		if i == 0 || cmplx.Abs(pt.Current) > cmplx.Abs(best.Current) {
+			best = pt
+		}
+	}
+	return best
+}
+
+// Receive runs the model as a receiving antenna, giving its receiving pattern
+// at each frequency set up by build: the current the plane wave induces on the
+// load segment from each direction. build should put the receiver's load on
+// that segment (with LdCard()) if it's to be anything but a short circuit.
+//
+// NEC solves for every direction in the one run, but only prints the currents,
+// so they're taken from libnecpp's output, which is caught and then passed on to
+// standard output. The printing of currents must not be turned off with a PT
+// card. The model is run on a new engine from newEngine,
+// which can be nil for libnecpp.
+func Receive(newEngine EngineFactory, build BuildFunc, load Port, wave PlaneWave) ([]*ReceivePattern, error) {
+	n, err := newEngine.engine()
+	if err != nil {
+		return nil, err
+	}
+	defer n.Delete()
+
+	if err = build(n); err != nil {
+		return nil, err
+	}
+	if err = wave.excite(n); err != nil {
+		return nil, err
+	}
+	// the currents are read back out of all of the report
+	n.SetOutput(nil)
+	n.SetOutputLimit(-1)
+	if err = n.XqCard(NoPattern); err != nil {
+		return nil, err
+	}
+	tables, err := parseCurrents(n.Output())
+	if err != nil {
+		return nil, err
+	}
+	return receivePatterns(tables, load, wave.Directions)
+}
+
+// receivePatterns sorts the current tables NEC printed, one per direction
+// within each frequency, into receiving patterns.
+func receivePatterns(tables []currentTable, load Port, dirs Directions) ([]*ReceivePattern, error) {
+	nTheta, nPhi := dirs.counts()
+	per := nTheta * nPhi
+	if len(tables) == 0 {
+		return nil, ErrNoCurrents
+	}
+	if len(tables)%per != 0 {
+		return nil, fmt.Errorf("found %d current tables, which isn't a whole number of %d direction grids", len(tables), per)
+	}
+	var out []*ReceivePattern
+	for start := 0; start < len(tables); start += per {
+		r := &ReceivePattern{FreqMHz: tables[start].freqMHz, NTheta: nTheta, NPhi: nPhi, Points: make([]ReceivePoint, per)}
+		for i := range r.Points {
+			c, ok := portCurrent(tables[start+i].currents, load)
+			if !ok {
+				return nil, fmt.Errorf("no current found for tag %d segment %d", load.Tag, load.Segment)
+			}
+			th, ph := dirs.at(i)
+			r.Points[i] = ReceivePoint{Theta: th, Phi: ph, Current: c}
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// RCSPoint is the radar cross section in one direction. Sigma is in square
+// meters, and DBsm is the same in dB relative to a square meter.
+type RCSPoint struct {
+	Theta float64 // degrees
+	Phi   float64 // degrees
+	Sigma float64
+	DBsm  float64
+}
+
+// RCS is a set of radar cross sections at one frequency. For a bistatic RCS
+// the points are the directions the scattered field was observed in; for a
+// monostatic RCS they're the directions the wave came from, which are also the
+// directions it's observed in.
+type RCS struct {
+	FreqMHz float64
+	NTheta  int
+	NPhi    int
+	Points  []RCSPoint
+}
+
+// Point returns the point at theta index t and phi index p.
+func (r *RCS) Point(t int, p int) RCSPoint {
+	return r.Points[p*r.NTheta+t]
+}
+
+// rcsPoint turns the figure NEC gives in place of gain for a plane wave
+// excitation, sigma/lambda^2 in dB, into square meters.
+func rcsPoint(theta float64, phi float64, db float64, freqMHz float64) RCSPoint {
+	p := RCSPoint{Theta: theta, Phi: phi, DBsm: math.Inf(-1)}
+	if db <= -999 || freqMHz <= 0 {
+		return p
+	}
+	lambda := SpeedOfLight / freqMHz
+	p.Sigma = lambda * lambda * math.Pow(10, db/10)
+	p.DBsm = 10 * math.Log10(p.Sigma)
+	return p
+}
+
+// BistaticRCS works out the radar cross section of the model, lit by a plane
+// wave from the first direction of wave, and observed from each of the
+// directions in observe, at each of the frequencies set up by build. With a
+// plane wave excitation NEC gives the scattering cross section in place of
+// gain in its radiation patterns, which is where these come from. The model is
+// run on a new engine from newEngine, which can be nil for libnecpp.
+func BistaticRCS(newEngine EngineFactory, build BuildFunc, wave PlaneWave, observe Directions) ([]*RCS, error) {
+	wave.NTheta, wave.NPhi = 1, 1
+	n, err := newEngine.engine()
+	if err != nil {
+		return nil, err
+	}
+	defer n.Delete()
+
+	if err = build(n); err != nil {
+		return nil, err
+	}
+	if err = wave.excite(n); err != nil {
+		return nil, err
+	}
+	nTheta, nPhi := observe.counts()
+	if err = n.RpCard(Normal, nTheta, nPhi, MajorMinor, NoNormalization, PowerGain, NoAvg, observe.Theta0, observe.Phi0, observe.DTheta, observe.DPhi, 0, 0); err != nil {
+		return nil, err
+	}
+	patterns, err := n.RadiationPatterns()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*RCS, len(patterns))
+	for i, p := range patterns {
+		r := &RCS{FreqMHz: p.FreqMHz, NTheta: p.NTheta, NPhi: p.NPhi, Points: make([]RCSPoint, len(p.Points))}
+		for j, pt := range p.Points {
+			r.Points[j] = rcsPoint(pt.Theta, pt.Phi, pt.Total, p.FreqMHz)
+		}
+		out[i] = r
+	}
+	return out, nil
+}
+
+// MonostaticRCS works out the radar cross section of the model back towards
+// each of the directions the plane wave comes from, as a radar would see it,
+// at each of the frequencies set up by build. Every direction needs its own run
+// of the model, each on a new engine from newEngine.
+func MonostaticRCS(newEngine EngineFactory, build BuildFunc, wave PlaneWave) ([]*RCS, error) {
+	nTheta, nPhi := wave.counts()
+	var out []*RCS
+	for i := 0; i < nTheta*nPhi; i++ {
+		th, ph := wave.at(i)
+		single := wave
+		single.Directions = Directions{NTheta: 1, NPhi: 1, Theta0: th, Phi0: ph}
+		back, err := BistaticRCS(newEngine, build, single, single.Directions)
+		if err != nil {
+			return nil, err
+		}
+		if out == nil {
+			out = make([]*RCS, len(back))
+			for f, b := range back {
+				out[f] = &RCS{FreqMHz: b.FreqMHz, NTheta: nTheta, NPhi: nPhi, Points: make([]RCSPoint, nTheta*nPhi)}
+			}
+		}
+		if len(back) != len(out) {
+			return nil, errors.New("the model gave a different number of frequencies from one run to the next")
+		}
+		for f, b := range back {
+			out[f].Points[i] = b.Points[0]
+		}
+	}
+	return out, nil
+}
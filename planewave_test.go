@@ -0,0 +1,42 @@
+package necpp
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestReceivePatterns(t *testing.T) {
+	tables, err := parseCurrents(currentsOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs := Directions{NTheta: 2, NPhi: 1, Theta0: 90, DTheta: 10}
+	rs, err := receivePatterns(tables, Port{Tag: 1, Segment: 2}, dirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 1 || len(rs[0].Points) != 2 {
+		t.Fatalf("should have been one pattern of two points, got %d", len(rs))
+	}
+	if p := rs[0].Point(1, 0); p.Theta != 100 || cmplx.Abs(p.Current-5e-3) > 1e-12 {
+		t.Errorf("second point was %+v, should have been theta 100 with 5 mA", p)
+	}
+	if m := rs[0].MaxCurrent(); m.Theta != 100 {
+		t.Errorf("strongest direction was theta %g, should have been 100", m.Theta)
+	}
+	if _, err := receivePatterns(tables, Port{Tag: 1, Segment: 2}, Directions{NTheta: 3}); err == nil {
+		t.Errorf("two tables for a three direction grid should have been an error")
+	}
+}
+
+func TestRCSPoint(t *testing.T) {
+	// at 299.79 MHz a wavelength is a meter, so sigma/lambda^2 is square meters
+	p := rcsPoint(0, 0, 10, SpeedOfLight)
+	if math.Abs(p.Sigma-10) > 1e-9 || math.Abs(p.DBsm-10) > 1e-9 {
+		t.Errorf("10 dB should have been 10 square meters, got %g (%g dBsm)", p.Sigma, p.DBsm)
+	}
+	if p := rcsPoint(0, 0, -999.99, 100); p.Sigma != 0 {
+		t.Errorf("NEC's -999.99 should have been no cross section at all, got %g", p.Sigma)
+	}
+}
@@ -0,0 +1,353 @@
+package plot
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/cmplx"
+
+	"github.com/ctdk/go-libnecpp"
+)
+
+// View is the direction an antenna's geometry is looked at from in an
+// orthographic projection.
+//
+// • TopView - looking down the Z axis, onto the XY plane.
+//
+// • FrontView - looking along the Y axis, onto the XZ plane.
+//
+// • SideView - looking along the X axis, onto the YZ plane.
+type View int
+
+const (
+	TopView View = iota
+	FrontView
+	SideView
+)
+
+func (v View) String() string {
+	switch v {
+	case TopView:
+		return "XY (top)"
+	case FrontView:
+		return "XZ (front)"
+	case SideView:
+		return "YZ (side)"
+	}
+	return fmt.Sprintf("View(%d)", int(v))
+}
+
+// axes returns the indices of the coordinates across and up the projection.
+func (v View) axes() (int, int) {
+	switch v {
+	case FrontView:
+		return 0, 2
+	case SideView:
+		return 1, 2
+	}
+	return 0, 1
+}
+
+// GeometryOptions controls the appearance of a geometry drawing.
+type GeometryOptions struct {
+	Title string
+
+	// Views are the projections to draw, side by side. Defaults to all
+	// three.
+	Views []View
+
+	// Size is the width and height of each projection in pixels. Defaults
+	// to 300.
+	Size int
+
+	// Currents, if given, colors each segment by the magnitude of its
+	// current, from blue for no current to red for the largest.
+	Currents []necpp.SegmentCurrent
+}
+
+// Features of a segment, for marking feeds and loads.
+const (
+	plainSegment = iota
+	fedSegment
+	loadedSegment
+)
+
+// GeometrySVG draws orthographic projections of an antenna's geometry to w.
+// Wires are drawn at their radius (or a hairline, if that's too thin to see),
+// patches as outlines, feeds as red dots, and loads as green squares. All of
+// the projections are drawn to the same scale, which is given on the drawing.
+func GeometrySVG(w io.Writer, g *necpp.Geometry, opts GeometryOptions) error {
+	segs := g.Segments()
+	patches := patchOutlines(g)
+	if len(segs) == 0 && len(patches) == 0 {
+		return errors.New("the geometry is empty")
+	}
+	if len(opts.Views) == 0 {
+		opts.Views = []View{TopView, FrontView, SideView}
+	}
+	if opts.Size <= 0 {
+		opts.Size = 300
+	}
+
+	lo := [3]float64{math.Inf(1), math.Inf(1), math.Inf(1)}
+	hi := [3]float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	grow := func(p [3]float64) {
+		for i := range p {
+			lo[i], hi[i] = math.Min(lo[i], p[i]), math.Max(hi[i], p[i])
+		}
+	}
+	for _, s := range segs {
+		grow(s.Start)
+		grow(s.End)
+	}
+	for _, p := range patches {
+		for _, c := range p {
+			grow(c)
+		}
+	}
+	extent := 0.0
+	for i := range lo {
+		extent = math.Max(extent, hi[i]-lo[i])
+	}
+	if extent == 0 {
+		extent = 1
+	}
+
+	size := float64(opts.Size)
+	margin := 20.0
+	top := 30.0
+	scale := (size - 2*margin) / extent
+	features := segmentFeatures(g, segs)
+	mags := currentMagnitudes(segs, opts.Currents)
+
+	s := newSVGWriter(w, opts.Size*len(opts.Views), opts.Size+int(top)+20)
+	if opts.Title != "" {
+		s.text(size*float64(len(opts.Views))/2, 18, "middle", opts.Title)
+	}
+	for vi, v := range opts.Views {
+		a, b := v.axes()
+		ox := float64(vi) * size
+		// the center of the bounding box goes in the middle of the panel
+		cx := ox + size/2
+		cy := top + size/2
+		ca, cb := (lo[a]+hi[a])/2, (lo[b]+hi[b])/2
+		px := func(p [3]float64) (float64, float64) {
+			return cx + (p[a]-ca)*scale, cy - (p[b]-cb)*scale
+		}
+		s.printf(`<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="none" stroke="#bbb"/>`+"\n", ox+2, top, size-4, size-4)
+		s.text(ox+8, top+14, "start", v.String())
+
+		for _, p := range patches {
+			s.printf(`<polygon fill="#eee" stroke="#888" points="`)
+			for i, c := range p {
+				x, y := px(c)
+				if i > 0 {
+					s.printf(" ")
+				}
+				s.printf("%.2f,%.2f", x, y)
+			}
+			s.printf(`"/>` + "\n")
+		}
+		for i, seg := range segs {
+			x1, y1 := px(seg.Start)
+			x2, y2 := px(seg.End)
+			stroke := "black"
+			if mags != nil {
+				stroke = heatColor(mags[i])
+			}
+			s.line(x1, y1, x2, y2, stroke, math.Max(1, 2*seg.Radius*scale), false)
+		}
+		for i, seg := range segs {
+			x, y := px(seg.Center())
+			switch features[i] {
+			case fedSegment:
+				s.printf(`<circle cx="%.2f" cy="%.2f" r="4" fill="red"/>`+"\n", x, y)
+			case loadedSegment:
+				s.printf(`<rect x="%.2f" y="%.2f" width="7" height="7" fill="green"/>`+"\n", x-3.5, y-3.5)
+			}
+		}
+	}
+	s.text(margin, size+top+12, "start", fmt.Sprintf("%d pixels = 1 m", int(math.Round(scale))))
+	return s.close()
+}
+
+// GeometryVTK writes an antenna's geometry to w as a legacy format VTK
+// polydata file, with each segment as a line and each patch as a polygon. The
+// cells have a radius, the tag, and a feature (0 for nothing, 1 for a feed and
+// 2 for a load) as cell data, plus current_mag_a (in amps) if currents are
+// given.
+func GeometryVTK(w io.Writer, g *necpp.Geometry, currents []necpp.SegmentCurrent) error {
+	segs := g.Segments()
+	patches := patchOutlines(g)
+	if len(segs) == 0 && len(patches) == 0 {
+		return errors.New("the geometry is empty")
+	}
+	bw := bufio.NewWriter(w)
+	nPoints := 2 * len(segs)
+	polySize := 0
+	for _, p := range patches {
+		nPoints += len(p)
+		polySize += len(p) + 1
+	}
+	fmt.Fprintf(bw, "# vtk DataFile Version 3.0\nantenna geometry\nASCII\nDATASET POLYDATA\n")
+	fmt.Fprintf(bw, "POINTS %d double\n", nPoints)
+	for _, s := range segs {
+		fmt.Fprintf(bw, "%g %g %g\n%g %g %g\n", s.Start[0], s.Start[1], s.Start[2], s.End[0], s.End[1], s.End[2])
+	}
+	for _, p := range patches {
+		for _, c := range p {
+			fmt.Fprintf(bw, "%g %g %g\n", c[0], c[1], c[2])
+		}
+	}
+	if len(segs) > 0 {
+		fmt.Fprintf(bw, "LINES %d %d\n", len(segs), 3*len(segs))
+		for i := range segs {
+			fmt.Fprintf(bw, "2 %d %d\n", 2*i, 2*i+1)
+		}
+	}
+	if len(patches) > 0 {
+		fmt.Fprintf(bw, "POLYGONS %d %d\n", len(patches), polySize)
+		next := 2 * len(segs)
+		for _, p := range patches {
+			fmt.Fprintf(bw, "%d", len(p))
+			for range p {
+				fmt.Fprintf(bw, " %d", next)
+				next++
+			}
+			fmt.Fprintf(bw, "\n")
+		}
+	}
+
+	// VTK puts the cell data for lines before that for polygons
+	nCells := len(segs) + len(patches)
+	radius := make([]float64, nCells)
+	tags := make([]float64, nCells)
+	features := make([]float64, nCells)
+	for i, s := range segs {
+		radius[i] = s.Radius
+		tags[i] = float64(s.Tag)
+	}
+	for i, f := range segmentFeatures(g, segs) {
+		features[i] = float64(f)
+	}
+	fmt.Fprintf(bw, "CELL_DATA %d\n", nCells)
+	writeVTKCellScalars(bw, "radius_m", radius)
+	writeVTKCellScalars(bw, "tag", tags)
+	writeVTKCellScalars(bw, "feature", features)
+	if len(currents) > 0 {
+		mags := make([]float64, nCells)
+		byNumber := make(map[int]float64)
+		for _, c := range currents {
+			byNumber[c.Segment] = cmplx.Abs(c.Current)
+		}
+		for i, s := range segs {
+			mags[i] = byNumber[s.Number]
+		}
+		writeVTKCellScalars(bw, "current_mag_a", mags)
+	}
+	return bw.Flush()
+}
+
+func writeVTKCellScalars(w io.Writer, name string, scalars []float64) {
+	fmt.Fprintf(w, "SCALARS %s double 1\nLOOKUP_TABLE default\n", name)
+	for _, s := range scalars {
+		fmt.Fprintf(w, "%g\n", s)
+	}
+}
+
+// segmentFeatures works out which of the segments are fed or loaded. A feed
+// wins over a load on the same segment.
+func segmentFeatures(g *necpp.Geometry, segs []necpp.Segment) []int {
+	features := make([]int, len(segs))
+	for _, l := range g.Loads {
+		if l.Type < 0 {
+			// an LD card of type -1 takes away all of the loads
+			for i := range features {
+				features[i] = plainSegment
+			}
+			continue
+		}
+		for i, s := range segs {
+			if l.Tag != 0 && s.Tag != l.Tag {
+				continue
+			}
+			n := s.Number
+			if l.Tag != 0 {
+				n = s.TagIndex
+			}
+			// with no segments given, the whole wire (or structure) is loaded
+			if (l.From == 0 && l.To == 0) || (n >= l.From && n <= l.To) {
+				features[i] = loadedSegment
+			}
+		}
+	}
+	for _, f := range g.Feeds {
+		for i, s := range segs {
+			if (f.Tag == 0 && s.Number == f.Segment) || (f.Tag != 0 && s.Tag == f.Tag && s.TagIndex == f.Segment) {
+				features[i] = fedSegment
+			}
+		}
+	}
+	return features
+}
+
+// currentMagnitudes returns the current magnitude on each segment as a fraction
+// of the largest, or nil if there are no currents.
+func currentMagnitudes(segs []necpp.Segment, currents []necpp.SegmentCurrent) []float64 {
+	if len(currents) == 0 {
+		return nil
+	}
+	byNumber := make(map[int]float64)
+	maxMag := 0.0
+	for _, c := range currents {
+		m := cmplx.Abs(c.Current)
+		byNumber[c.Segment] = m
+		maxMag = math.Max(maxMag, m)
+	}
+	mags := make([]float64, len(segs))
+	if maxMag == 0 {
+		return mags
+	}
+	for i, s := range segs {
+		mags[i] = byNumber[s.Number] / maxMag
+	}
+	return mags
+}
+
+// heatColor returns a color from blue for 0 to red for 1.
+func heatColor(f float64) string {
+	f = math.Max(0, math.Min(1, f))
+	return fmt.Sprintf("rgb(%d,0,%d)", int(math.Round(255*f)), int(math.Round(255*(1-f))))
+}
+
+// patchOutlines returns the corners of each patch. Arbitrary patches, which
+// are only given by their center, normal and area, are drawn as squares of
+// that area.
+func patchOutlines(g *necpp.Geometry) [][][3]float64 {
+	var out [][][3]float64
+	for _, p := range g.Patches {
+		if p.Shape != necpp.Arbitrary {
+			out = append(out, p.Corners)
+			continue
+		}
+		c := p.Corners[0]
+		el := p.Elevation * math.Pi / 180
+		az := p.Azimuth * math.Pi / 180
+		n := [3]float64{math.Cos(el) * math.Cos(az), math.Cos(el) * math.Sin(az), math.Sin(el)}
+		t1 := [3]float64{-math.Sin(az), math.Cos(az), 0}
+		t2 := [3]float64{n[1]*t1[2] - n[2]*t1[1], n[2]*t1[0] - n[0]*t1[2], n[0]*t1[1] - n[1]*t1[0]}
+		h := math.Sqrt(p.Area) / 2
+		var sq [][3]float64
+		for _, k := range [][2]float64{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+			sq = append(sq, [3]float64{
+				c[0] + h*(k[0]*t1[0]+k[1]*t2[0]),
+				c[1] + h*(k[0]*t1[1]+k[1]*t2[1]),
+				c[2] + h*(k[0]*t1[2]+k[1]*t2[2]),
+			})
+		}
+		out = append(out, sq)
+	}
+	return out
+}
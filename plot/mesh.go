@@ -0,0 +1,182 @@
+package plot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/ctdk/go-libnecpp"
+)
+
+// Mesh is a triangulated surface, with a scalar value at each vertex. For a
+// radiation pattern mesh the scalar is the gain in dBi.
+type Mesh struct {
+	Name      string
+	Vertices  [][3]float64
+	Scalars   []float64
+	Triangles [][3]int
+}
+
+// MeshOptions controls how a radiation pattern is turned into a mesh.
+type MeshOptions struct {
+	// Scale is how gain is turned into distance from the origin, the same
+	// as the radial scale of a polar plot.
+	Scale Scale
+
+	// RangeDB is how far below the maximum gain the origin is for a
+	// LinearDB mesh. Gains lower than this are clamped to it, in the
+	// scalar values as well as the shape. Defaults to 40.
+	RangeDB float64
+
+	// Radius is the distance from the origin of the point of maximum gain,
+	// in whatever units the viewer is using. Defaults to 1.
+	Radius float64
+}
+
+// PatternMesh makes a gain scaled surface out of a radiation pattern: each
+// point of the pattern is placed in its direction from the origin, at a
+// distance depending on its gain. The pattern must cover a grid of at least two
+// theta and two phi angles. If the phi angles go all the way around, the
+// surface is closed up.
+func PatternMesh(p *necpp.RadiationPattern, opts MeshOptions) (*Mesh, error) {
+	if p.NTheta < 2 || p.NPhi < 2 {
+		return nil, errors.New("a mesh needs a pattern with at least two theta and two phi angles")
+	}
+	if opts.RangeDB <= 0 {
+		opts.RangeDB = 40
+	}
+	if opts.Radius <= 0 {
+		opts.Radius = 1
+	}
+	po := PolarOptions{Scale: opts.Scale, RangeDB: opts.RangeDB}
+	maxGain := p.MaxGain().Total
+	floor := maxGain - opts.RangeDB
+
+	m := &Mesh{Name: fmt.Sprintf("radiation pattern at %g MHz", p.FreqMHz)}
+	for _, pt := range p.Points {
+		g := math.Max(pt.Total, floor)
+		r := opts.Radius * po.radius(g-maxGain)
+		th := pt.Theta * math.Pi / 180
+		ph := pt.Phi * math.Pi / 180
+		m.Vertices = append(m.Vertices, [3]float64{
+			r * math.Sin(th) * math.Cos(ph),
+			r * math.Sin(th) * math.Sin(ph),
+			r * math.Cos(th),
+		})
+		m.Scalars = append(m.Scalars, g)
+	}
+
+	dPhi := p.Point(0, 1).Phi - p.Point(0, 0).Phi
+	phiSpan := p.Point(0, p.NPhi-1).Phi - p.Point(0, 0).Phi + dPhi
+	wrap := math.Abs(math.Abs(phiSpan)-360) < 1e-6
+	nPhi := p.NPhi - 1
+	if wrap {
+		nPhi = p.NPhi
+	}
+	idx := func(t int, ph int) int { return (ph%p.NPhi)*p.NTheta + t }
+	for ph := 0; ph < nPhi; ph++ {
+		for t := 0; t < p.NTheta-1; t++ {
+			a, b, c, d := idx(t, ph), idx(t+1, ph), idx(t+1, ph+1), idx(t, ph+1)
+			// Wound so the normals point outwards, which is the
+			// direction of theta cross phi.
+			m.addTriangle(a, b, c)
+			m.addTriangle(a, c, d)
+		}
+	}
+	return m, nil
+}
+
+// addTriangle adds a triangle to the mesh, unless it has (next to) no area,
+// which happens where the rows of a pattern meet at a pole.
+func (m *Mesh) addTriangle(a int, b int, c int) {
+	va, vb, vc := m.Vertices[a], m.Vertices[b], m.Vertices[c]
+	u := [3]float64{vb[0] - va[0], vb[1] - va[1], vb[2] - va[2]}
+	v := [3]float64{vc[0] - va[0], vc[1] - va[1], vc[2] - va[2]}
+	size := norm(u)*norm(u) + norm(v)*norm(v)
+	if size == 0 || norm(m.normal([3]int{a, b, c})) <= 1e-9*size {
+		return
+	}
+	m.Triangles = append(m.Triangles, [3]int{a, b, c})
+}
+
+// normal returns the (unnormalized) normal of a triangle of the mesh.
+func (m *Mesh) normal(tri [3]int) [3]float64 {
+	a, b, c := m.Vertices[tri[0]], m.Vertices[tri[1]], m.Vertices[tri[2]]
+	u := [3]float64{b[0] - a[0], b[1] - a[1], b[2] - a[2]}
+	v := [3]float64{c[0] - a[0], c[1] - a[1], c[2] - a[2]}
+	return [3]float64{u[1]*v[2] - u[2]*v[1], u[2]*v[0] - u[0]*v[2], u[0]*v[1] - u[1]*v[0]}
+}
+
+func norm(v [3]float64) float64 {
+	return math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+}
+
+// WriteOBJ writes the mesh to w as a Wavefront OBJ file. OBJ has no place for
+// the scalar values, so they're left out.
+func (m *Mesh) WriteOBJ(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %s\n", m.Name)
+	fmt.Fprintf(bw, "o pattern\n")
+	for _, v := range m.Vertices {
+		fmt.Fprintf(bw, "v %g %g %g\n", v[0], v[1], v[2])
+	}
+	for _, t := range m.Triangles {
+		fmt.Fprintf(bw, "f %d %d %d\n", t[0]+1, t[1]+1, t[2]+1)
+	}
+	return bw.Flush()
+}
+
+// WriteSTL writes the mesh to w as a binary STL file.
+func (m *Mesh) WriteSTL(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	header := make([]byte, 80)
+	copy(header, m.Name)
+	bw.Write(header)
+	binary.Write(bw, binary.LittleEndian, uint32(len(m.Triangles)))
+	for _, t := range m.Triangles {
+		n := m.normal(t)
+		l := norm(n)
+		rec := make([]float32, 0, 12)
+		rec = append(rec, float32(n[0]/l), float32(n[1]/l), float32(n[2]/l))
+		for _, i := range t {
+			v := m.Vertices[i]
+			rec = append(rec, float32(v[0]), float32(v[1]), float32(v[2]))
+		}
+		if err := binary.Write(bw, binary.LittleEndian, rec); err != nil {
+			return err
+		}
+		// the attribute byte count, which nothing uses
+		bw.Write([]byte{0, 0})
+	}
+	return bw.Flush()
+}
+
+// WriteVTK writes the mesh to w as a legacy format VTK polydata file, with the
+// scalars as point data called scalarName (such as "gain_dbi").
+func (m *Mesh) WriteVTK(w io.Writer, scalarName string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# vtk DataFile Version 3.0\n%s\nASCII\nDATASET POLYDATA\n", m.Name)
+	fmt.Fprintf(bw, "POINTS %d double\n", len(m.Vertices))
+	for _, v := range m.Vertices {
+		fmt.Fprintf(bw, "%g %g %g\n", v[0], v[1], v[2])
+	}
+	fmt.Fprintf(bw, "POLYGONS %d %d\n", len(m.Triangles), 4*len(m.Triangles))
+	for _, t := range m.Triangles {
+		fmt.Fprintf(bw, "3 %d %d %d\n", t[0], t[1], t[2])
+	}
+	writeVTKScalars(bw, scalarName, m.Scalars)
+	return bw.Flush()
+}
+
+func writeVTKScalars(w io.Writer, name string, scalars []float64) {
+	if len(scalars) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "POINT_DATA %d\nSCALARS %s double 1\nLOOKUP_TABLE default\n", len(scalars), name)
+	for _, s := range scalars {
+		fmt.Fprintf(w, "%g\n", s)
+	}
+}
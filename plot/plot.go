@@ -0,0 +1,128 @@
+/*
+Package plot renders results from go-libnecpp as SVG images: polar plots of
+radiation pattern cuts, in either the ARRL style log scale or linear dB, and
+rectangular charts of VSWR and impedance against frequency.
+
+Several series can be overlaid on the same plot, such as the same pattern cut
+at each frequency of a sweep. Each series gets its own color and an entry in
+the legend.
+
+Full radiation patterns can also be turned into 3D surface meshes, which can
+be written out as Wavefront OBJ, binary STL, or legacy VTK files for viewing in
+something like ParaView or Blender.
+
+An antenna's geometry can be drawn as orthographic projections in SVG, or
+written as VTK polydata, to check a model over before running it. The segments
+can be colored by the magnitude of their currents.
+*/
+package plot
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+)
+
+// palette is the set of colors series are drawn in, in order.
+var palette = []string{"#1f77b4", "#d62728", "#2ca02c", "#ff7f0e", "#9467bd", "#8c564b", "#e377c2", "#17becf"}
+
+func color(i int) string {
+	return palette[i%len(palette)]
+}
+
+// svgWriter is a thin wrapper around a buffered writer for putting together
+// SVG documents, which keeps hold of the first error so it only needs checking
+// at the end.
+type svgWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func newSVGWriter(w io.Writer, width int, height int) *svgWriter {
+	s := &svgWriter{w: bufio.NewWriter(w)}
+	s.printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height)
+	s.printf(`<rect width="%d" height="%d" fill="white"/>`+"\n", width, height)
+	return s
+}
+
+func (s *svgWriter) printf(format string, a ...interface{}) {
+	if s.err != nil {
+		return
+	}
+	_, s.err = fmt.Fprintf(s.w, format, a...)
+}
+
+func (s *svgWriter) line(x1 float64, y1 float64, x2 float64, y2 float64, stroke string, width float64, dash bool) {
+	d := ""
+	if dash {
+		d = ` stroke-dasharray="4,3"`
+	}
+	s.printf(`<line x1="%.2f" y1="%.2f" x2="%.2f" y2="%.2f" stroke="%s" stroke-width="%g"%s/>`+"\n", x1, y1, x2, y2, stroke, width, d)
+}
+
+func (s *svgWriter) circle(cx float64, cy float64, r float64, stroke string, dash bool) {
+	d := ""
+	if dash {
+		d = ` stroke-dasharray="4,3"`
+	}
+	s.printf(`<circle cx="%.2f" cy="%.2f" r="%.2f" fill="none" stroke="%s"%s/>`+"\n", cx, cy, r, stroke, d)
+}
+
+// text writes a label at (x, y). anchor is the SVG text-anchor: "start",
+// "middle" or "end".
+func (s *svgWriter) text(x float64, y float64, anchor string, str string) {
+	s.printf(`<text x="%.2f" y="%.2f" text-anchor="%s">%s</text>`+"\n", x, y, anchor, html.EscapeString(str))
+}
+
+// polyline draws a series of points, breaking the line wherever a point is
+// missing (NaN).
+func (s *svgWriter) polyline(xs []float64, ys []float64, stroke string, closed bool) {
+	var pts []string
+	flush := func() {
+		if len(pts) > 1 {
+			s.printf(`<polyline fill="none" stroke="%s" stroke-width="1.5" points="`, stroke)
+			for i, p := range pts {
+				if i > 0 {
+					s.printf(" ")
+				}
+				s.printf("%s", p)
+			}
+			s.printf(`"/>` + "\n")
+		}
+		pts = nil
+	}
+	for i := range xs {
+		if math.IsNaN(xs[i]) || math.IsNaN(ys[i]) {
+			flush()
+			continue
+		}
+		pts = append(pts, fmt.Sprintf("%.2f,%.2f", xs[i], ys[i]))
+	}
+	if closed && len(pts) == len(xs) && len(pts) > 2 {
+		pts = append(pts, pts[0])
+	}
+	flush()
+}
+
+// legend draws a legend box of labels in the series colors, starting at
+// (x, y).
+func (s *svgWriter) legend(x float64, y float64, labels []string) {
+	for i, l := range labels {
+		if l == "" {
+			continue
+		}
+		ly := y + float64(i)*16
+		s.line(x, ly-4, x+20, ly-4, color(i), 2, false)
+		s.text(x+25, ly, "start", l)
+	}
+}
+
+func (s *svgWriter) close() error {
+	s.printf("</svg>\n")
+	if s.err != nil {
+		return s.err
+	}
+	return s.w.Flush()
+}
@@ -0,0 +1,226 @@
+package plot
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/ctdk/go-libnecpp"
+)
+
+// checkSVG makes sure the output is well formed XML with an svg root element.
+func checkSVG(t *testing.T, b []byte) {
+	d := xml.NewDecoder(bytes.NewReader(b))
+	root := ""
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("bad SVG: %s", err)
+		}
+		if se, ok := tok.(xml.StartElement); ok && root == "" {
+			root = se.Name.Local
+		}
+	}
+	if root != "svg" {
+		t.Errorf("root element was %q, should have been svg", root)
+	}
+}
+
+func TestPolar(t *testing.T) {
+	c := Cut{Label: "dipole & friends"}
+	for a := 0.0; a < 360; a += 5 {
+		c.Angles = append(c.Angles, a)
+		c.Gains = append(c.Gains, 2.15+10*math.Log10(math.Max(math.Pow(math.Cos(a*math.Pi/180), 2), 1e-6)))
+	}
+	for _, scale := range []Scale{ARRLLog, LinearDB} {
+		var buf bytes.Buffer
+		if err := Polar(&buf, []Cut{c}, PolarOptions{Title: "Azimuth", Scale: scale}); err != nil {
+			t.Fatal(err)
+		}
+		checkSVG(t, buf.Bytes())
+		if !strings.Contains(buf.String(), "0 dB = 2.15 dBi") {
+			t.Errorf("the plot should say what 0 dB is")
+		}
+	}
+}
+
+func TestAzimuthCut(t *testing.T) {
+	var pts []necpp.PatternPoint
+	for phi := 0.0; phi < 360; phi += 90 {
+		for theta := 0.0; theta <= 90; theta += 45 {
+			pts = append(pts, necpp.PatternPoint{Theta: theta, Phi: phi, Total: phi / 100})
+		}
+	}
+	p := &necpp.RadiationPattern{FreqMHz: 7, NTheta: 3, NPhi: 4, Points: pts}
+	c, err := AzimuthCut(p, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Angles) != 4 || c.Angles[3] != 270 || c.Gains[3] != 2.7 {
+		t.Errorf("the cut was %v, %v", c.Angles, c.Gains)
+	}
+	for _, bad := range []int{-1, 3} {
+		if _, err := AzimuthCut(p, bad); err == nil {
+			t.Errorf("a cut at theta index %d should fail", bad)
+		}
+	}
+}
+
+func TestElevationCut(t *testing.T) {
+	// the pattern's phi angles are given as 0 and 180 degrees, with theta
+	// from 0 to 90
+	var pts []necpp.PatternPoint
+	for _, phi := range []float64{0, 180} {
+		for theta := 0.0; theta <= 90; theta += 30 {
+			pts = append(pts, necpp.PatternPoint{Theta: theta, Phi: phi, Total: theta / 10})
+		}
+	}
+	p := &necpp.RadiationPattern{FreqMHz: 7, NTheta: 4, NPhi: 2, Points: pts}
+	c, err := ElevationCut(p, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{90, 60, 30, 0, 180, 150, 120, 90}
+	if len(c.Angles) != len(want) {
+		t.Fatalf("expected %d angles, got %v", len(want), c.Angles)
+	}
+	for i := range want {
+		if math.Abs(c.Angles[i]-want[i]) > 1e-9 {
+			t.Errorf("angle %d was %g, should have been %g", i, c.Angles[i], want[i])
+		}
+	}
+	if _, err := ElevationCut(p, 45); err == nil {
+		t.Errorf("a cut at an azimuth that isn't in the pattern should fail")
+	}
+}
+
+func TestVSWRChart(t *testing.T) {
+	sweep := []necpp.ImpedancePoint{
+		{FreqMHz: 14.0, Impedance: complex(35, -30)},
+		{FreqMHz: 14.1, Impedance: complex(50, 0)},
+		{FreqMHz: 14.2, Impedance: complex(0, 30)},
+	}
+	var buf bytes.Buffer
+	if err := VSWRChart(&buf, []string{"20m"}, [][]necpp.ImpedancePoint{sweep}, 50); err != nil {
+		t.Fatal(err)
+	}
+	checkSVG(t, buf.Bytes())
+}
+
+func TestRectLabels(t *testing.T) {
+	var buf bytes.Buffer
+	series := []Series{{Label: "R & X", X: []float64{1, 2}, Y: []float64{3, 4}}}
+	if err := Rect(&buf, series, RectOptions{XLabel: "f <MHz>", YLabel: "Z <ohms> & more"}); err != nil {
+		t.Fatal(err)
+	}
+	checkSVG(t, buf.Bytes())
+	if !strings.Contains(buf.String(), "Z &lt;ohms&gt; &amp; more") {
+		t.Errorf("the y axis label wasn't escaped")
+	}
+}
+
+func TestImpedanceChart(t *testing.T) {
+	a := []necpp.ImpedancePoint{{FreqMHz: 14.0, Impedance: complex(35, -30)}, {FreqMHz: 14.2, Impedance: complex(50, 0)}}
+	b := []necpp.ImpedancePoint{{FreqMHz: 14.0, Impedance: complex(40, -10)}, {FreqMHz: 14.2, Impedance: complex(60, 20)}}
+	var buf bytes.Buffer
+	if err := ImpedanceChart(&buf, []string{"measured", "simulated"}, [][]necpp.ImpedancePoint{a, b}); err != nil {
+		t.Fatal(err)
+	}
+	checkSVG(t, buf.Bytes())
+	for _, label := range []string{"measured R", "measured X", "simulated R", "simulated X"} {
+		if !strings.Contains(buf.String(), label) {
+			t.Errorf("the legend has no %q", label)
+		}
+	}
+	if err := ImpedanceChart(&buf, []string{"one"}, [][]necpp.ImpedancePoint{a, b}); err == nil {
+		t.Errorf("a missing label should have been an error")
+	}
+}
+
+func TestNiceTicks(t *testing.T) {
+	ticks := niceTicks(13.95, 14.35)
+	want := []float64{13.9, 14.0, 14.1, 14.2, 14.3, 14.4}
+	if len(ticks) != len(want) {
+		t.Fatalf("ticks were %v, should have been %v", ticks, want)
+	}
+	for i := range want {
+		if math.Abs(ticks[i]-want[i]) > 1e-9 {
+			t.Errorf("ticks were %v, should have been %v", ticks, want)
+			break
+		}
+	}
+}
+
+func TestPatternMesh(t *testing.T) {
+	g := necpp.RadiationPattern{FreqMHz: 14, NTheta: 7, NPhi: 12}
+	for ph := 0; ph < 12; ph++ {
+		for th := 0; th < 7; th++ {
+			g.Points = append(g.Points, necpp.PatternPoint{Theta: float64(th * 30), Phi: float64(ph * 30), Total: 0})
+		}
+	}
+	m, err := PatternMesh(&g, MeshOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every quad is two triangles, less the ones squashed flat at the poles
+	if len(m.Triangles) != 120 {
+		t.Errorf("expected 120 triangles, got %d", len(m.Triangles))
+	}
+	for _, tri := range m.Triangles {
+		n := m.normal(tri)
+		c := m.Vertices[tri[0]]
+		if n[0]*c[0]+n[1]*c[1]+n[2]*c[2] <= 0 {
+			t.Errorf("triangle %v faces inwards", tri)
+			break
+		}
+	}
+	var buf bytes.Buffer
+	if err := m.WriteSTL(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 84+50*len(m.Triangles) {
+		t.Errorf("STL file was %d bytes, should have been %d", buf.Len(), 84+50*len(m.Triangles))
+	}
+	buf.Reset()
+	if err := m.WriteVTK(&buf, "gain_dbi"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "POLYGONS 120 480") {
+		t.Errorf("VTK file has the wrong polygon count")
+	}
+}
+
+func TestGeometry(t *testing.T) {
+	g := &necpp.Geometry{
+		Wires:   []necpp.WireSpec{{Tag: 1, Segments: 5, Z1: -0.5, Z2: 0.5, Radius: 0.001}},
+		Patches: []necpp.PatchSpec{{Shape: necpp.Arbitrary, Corners: [][3]float64{{0, 0, -1}}, Elevation: 90, Area: 0.04}},
+		Feeds:   []necpp.Feed{{Port: necpp.Port{Tag: 1, Segment: 3}, Voltage: 1}},
+		Loads:   []necpp.Load{{Type: 0, Tag: 1, From: 1, To: 1, R: 50}},
+	}
+	currents := []necpp.SegmentCurrent{{Segment: 3, Tag: 1, Current: 0.02}}
+	var buf bytes.Buffer
+	if err := GeometrySVG(&buf, g, GeometryOptions{Title: "Dipole", Currents: currents}); err != nil {
+		t.Fatal(err)
+	}
+	checkSVG(t, buf.Bytes())
+	if !strings.Contains(buf.String(), `fill="red"`) || !strings.Contains(buf.String(), `fill="green"`) {
+		t.Errorf("the feed and load should be marked")
+	}
+
+	buf.Reset()
+	if err := GeometryVTK(&buf, g, currents); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"POINTS 14 double", "LINES 5 15", "POLYGONS 1 5", "CELL_DATA 6", "SCALARS current_mag_a"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("VTK output is missing %q", want)
+		}
+	}
+}
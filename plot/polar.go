@@ -0,0 +1,225 @@
+package plot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/ctdk/go-libnecpp"
+)
+
+// Scale is the radial scale of a polar plot.
+//
+// • ARRLLog - the ARRL's modified log scale, where every 2 dB down from the
+// outer ring shrinks the radius by a factor of 0.89. This shows lobes well
+// while still giving some detail down to around -40 dB.
+//
+// • LinearDB - the radius is linear in dB, from 0 dB at the outer ring down
+// to -RangeDB at the center.
+type Scale int
+
+const (
+	ARRLLog Scale = iota
+	LinearDB
+)
+
+// PolarOptions controls the appearance of a polar plot.
+type PolarOptions struct {
+	Title string
+	Scale Scale
+
+	// RangeDB is how many dB below the maximum the center of a LinearDB plot
+	// is. Defaults to 40.
+	RangeDB float64
+
+	// Size is the width and height of the plot in pixels. Defaults to 500.
+	Size int
+
+	// Elevation draws the plot as an elevation plot, with only the upper
+	// half of the circle and angles measured up from the horizon. Otherwise
+	// it's drawn as an azimuth plot, with angles measured counterclockwise
+	// from the X axis.
+	Elevation bool
+}
+
+// Cut is a slice through a radiation pattern: the gain at each of a set of
+// angles, in degrees. The angles are either azimuth (phi) angles or elevation
+// angles above the horizon, depending on how the cut was taken. Label is shown
+// in the plot's legend.
+type Cut struct {
+	Label  string
+	Angles []float64
+	Gains  []float64 // dBi
+}
+
+// AzimuthCut takes a cut through the pattern at the theta index t, across all
+// of the phi angles.
+func AzimuthCut(p *necpp.RadiationPattern, t int) (Cut, error) {
+	if t < 0 || t >= p.NTheta {
+		return Cut{}, fmt.Errorf("theta index %d out of range; the pattern has %d", t, p.NTheta)
+	}
+	c := Cut{Label: freqLabel(p.FreqMHz)}
+	for i := 0; i < p.NPhi; i++ {
+		pt := p.Point(t, i)
+		c.Angles = append(c.Angles, pt.Phi)
+		c.Gains = append(c.Gains, pt.Total)
+	}
+	return c, nil
+}
+
+// ElevationCut takes a cut through the pattern at the azimuth angle phi, in
+// degrees, across all of the theta angles. If the pattern also covers the
+// azimuth directly behind phi, that half is included as well, giving
+// elevation angles from 0 to 180 degrees. The theta angles are converted to
+// elevation angles above the horizon.
+func ElevationCut(p *necpp.RadiationPattern, phi float64) (Cut, error) {
+	front := phiIndex(p, phi)
+	if front < 0 {
+		return Cut{}, fmt.Errorf("the pattern has no points at phi = %g degrees", phi)
+	}
+	c := Cut{Label: freqLabel(p.FreqMHz)}
+	for t := 0; t < p.NTheta; t++ {
+		pt := p.Point(t, front)
+		c.Angles = append(c.Angles, 90-pt.Theta)
+		c.Gains = append(c.Gains, pt.Total)
+	}
+	if back := phiIndex(p, phi+180); back >= 0 && back != front {
+		for t := p.NTheta - 1; t >= 0; t-- {
+			pt := p.Point(t, back)
+			c.Angles = append(c.Angles, 90+pt.Theta)
+			c.Gains = append(c.Gains, pt.Total)
+		}
+	}
+	return c, nil
+}
+
+func phiIndex(p *necpp.RadiationPattern, phi float64) int {
+	for i := 0; i < p.NPhi; i++ {
+		d := math.Mod(p.Point(0, i).Phi-phi, 360)
+		if math.Abs(d) < 1e-6 || math.Abs(math.Abs(d)-360) < 1e-6 {
+			return i
+		}
+	}
+	return -1
+}
+
+func freqLabel(f float64) string {
+	return fmt.Sprintf("%g MHz", f)
+}
+
+// arrlRings are the dB levels the rings of an ARRL log scale plot are drawn at.
+var arrlRings = []float64{0, -3, -6, -10, -20, -30, -40}
+
+// radius returns the fraction of the plot's radius gain g is drawn at, with
+// gains normalized so that 0 dB is the outer ring.
+func (o PolarOptions) radius(g float64) float64 {
+	if o.Scale == LinearDB {
+		return math.Max(0, 1+g/o.RangeDB)
+	}
+	return math.Pow(0.89, -g/2)
+}
+
+// Polar draws the cuts as a polar plot to w. All of the cuts are normalized to
+// the highest gain in any of them, which is given on the plot.
+func Polar(w io.Writer, cuts []Cut, opts PolarOptions) error {
+	if len(cuts) == 0 {
+		return errors.New("nothing to plot")
+	}
+	if opts.Size <= 0 {
+		opts.Size = 500
+	}
+	if opts.RangeDB <= 0 {
+		opts.RangeDB = 40
+	}
+	maxGain := math.Inf(-1)
+	for _, c := range cuts {
+		if len(c.Angles) != len(c.Gains) {
+			return fmt.Errorf("cut %q has %d angles but %d gains", c.Label, len(c.Angles), len(c.Gains))
+		}
+		for _, g := range c.Gains {
+			maxGain = math.Max(maxGain, g)
+		}
+	}
+
+	size := float64(opts.Size)
+	margin := 40.0
+	cx := size / 2
+	cy := size / 2
+	r := size/2 - margin
+	height := opts.Size
+	if opts.Elevation {
+		cy = size/2 + r/2
+		height = int(cy + margin*1.5)
+	}
+
+	s := newSVGWriter(w, opts.Size, height+16*len(cuts))
+	if opts.Title != "" {
+		s.text(cx, 20, "middle", opts.Title)
+	}
+
+	// the grid: rings of constant gain, and spokes every 30 degrees
+	var rings []float64
+	if opts.Scale == LinearDB {
+		for g := 0.0; g > -opts.RangeDB; g -= 10 {
+			rings = append(rings, g)
+		}
+	} else {
+		rings = arrlRings
+	}
+	for _, g := range rings {
+		rr := r * opts.radius(g)
+		if opts.Elevation {
+			s.printf(`<path d="M %.2f %.2f A %.2f %.2f 0 0 0 %.2f %.2f" fill="none" stroke="#bbb" stroke-dasharray="4,3"/>`+"\n", cx+rr, cy, rr, rr, cx-rr, cy)
+		} else {
+			s.circle(cx, cy, rr, "#bbb", g != 0)
+		}
+		s.text(cx+rr+2, cy-3, "start", fmt.Sprintf("%g", g))
+	}
+	maxAngle := 360
+	if opts.Elevation {
+		maxAngle = 180
+	}
+	for a := 0; a < maxAngle; a += 30 {
+		x, y := polarXY(cx, cy, r, float64(a))
+		s.line(cx, cy, x, y, "#ddd", 1, false)
+		lx, ly := polarXY(cx, cy, r+15, float64(a))
+		s.text(lx, ly+4, "middle", fmt.Sprintf("%d°", a))
+	}
+	if opts.Elevation {
+		s.line(cx-r, cy, cx+r, cy, "#bbb", 1, false)
+		lx, ly := polarXY(cx, cy, r+15, 180)
+		s.text(lx, ly+4, "middle", "180°")
+	}
+
+	labels := make([]string, len(cuts))
+	for i, c := range cuts {
+		xs := make([]float64, len(c.Angles))
+		ys := make([]float64, len(c.Angles))
+		for j, a := range c.Angles {
+			xs[j], ys[j] = polarXY(cx, cy, r*opts.radius(c.Gains[j]-maxGain), a)
+		}
+		s.polyline(xs, ys, color(i), !opts.Elevation && spansCircle(c.Angles))
+		labels[i] = c.Label
+	}
+	s.text(margin/2, float64(height)-4, "start", fmt.Sprintf("0 dB = %.2f dBi", maxGain))
+	s.legend(margin/2, float64(height)+14, labels)
+	return s.close()
+}
+
+// polarXY converts an angle in degrees, measured counterclockwise from the
+// right hand side, and a radius to SVG coordinates around (cx, cy).
+func polarXY(cx float64, cy float64, r float64, deg float64) (float64, float64) {
+	rad := deg * math.Pi / 180
+	return cx + r*math.Cos(rad), cy - r*math.Sin(rad)
+}
+
+// spansCircle reports whether a set of angles goes most of the way around the
+// circle, so the line should be closed up.
+func spansCircle(angles []float64) bool {
+	if len(angles) < 3 {
+		return false
+	}
+	step := angles[1] - angles[0]
+	return math.Abs(angles[len(angles)-1]-angles[0]+step) >= 360-1e-6
+}
@@ -0,0 +1,242 @@
+package plot
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"math"
+
+	"github.com/ctdk/go-libnecpp"
+)
+
+// Series is a set of (x, y) points to draw as a line on a rectangular chart.
+// Points with a y value of NaN or infinity are left out, breaking the line.
+type Series struct {
+	Label string
+	X     []float64
+	Y     []float64
+}
+
+// RectOptions controls the appearance of a rectangular chart.
+type RectOptions struct {
+	Title  string
+	XLabel string
+	YLabel string
+
+	// Width and Height are the size of the chart in pixels. They default to
+	// 640 by 400.
+	Width  int
+	Height int
+
+	// YMin and YMax fix the range of the y axis. If they're equal, the range
+	// is worked out from the data.
+	YMin float64
+	YMax float64
+}
+
+// VSWRSeries makes a series of the VSWR against z0 at each frequency of an
+// impedance sweep. VSWRs above 10 are left off the chart.
+func VSWRSeries(label string, sweep []necpp.ImpedancePoint, z0 float64) Series {
+	s := Series{Label: label}
+	for _, p := range sweep {
+		v := necpp.VSWR(p.Impedance, z0)
+		if v > 10 {
+			v = math.NaN()
+		}
+		s.X = append(s.X, p.FreqMHz)
+		s.Y = append(s.Y, v)
+	}
+	return s
+}
+
+// ResistanceSeries makes a series of the resistance at each frequency of an
+// impedance sweep.
+func ResistanceSeries(label string, sweep []necpp.ImpedancePoint) Series {
+	s := Series{Label: label}
+	for _, p := range sweep {
+		s.X = append(s.X, p.FreqMHz)
+		s.Y = append(s.Y, real(p.Impedance))
+	}
+	return s
+}
+
+// ReactanceSeries makes a series of the reactance at each frequency of an
+// impedance sweep.
+func ReactanceSeries(label string, sweep []necpp.ImpedancePoint) Series {
+	s := Series{Label: label}
+	for _, p := range sweep {
+		s.X = append(s.X, p.FreqMHz)
+		s.Y = append(s.Y, imag(p.Impedance))
+	}
+	return s
+}
+
+// VSWRChart draws the VSWR against z0 of each sweep on one chart, labelled in
+// the legend by the given labels.
+func VSWRChart(w io.Writer, labels []string, sweeps [][]necpp.ImpedancePoint, z0 float64) error {
+	if len(labels) != len(sweeps) {
+		return errors.New("there must be a label for each sweep")
+	}
+	series := make([]Series, len(sweeps))
+	for i := range sweeps {
+		series[i] = VSWRSeries(labels[i], sweeps[i], z0)
+	}
+	opts := RectOptions{
+		Title:  fmt.Sprintf("VSWR (%g ohms)", z0),
+		XLabel: "Frequency (MHz)",
+		YLabel: "VSWR",
+		YMin:   1,
+	}
+	// let the top of the chart fit the data, but no more than 10:1
+	for _, s := range series {
+		for _, y := range s.Y {
+			if !math.IsNaN(y) {
+				opts.YMax = math.Max(opts.YMax, y)
+			}
+		}
+	}
+	opts.YMax = math.Min(math.Max(math.Ceil(opts.YMax), 2), 10)
+	return Rect(w, series, opts)
+}
+
+// ImpedanceChart draws the resistance and reactance of each sweep against
+// frequency on one chart, labelled in the legend by the given labels followed
+// by R or X.
+func ImpedanceChart(w io.Writer, labels []string, sweeps [][]necpp.ImpedancePoint) error {
+	if len(labels) != len(sweeps) {
+		return errors.New("there must be a label for each sweep")
+	}
+	var series []Series
+	for i := range sweeps {
+		series = append(series, ResistanceSeries(labels[i]+" R", sweeps[i]), ReactanceSeries(labels[i]+" X", sweeps[i]))
+	}
+	opts := RectOptions{
+		Title:  "Impedance",
+		XLabel: "Frequency (MHz)",
+		YLabel: "Ohms",
+	}
+	return Rect(w, series, opts)
+}
+
+// Rect draws the series as lines on a rectangular chart to w.
+func Rect(w io.Writer, series []Series, opts RectOptions) error {
+	if len(series) == 0 {
+		return errors.New("nothing to plot")
+	}
+	if opts.Width <= 0 {
+		opts.Width = 640
+	}
+	if opts.Height <= 0 {
+		opts.Height = 400
+	}
+	xmin, xmax := math.Inf(1), math.Inf(-1)
+	ymin, ymax := math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		if len(s.X) != len(s.Y) {
+			return fmt.Errorf("series %q has %d x values but %d y values", s.Label, len(s.X), len(s.Y))
+		}
+		for i := range s.X {
+			if !finite(s.Y[i]) {
+				continue
+			}
+			xmin, xmax = math.Min(xmin, s.X[i]), math.Max(xmax, s.X[i])
+			ymin, ymax = math.Min(ymin, s.Y[i]), math.Max(ymax, s.Y[i])
+		}
+	}
+	if math.IsInf(xmin, 0) {
+		return errors.New("no points to plot")
+	}
+	if opts.YMin != opts.YMax {
+		ymin, ymax = opts.YMin, opts.YMax
+	}
+	xticks := niceTicks(xmin, xmax)
+	yticks := niceTicks(ymin, ymax)
+	xmin, xmax = math.Min(xmin, xticks[0]), math.Max(xmax, xticks[len(xticks)-1])
+	ymin, ymax = math.Min(ymin, yticks[0]), math.Max(ymax, yticks[len(yticks)-1])
+	if xmax == xmin {
+		xmin, xmax = xmin-1, xmax+1
+	}
+	if ymax == ymin {
+		ymin, ymax = ymin-1, ymax+1
+	}
+
+	left, right, top, bottom := 60.0, 20.0, 35.0, 45.0
+	pw := float64(opts.Width) - left - right
+	ph := float64(opts.Height) - top - bottom
+	px := func(x float64) float64 { return left + (x-xmin)/(xmax-xmin)*pw }
+	py := func(y float64) float64 { return top + ph - (y-ymin)/(ymax-ymin)*ph }
+
+	s := newSVGWriter(w, opts.Width, opts.Height+16*len(series))
+	if opts.Title != "" {
+		s.text(float64(opts.Width)/2, 20, "middle", opts.Title)
+	}
+	for _, x := range xticks {
+		if x < xmin || x > xmax {
+			continue
+		}
+		s.line(px(x), top, px(x), top+ph, "#ddd", 1, false)
+		s.text(px(x), top+ph+15, "middle", ftoa(x))
+	}
+	for _, y := range yticks {
+		if y < ymin || y > ymax {
+			continue
+		}
+		s.line(left, py(y), left+pw, py(y), "#ddd", 1, false)
+		s.text(left-5, py(y)+4, "end", ftoa(y))
+	}
+	s.printf(`<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="none" stroke="black"/>`+"\n", left, top, pw, ph)
+	if opts.XLabel != "" {
+		s.text(left+pw/2, top+ph+35, "middle", opts.XLabel)
+	}
+	if opts.YLabel != "" {
+		s.printf(`<text x="15" y="%.2f" text-anchor="middle" transform="rotate(-90 15 %.2f)">%s</text>`+"\n", top+ph/2, top+ph/2, html.EscapeString(opts.YLabel))
+	}
+
+	labels := make([]string, len(series))
+	for i, ser := range series {
+		xs := make([]float64, len(ser.X))
+		ys := make([]float64, len(ser.Y))
+		for j := range ser.X {
+			if !finite(ser.Y[j]) || ser.Y[j] < ymin || ser.Y[j] > ymax {
+				xs[j], ys[j] = math.NaN(), math.NaN()
+				continue
+			}
+			xs[j], ys[j] = px(ser.X[j]), py(ser.Y[j])
+		}
+		s.polyline(xs, ys, color(i), false)
+		labels[i] = ser.Label
+	}
+	s.legend(left, float64(opts.Height)+10, labels)
+	return s.close()
+}
+
+func finite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// niceTicks picks round numbers to put tick marks at covering lo to hi.
+func niceTicks(lo float64, hi float64) []float64 {
+	if hi <= lo {
+		return []float64{lo}
+	}
+	raw := (hi - lo) / 6
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := mag
+	for _, m := range []float64{1, 2, 5, 10} {
+		if m*mag >= raw {
+			step = m * mag
+			break
+		}
+	}
+	var ticks []float64
+	for t := math.Floor(lo/step) * step; t <= hi+step/2; t += step {
+		// get rid of floating point fuzz like 0.30000000000000004
+		ticks = append(ticks, math.Round(t/step)*step)
+	}
+	return ticks
+}
+
+func ftoa(f float64) string {
+	return fmt.Sprintf("%g", f)
+}
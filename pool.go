@@ -0,0 +1,138 @@
+package necpp
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrPoolClosed is returned by ContextPool.Get() once the pool has been closed.
+var ErrPoolClosed = errors.New("the context pool has been closed")
+
+// ContextPool hands out NEC contexts that have been made ahead of time, and
+// frees the ones given back, both on a goroutine of its own. libnecpp can't
+// clear a nec_context for another model, so making and freeing them can't be
+// avoided; a pool just takes that work out of the way of a loop that runs one
+// model after another, such as an optimizer's.
+//
+// The contexts are as fresh as New() would give, with nothing set up on them.
+// Call Close() when done with the pool to free the contexts it's holding on
+// to.
+type ContextPool struct {
+	ready chan *NecppCtx // made, and waiting to be handed out
+	used  chan *NecppCtx // given back, and waiting to be freed
+	quit  chan struct{}
+	done  chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewContextPool starts a pool that keeps up to size contexts ready.
+func NewContextPool(size int) *ContextPool {
+	if size < 1 {
+		size = 1
+	}
+	p := &ContextPool{
+		ready: make(chan *NecppCtx, size),
+		used:  make(chan *NecppCtx, size),
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go p.keep()
+	return p
+}
+
+// keep frees the contexts that are given back, and makes new ones until there
+// are enough ready. It's the only thing that adds to p.ready, so there's always
+// room for the one it's just made.
+func (p *ContextPool) keep() {
+	defer close(p.done)
+	for {
+		select {
+		case n := <-p.used:
+			n.Delete()
+			continue
+		case <-p.quit:
+			return
+		default:
+		}
+		if len(p.ready) < cap(p.ready) {
+			if n, err := New(); err == nil {
+				p.ready <- n
+				continue
+			}
+			// if libnecpp won't make one now, Get() will find out why
+		}
+		select {
+		case n := <-p.used:
+			n.Delete()
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// Get returns a fresh context, one that's been made ahead of time if there is
+// one, or a new one if not. It should be given back with Put() when it's done
+// with, rather than deleted.
+func (p *ContextPool) Get() (*NecppCtx, error) {
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		return nil, ErrPoolClosed
+	}
+	select {
+	case n := <-p.ready:
+		return n, nil
+	default:
+		return New()
+	}
+}
+
+// Put gives a context back to the pool, to be freed. It mustn't be used after
+// that. If the pool is behind, or closed, the context is freed straight away.
+func (p *ContextPool) Put(n *NecppCtx) {
+	p.mu.Lock()
+	if !p.closed {
+		select {
+		case p.used <- n:
+			p.mu.Unlock()
+			return
+		default:
+		}
+	}
+	p.mu.Unlock()
+	n.Delete()
+}
+
+// Close stops the pool and frees the contexts it has made and been given back.
+// Contexts handed out by Get() and not yet given back are the caller's to
+// delete, or to give to Put(), which frees them once the pool is closed.
+func (p *ContextPool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	close(p.quit)
+	<-p.done
+	var err error
+	for {
+		select {
+		case n := <-p.ready:
+			if derr := n.Delete(); err == nil {
+				err = derr
+			}
+		case n := <-p.used:
+			if derr := n.Delete(); err == nil {
+				err = derr
+			}
+		default:
+			return err
+		}
+	}
+}
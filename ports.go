@@ -0,0 +1,169 @@
+package necpp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Port identifies a feed point on the antenna, in the same way as the tag and
+// segment parameters for ExcitationVoltage(). If Tag is zero, Segment is an
+// absolute segment number.
+type Port struct {
+	Tag     int
+	Segment int
+}
+
+// BuildFunc sets up a model on a freshly created Engine - its geometry,
+// ground, loading, and the FR card for the frequencies of interest. It must not
+// add any excitation; the functions that take a BuildFunc apply their own
+// excitations to the ports they are measuring.
+type BuildFunc func(n Engine) error
+
+// openCircuitResistance is the series resistance loaded onto a port's segment
+// to hold it open while another port is being measured.
+const openCircuitResistance float64 = 1.0e9
+
+// TwoPortSweep measures the 2x2 port impedance matrix of a model with two feed
+// points at each of the frequencies set up by build, suitable for writing out
+// with WriteS2P(). It's ZMatrix() for two ports; see that for how the matrix
+// is measured.
+func TwoPortSweep(newEngine EngineFactory, build BuildFunc, p1 Port, p2 Port) ([]NetworkPoint, error) {
+	return ZMatrix(newEngine, build, []Port{p1, p2})
+}
+
+// ZMatrix measures the NxN port impedance matrix of a model between the given
+// ports, at each of the frequencies set up by build. Each run of the model is
+// on a new engine from newEngine, which can be nil for libnecpp.
+//
+// libnecpp only reports the input impedance of the first voltage source, so the
+// matrix is worked out from separate runs of the model, with every port that
+// isn't being driven or shorted held open:
+//
+// • port i driven, giving Zii.
+//
+// • port i driven with port j shorted, giving Zii - Zij^2/Zjj.
+//
+// • ports i and j driven with equal voltages, giving det/(Zjj - Zij), where det
+// is the determinant of the 2x2 matrix for ports i and j, which settles the
+// sign of Zij.
+//
+// That's N^2 runs in all. A port is held open by loading its segment with a
+// very large resistance, and shorted by leaving it as plain wire. The network
+// is assumed to be reciprocal.
+func ZMatrix(newEngine EngineFactory, build BuildFunc, ports []Port) ([]NetworkPoint, error) {
+	if len(ports) == 0 {
+		return nil, errors.New("no ports")
+	}
+	// others returns all of the ports but the ones given
+	others := func(skip ...int) []Port {
+		var ps []Port
+	outer:
+		for k, p := range ports {
+			for _, s := range skip {
+				if k == s {
+					continue outer
+				}
+			}
+			ps = append(ps, p)
+		}
+		return ps
+	}
+
+	var points []NetworkPoint
+	for i := range ports {
+		zii, err := measureInput(newEngine, build, []Port{ports[i]}, others(i))
+		if err != nil {
+			return nil, err
+		}
+		if points == nil {
+			points = make([]NetworkPoint, len(zii))
+			for f := range points {
+				points[f] = NetworkPoint{FreqMHz: zii[f].FreqMHz, Z: newMatrix(len(ports), len(ports))}
+			}
+		}
+		if len(zii) != len(points) {
+			return nil, errors.New("the model gave a different number of frequencies from one run to the next")
+		}
+		for f := range points {
+			points[f].Z[i][i] = zii[f].Impedance
+		}
+	}
+	for i := range ports {
+		for j := i + 1; j < len(ports); j++ {
+			zShort, err := measureInput(newEngine, build, []Port{ports[i]}, others(i, j))
+			if err != nil {
+				return nil, err
+			}
+			zEven, err := measureInput(newEngine, build, []Port{ports[i], ports[j]}, others(i, j))
+			if err != nil {
+				return nil, err
+			}
+			if len(zShort) != len(points) || len(zEven) != len(points) {
+				return nil, errors.New("the model gave a different number of frequencies from one run to the next")
+			}
+			for f := range points {
+				z := points[f].Z
+				zij, err := mutualImpedance(z[i][i], z[j][j], zShort[f].Impedance, zEven[f].Impedance)
+				if err != nil {
+					return nil, fmt.Errorf("ports %d and %d at %g MHz: %s", i+1, j+1, points[f].FreqMHz, err.Error())
+				}
+				z[i][j], z[j][i] = zij, zij
+			}
+		}
+	}
+	return points, nil
+}
+
+// mutualImpedance works out Zij from the self impedances of ports i and j, the
+// impedance at i with j shorted, and the impedance at i with both driven
+// equally.
+func mutualImpedance(zii complex128, zjj complex128, zShort complex128, zEven complex128) (complex128, error) {
+	if zEven == 0 {
+		return 0, errors.New("zero even mode impedance")
+	}
+	zijSq := zjj * (zii - zShort)
+	det := zii*zjj - zijSq
+	return zjj - det/zEven, nil
+}
+
+// S returns the scattering matrix of the network, with the reference impedance
+// z0 on every port.
+func (p NetworkPoint) S(z0 float64) ([][]complex128, error) {
+	if z0 <= 0 {
+		return nil, fmt.Errorf("reference impedance must be positive, got %g", z0)
+	}
+	return zToS(p.Z, z0)
+}
+
+// measureInput runs the model with a 1V source on each of the driven ports, in
+// order, and each of the open ports held open, returning the input impedance
+// seen by the first driven port across the sweep.
+func measureInput(newEngine EngineFactory, build BuildFunc, driven []Port, open []Port) ([]ImpedancePoint, error) {
+	if len(driven) == 0 {
+		return nil, errors.New("no driven ports")
+	}
+	n, err := newEngine.engine()
+	if err != nil {
+		return nil, err
+	}
+	defer n.Delete()
+
+	if err = build(n); err != nil {
+		return nil, err
+	}
+	for _, p := range open {
+		// a series RLC load (type 0) with no L or C is just a resistor
+		if err = n.LdCard(0, p.Tag, p.Segment, p.Segment, openCircuitResistance, 0, 0); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range driven {
+		if err = n.ExcitationVoltage(p.Tag, p.Segment, complex(1, 0)); err != nil {
+			return nil, err
+		}
+	}
+	if err = n.XqCard(NoPattern); err != nil {
+		return nil, err
+	}
+	return n.ImpedanceSweep()
+}
@@ -0,0 +1,79 @@
+package necpp
+
+import (
+	"math/cmplx"
+	"testing"
+)
+
+func TestMutualImpedance(t *testing.T) {
+	zii, zjj, zij := complex(73, 42), complex(70, 30), complex(-12, -30)
+	zShort := zii - zij*zij/zjj
+	det := zii*zjj - zij*zij
+	zEven := det / (zjj - zij)
+	got, err := mutualImpedance(zii, zjj, zShort, zEven)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmplx.Abs(got-zij) > 1e-9 {
+		t.Errorf("mutual impedance was %v, should have been %v", got, zij)
+	}
+
+	s, err := NetworkPoint{Z: [][]complex128{{zii, zij}, {zij, zjj}}}.S(50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmplx.Abs(s[0][1]-s[1][0]) > 1e-12 {
+		t.Errorf("S matrix of a reciprocal network should be symmetric, got %v", s)
+	}
+}
+
+func TestZMatrixEngines(t *testing.T) {
+	var made []*FakeEngine
+	newEngine := func() (Engine, error) {
+		f := &FakeEngine{ImpedanceFunc: func(freqMHz float64) complex128 { return complex(50, freqMHz) }}
+		made = append(made, f)
+		return f, nil
+	}
+	build := func(n Engine) error {
+		if err := n.Wire(1, 11, 0, 0, -5, 0, 0, 5, 0.001, 1, 1); err != nil {
+			return err
+		}
+		if err := n.GeometryComplete(NoGroundPlane); err != nil {
+			return err
+		}
+		return n.FrCard(Linear, 2, 14, 0.1)
+	}
+	p1, p2 := Port{Tag: 1, Segment: 3}, Port{Tag: 1, Segment: 9}
+	points, err := ZMatrix(newEngine, build, []Port{p1, p2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 2 {
+		t.Errorf("got %d frequencies, should have been 2", len(points))
+	}
+	if len(made) != 4 {
+		t.Fatalf("made %d engines, should have been one for each of the 4 runs", len(made))
+	}
+	// the first run drives port 1 with port 2 held open
+	calls := made[0].Calls
+	var ld, ex *Call
+	for i := range calls {
+		switch calls[i].Method {
+		case "LdCard":
+			ld = &calls[i]
+		case "ExcitationVoltage":
+			ex = &calls[i]
+		}
+	}
+	if ld == nil || ld.Args[2] != p2.Segment || ld.Args[4] != openCircuitResistance {
+		t.Errorf("port 2 wasn't held open on the first run: %v", ld)
+	}
+	if ex == nil || ex.Args[1] != p1.Segment {
+		t.Errorf("port 1 wasn't driven on the first run: %v", ex)
+	}
+	for i, f := range made {
+		if last := f.Calls[len(f.Calls)-1]; last.Method != "Delete" {
+			t.Errorf("engine %d wasn't deleted", i)
+		}
+	}
+}
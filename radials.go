@@ -0,0 +1,123 @@
+package necpp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Radials is a set of radial wires fanning out from the base of a vertical,
+// evenly spaced in azimuth.
+//
+// Elevated radials are added to the structure as wires with Add(). Radials
+// lying on or buried in the ground can't be modelled as wires, so for those
+// Screen() gives NEC's radial ground screen approximation to use in a Ground.
+type Radials struct {
+	Count    int
+	Length   float64    // meters
+	Radius   float64    // wire radius, in meters
+	Segments int        // segments in each radial
+	Droop    float64    // degrees below horizontal
+	Azimuth  float64    // degrees from the X axis of the first radial
+	Hub      [3]float64 // where the radials meet, in meters
+	Tag      int        // the tag of the first radial; the rest follow on
+
+	// FreeSpace says the antenna isn't over a ground, so there's nothing
+	// to stop the radials going below z = 0. Over a ground they mustn't.
+	FreeSpace bool
+}
+
+// wires works out the wires for the radials.
+func (r Radials) wires() ([]WireSpec, error) {
+	if r.Count < 1 || r.Length <= 0 || r.Radius <= 0 || r.Segments < 1 {
+		return nil, errors.New("radials need a count, length, wire radius and number of segments")
+	}
+	if r.Droop < 0 || r.Droop > 90 {
+		return nil, errors.New("the droop angle must be between 0 and 90 degrees")
+	}
+	droop := r.Droop * math.Pi / 180
+	if !r.FreeSpace && r.Hub[2]-r.Length*math.Sin(droop) < 0 {
+		return nil, fmt.Errorf("radials drooping %g degrees from a height of %g m go %g m into the ground", r.Droop, r.Hub[2], r.Length*math.Sin(droop)-r.Hub[2])
+	}
+	ws := make([]WireSpec, r.Count)
+	for i := range ws {
+		az := (r.Azimuth + 360*float64(i)/float64(r.Count)) * math.Pi / 180
+		ws[i] = WireSpec{
+			Tag:      r.Tag + i,
+			Segments: r.Segments,
+			X1:       r.Hub[0],
+			Y1:       r.Hub[1],
+			Z1:       r.Hub[2],
+			X2:       r.Hub[0] + r.Length*math.Cos(droop)*math.Cos(az),
+			Y2:       r.Hub[1] + r.Length*math.Cos(droop)*math.Sin(az),
+			Z2:       r.Hub[2] - r.Length*math.Sin(droop),
+			Radius:   r.Radius,
+			RDel:     1,
+			RRad:     1,
+		}
+	}
+	return ws, nil
+}
+
+// Add adds the radials to the structure as wires, one tag per radial. It must
+// be called before GeometryComplete(). Unless FreeSpace is set, radials that
+// droop into the ground are an error.
+func (r Radials) Add(n Engine) error {
+	ws, err := r.wires()
+	if err != nil {
+		return err
+	}
+	for _, w := range ws {
+		if err := n.Wire(w.Tag, w.Segments, w.X1, w.Y1, w.Z1, w.X2, w.Y2, w.Z2, w.Radius, w.RDel, w.RRad); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Screen returns the radials as a radial ground screen, for radials lying on
+// or just under the ground. The screen is always centered on the origin, and
+// only changes the radiation pattern; NEC doesn't take it into account in the
+// feed impedance.
+func (r Radials) Screen() *GroundScreen {
+	return &GroundScreen{Radials: r.Count, Radius: r.Length, WireRadius: r.Radius}
+}
+
+// ImpedanceChange is the feed impedance of a model at one frequency with and
+// without some change to it.
+type ImpedanceChange struct {
+	FreqMHz float64
+	Without complex128
+	With    complex128
+}
+
+// Delta returns how much the change moved the impedance.
+func (c ImpedanceChange) Delta() complex128 {
+	return c.With - c.Without
+}
+
+// CompareRadials works out how much a set of radials changes the feed
+// impedance of an antenna. build is run twice, once with radials false and once
+// with it true, and should add the radials (or radial screen) only when it's
+// true; like any other BuildFunc it sets up everything but the excitation. The
+// feed impedance is measured at feed across all of the frequencies build sets
+// up. Each model is run on a new engine from newEngine, which can be nil for
+// libnecpp.
+func CompareRadials(newEngine EngineFactory, build func(n Engine, radials bool) error, feed Port) ([]ImpedanceChange, error) {
+	without, err := measureInput(newEngine, func(n Engine) error { return build(n, false) }, []Port{feed}, nil)
+	if err != nil {
+		return nil, err
+	}
+	with, err := measureInput(newEngine, func(n Engine) error { return build(n, true) }, []Port{feed}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(with) != len(without) {
+		return nil, errors.New("the models with and without radials have different frequencies")
+	}
+	changes := make([]ImpedanceChange, len(with))
+	for i := range with {
+		changes[i] = ImpedanceChange{FreqMHz: with[i].FreqMHz, Without: without[i].Impedance, With: with[i].Impedance}
+	}
+	return changes, nil
+}
@@ -0,0 +1,78 @@
+package necpp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRadialWires(t *testing.T) {
+	r := Radials{Count: 4, Length: 10, Radius: 0.001, Segments: 5, Droop: 30, Hub: [3]float64{0, 0, 6}, Tag: 10}
+	ws, err := r.wires()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ws) != 4 {
+		t.Fatalf("there were %d radials, should have been 4", len(ws))
+	}
+	w := ws[1]
+	if w.Tag != 11 || math.Abs(w.X2) > 1e-9 || math.Abs(w.Y2-10*math.Sqrt(3)/2) > 1e-9 || math.Abs(w.Z2-1) > 1e-9 {
+		t.Errorf("second radial was %+v", w)
+	}
+	if _, err := (Radials{Count: 4, Length: 10, Radius: 0.001, Segments: 5, Droop: 120}).wires(); err == nil {
+		t.Errorf("a droop of 120 degrees should have been an error")
+	}
+
+	r.Hub[2] = 3
+	if _, err := r.wires(); err == nil {
+		t.Errorf("radials drooping 2 m into the ground should have been an error")
+	}
+	r.FreeSpace = true
+	if _, err := r.wires(); err != nil {
+		t.Errorf("radials going below z = 0 in free space gave %s", err)
+	}
+}
+
+func TestCompareRadials(t *testing.T) {
+	// the radials take the impedance from 36 down to 22 ohms; the fake
+	// engines tell whether they were added by the wires they were given
+	newEngine := func() (Engine, error) {
+		f := new(FakeEngine)
+		f.ImpedanceFunc = func(freqMHz float64) complex128 {
+			if len(f.Geometry().Wires) > 1 {
+				return complex(22, freqMHz-7)
+			}
+			return complex(36, freqMHz-7)
+		}
+		return f, nil
+	}
+	radials := Radials{Count: 4, Length: 10, Radius: 0.001, Segments: 5, Droop: 30, Hub: [3]float64{0, 0, 6}, Tag: 2}
+	build := func(n Engine, withRadials bool) error {
+		if err := n.Wire(1, 11, 0, 0, 6, 0, 0, 16, 0.001, 1, 1); err != nil {
+			return err
+		}
+		if withRadials {
+			if err := radials.Add(n); err != nil {
+				return err
+			}
+		}
+		if err := n.GeometryComplete(NoGroundPlane); err != nil {
+			return err
+		}
+		return n.FrCard(Linear, 3, 7.0, 0.1)
+	}
+	changes, err := CompareRadials(newEngine, build, Port{Tag: 1, Segment: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("there were %d changes, should have been one for each of 3 frequencies", len(changes))
+	}
+	for _, c := range changes {
+		if real(c.Without) != 36 || real(c.With) != 22 || c.Delta() != -14 {
+			t.Errorf("at %g MHz the impedance went from %v to %v", c.FreqMHz, c.Without, c.With)
+		}
+	}
+	if math.Abs(changes[2].FreqMHz-7.2) > 1e-9 {
+		t.Errorf("the last frequency was %g MHz, should have been 7.2", changes[2].FreqMHz)
+	}
+}
@@ -0,0 +1,315 @@
+package necpp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// recorder keeps the bookkeeping that goes along with the cards sent to an
+// engine: the structure built so far, the frequencies asked for, the radiation
+// patterns that have been calculated and everything printed along the way.
+// libnecpp keeps most of this to itself, so it's tracked here in Go, and the
+// same bookkeeping is shared by NecppCtx, FakeEngine and Solver.
+type recorder struct {
+	fr        *frCard
+	frPending bool // an FR card has been given, but hasn't been run yet
+	patterns  []patternGrid
+	geom      Geometry
+	patch     *PatchSpec      // an SP card waiting for its SC card
+	output    strings.Builder // libnecpp's printed output
+	outLimit  int             // how much of it to keep; see SetOutputLimit()
+	stdout    io.Writer       // where the printed output is passed on to
+	capture   bool            // SetOutput() has been called, so the output is caught
+
+	// the results read out of the output as it was printed, so dropping the
+	// start of the output doesn't lose them
+	results  Listing
+	currents []currentTable // the currents in results, with their frequencies
+	lastFreq float64        // the frequency the latest results were printed at
+	parseErr error          // set if some of the output couldn't be read
+}
+
+// DefaultOutputLimit is how much of the printed report an engine keeps for
+// Output(), in bytes, unless SetOutputLimit() says otherwise.
+const DefaultOutputLimit = 16 << 20
+
+// frCard holds the parameters of the most recent FR card, so the frequencies
+// the results are stored at can be worked out later.
+type frCard struct {
+	ifrq    FrequencyRange
+	nfrq    int
+	freqMhz float64
+	delFreq float64
+}
+
+func (r *recorder) wire(w WireSpec) {
+	r.geom.Wires = append(r.geom.Wires, w)
+}
+
+func (r *recorder) spCard(ns PatchType, x1 float64, y1 float64, z1 float64, x2 float64, y2 float64, z2 float64) {
+	if ns == Arbitrary {
+		r.geom.Patches = append(r.geom.Patches, PatchSpec{Shape: ns, Corners: [][3]float64{{x1, y1, z1}}, Elevation: x2, Azimuth: y2, Area: z2})
+		r.patch = nil
+	} else {
+		r.patch = &PatchSpec{Shape: ns, Corners: [][3]float64{{x1, y1, z1}, {x2, y2, z2}}}
+	}
+}
+
+func (r *recorder) scCard(x3 float64, y3 float64, z3 float64, x4 float64, y4 float64, z4 float64) {
+	p := r.patch
+	if p == nil {
+		return
+	}
+	c1, c2, c3 := p.Corners[0], p.Corners[1], [3]float64{x3, y3, z3}
+	switch p.Shape {
+	case Rectangular:
+		p.Corners = append(p.Corners, c3, [3]float64{c1[0] + c3[0] - c2[0], c1[1] + c3[1] - c2[1], c1[2] + c3[2] - c2[2]})
+	case Triangular:
+		p.Corners = append(p.Corners, c3)
+	case Quadrilateral:
+		p.Corners = append(p.Corners, c3, [3]float64{x4, y4, z4})
+	}
+	r.geom.Patches = append(r.geom.Patches, *p)
+	r.patch = nil
+}
+
+func (r *recorder) frCard(ifrq FrequencyRange, nfrq int, freqMhz float64, delFreq float64) {
+	r.fr = &frCard{ifrq: ifrq, nfrq: nfrq, freqMhz: freqMhz, delFreq: delFreq}
+	r.frPending = true
+}
+
+func (r *recorder) ldCard(ldtype int, ldtag int, ldtagf int, ldtagt int, tmp1 float64, tmp2 float64, tmp3 float64) {
+	if ldtagt == 0 {
+		ldtagt = ldtagf
+	}
+	r.geom.Loads = append(r.geom.Loads, Load{Type: ldtype, Tag: ldtag, From: ldtagf, To: ldtagt, R: tmp1, L: tmp2, C: tmp3})
+}
+
+func (r *recorder) feed(tag int, segment int, v complex128) {
+	r.geom.Feeds = append(r.geom.Feeds, Feed{Port: Port{Tag: tag, Segment: segment}, Voltage: v})
+}
+
+// xqCard records the patterns an XQ card calculates, which are the vertical
+// cuts asked for, if any.
+func (r *recorder) xqCard(opt ExecutionOption) {
+	xz := patternGrid{nTheta: 91, nPhi: 1, dTheta: 1}
+	yz := patternGrid{nTheta: 91, nPhi: 1, phi0: 90, dTheta: 1}
+	switch opt {
+	case XZPlane:
+		r.recordPatterns(xz)
+	case YZPlane:
+		r.recordPatterns(yz)
+	case BothPlane:
+		r.recordPatterns(xz, yz)
+	default:
+		r.recordPatterns()
+	}
+}
+
+// runFrequencies returns the frequencies the next run of the simulation will
+// calculate at. The first run after an FR card goes through every frequency;
+// later ones only calculate at the last frequency.
+func (r *recorder) runFrequencies() []float64 {
+	freqs := r.Frequencies()
+	if !r.frPending && len(freqs) > 0 {
+		freqs = freqs[len(freqs)-1:]
+	}
+	if len(freqs) == 0 {
+		freqs = []float64{0}
+	}
+	return freqs
+}
+
+// recordPatterns keeps track of the radiation patterns a run of the simulation
+// has stored results for, in the order libnecpp stores them.
+func (r *recorder) recordPatterns(grids ...patternGrid) {
+	for _, f := range r.runFrequencies() {
+		for _, g := range grids {
+			g.freqMHz = f
+			r.patterns = append(r.patterns, g)
+		}
+	}
+	r.frPending = false
+}
+
+// Frequencies returns the frequencies, in MHz, requested by the most recent
+// FrCard() call. The index of a frequency in the returned slice is the
+// freqIndex used to get its results with Impedance(). If FrCard() has not been
+// called, nil is returned.
+func (r *recorder) Frequencies() []float64 {
+	if r.fr == nil {
+		return nil
+	}
+	nfrq := r.fr.nfrq
+	if nfrq < 1 {
+		// a blank frequency count on an FR card means one frequency
+		nfrq = 1
+	}
+	freqs := make([]float64, nfrq)
+	f := r.fr.freqMhz
+	for i := range freqs {
+		freqs[i] = f
+		if r.fr.ifrq == Logarithmic {
+			f *= r.fr.delFreq
+		} else {
+			f += r.fr.delFreq
+		}
+	}
+	return freqs
+}
+
+// Geometry returns a copy of the antenna's structure as it has been built up
+// so far, along with the feeds and loads that have been put on it.
+func (r *recorder) Geometry() *Geometry {
+	return r.geom.Copy()
+}
+
+// SetOutput has libnecpp's printed report caught while this context's
+// simulations run, and sets where it goes from there: pass ioutil.Discard to
+// throw it away, or nil to pass it on to standard output. It can be changed
+// between cards to send each run's report somewhere different. The report is
+// kept either way, and can be had with Output(); Currents(), NearField(),
+// InputPower() and Listing() are read out of it. Writes to w happen on another
+// goroutine while the card runs, but never after it returns. Output can't be
+// caught on Windows, where it always goes to standard output.
+//
+// Until SetOutput() is called, a NecppCtx leaves libnecpp's report alone: it
+// goes straight to standard output, and none of it is kept, so there's nothing
+// for those methods to find. FakeEngine and Solver keep their reports either
+// way.
+//
+// libnecpp prints its report straight to the process's standard output, so a
+// NecppCtx catches it by redirecting standard output while each card that
+// prints runs, and only then. Standard output belongs to the whole process, so
+// only one context can do that at a time: contexts that catch their output take
+// turns at the cards that print, even on separate goroutines, while contexts
+// that don't run side by side. Anything else printed to standard output while
+// it's redirected is caught as well: it ends up in the report, and goes to w
+// instead of standard output, which for ioutil.Discard means it's lost. To run
+// models in parallel with their reports caught, or to print from other
+// goroutines while they run, use a Worker, which runs a model in a process of
+// its own.
+func (r *recorder) SetOutput(w io.Writer) {
+	r.stdout = w
+	r.capture = true
+}
+
+// SetOutputLimit sets how much of the printed report is kept for Output(), in
+// bytes. Once there's more than that, the oldest lines are dropped, so what's
+// kept is always the end of the report, with the latest run in it. A limit of
+// zero means DefaultOutputLimit, and a negative one keeps everything, which can
+// use a lot of memory on a context that's run many times. Where the report is
+// passed on to, as set by SetOutput(), gets all of it regardless, and the
+// results Currents(), NearField(), InputPower() and Listing() return are read
+// as each run prints them, so they don't lose anything either.
+func (r *recorder) SetOutputLimit(limit int) {
+	r.outLimit = limit
+	r.record("")
+}
+
+// Output returns what libnecpp has printed for this context so far, or the end
+// of it, if there's been more than SetOutputLimit() allows.
+func (r *recorder) Output() string {
+	return r.output.String()
+}
+
+// record reads the results out of what a run printed, then adds it to the
+// kept report, dropping whole lines from the start of it if it's gone past the
+// limit.
+func (r *recorder) record(out string) {
+	r.parse(out)
+	r.output.WriteString(out)
+	limit := r.outLimit
+	if limit == 0 {
+		limit = DefaultOutputLimit
+	}
+	if limit < 0 || r.output.Len() <= limit {
+		return
+	}
+	s := r.output.String()
+	s = s[len(s)-limit:]
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	r.output.Reset()
+	r.output.WriteString(s)
+}
+
+// parse reads the results out of what one run printed, and adds them to those
+// read so far. What a card prints doesn't always say what frequency it's at, so
+// the frequency the last run ended at is carried over to this one.
+func (r *recorder) parse(out string) {
+	if out == "" || r.parseErr != nil {
+		return
+	}
+	text := out
+	if r.lastFreq != 0 {
+		text = "FREQUENCY= " + strconv.FormatFloat(r.lastFreq, 'E', -1, 64) + " MHZ\n" + out
+	}
+	l, tables, err := parseResults(text)
+	if err != nil {
+		r.parseErr = err
+		return
+	}
+	if f, ok := lastFrequency(out); ok {
+		r.lastFreq = f
+	}
+	r.results.Wires = append(r.results.Wires, l.Wires...)
+	r.results.Inputs = append(r.results.Inputs, l.Inputs...)
+	r.results.Patterns = append(r.results.Patterns, l.Patterns...)
+	for _, nf := range l.NearFields {
+		r.results.NearFields = appendNearField(r.results.NearFields, nf)
+	}
+	r.results.Power = append(r.results.Power, l.Power...)
+	r.currents = append(r.currents, tables...)
+}
+
+// impedanceSweep gets the impedance at each frequency of the sweep, using the
+// engine's own Impedance().
+func (r *recorder) impedanceSweep(impedance func(freqIndex int) (complex128, error)) ([]ImpedancePoint, error) {
+	freqs := r.Frequencies()
+	if freqs == nil {
+		return nil, errors.New("no frequencies have been set with FrCard")
+	}
+	sweep := make([]ImpedancePoint, len(freqs))
+	for i, f := range freqs {
+		z, err := impedance(i)
+		if err != nil {
+			return nil, fmt.Errorf("frequency %g MHz: %s", f, err.Error())
+		}
+		sweep[i] = ImpedancePoint{FreqMHz: f, Impedance: z}
+	}
+	return sweep, nil
+}
+
+// radiationPattern fills in a recorded pattern, point by point, using the
+// engine's own Gain().
+func (r *recorder) radiationPattern(freqIndex int, gain func(freqIndex int, thetaIndex int, phiIndex int) (float64, error)) (*RadiationPattern, error) {
+	if freqIndex < 0 || freqIndex >= len(r.patterns) {
+		return nil, fmt.Errorf("radiation pattern %d has not been calculated", freqIndex)
+	}
+	p := newRadiationPattern(r.patterns[freqIndex])
+	for i := range p.Points {
+		g, err := gain(freqIndex, i%p.NTheta, i/p.NTheta)
+		if err != nil {
+			return nil, err
+		}
+		p.Points[i].Total = g
+	}
+	return p, nil
+}
+
+func (r *recorder) radiationPatterns(gain func(freqIndex int, thetaIndex int, phiIndex int) (float64, error)) ([]*RadiationPattern, error) {
+	patterns := make([]*RadiationPattern, len(r.patterns))
+	for i := range r.patterns {
+		p, err := r.radiationPattern(i, gain)
+		if err != nil {
+			return nil, err
+		}
+		patterns[i] = p
+	}
+	return patterns, nil
+}
@@ -0,0 +1,744 @@
+package necpp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/cmplx"
+	"strings"
+)
+
+// defaultFreqMHz is the frequency NEC uses when there's no FR card.
+const defaultFreqMHz = 299.8
+
+// Solver is an Engine written in pure Go, for straight thin wire structures in
+// free space or over a perfect ground. It needs neither libnecpp nor cgo, so
+// programs using it can be cross compiled like any other Go program.
+//
+// The structure is broken up into the same segments NEC would use, and solved
+// with the method of moments, using triangle basis functions on the segments
+// and Galerkin testing of the thin wire electric field integral equation.
+// Results should agree closely with NEC's for the structures NEC handles well
+// (segments much shorter than a wavelength, and several times longer than the
+// wire is thick), but won't be identical, as NEC's current expansion differs.
+//
+// That's not the simplest method of moments for wires, pulse basis functions
+// point matched against Pocklington's equation, but that converges slowly,
+// needing many more segments than NEC would for the same accuracy. Triangle
+// bases with Galerkin testing let the equation be put in its mixed potential
+// form, where the worst singularity is 1/R and can be integrated exactly, so
+// the solver gets close to NEC's answers with NEC's own segmentation. The
+// pulse basis can still be had with SetBasis(), to check one against the other
+// or to compare with other programs that use it.
+//
+// What it can model:
+//
+// • Wires, including tapered wires and the copies made by GM and GX cards.
+// Wires are joined wherever their ends meet.
+//
+// • Free space, the medium set with MediumParameters(), and a perfect ground
+// (GN card type Perfect, or a GE card with a ground plane and no GN card).
+//
+// • Voltage sources, on EX cards or ExcitationVoltage(). The impedance is that
+// of the first one.
+//
+// • Loads of every LD card type, lumped at the middle of each segment. Wire
+// conductivity (type 5) uses the skin effect resistance of a round wire.
+//
+// • Radiation patterns from RP and XQ cards, in the Normal mode, with power or
+// directive gain.
+//
+// Cards for anything else (surface patches, transmission lines and networks,
+// finite grounds, plane wave and current source excitations, near fields)
+// return an error. Cards that only change what NEC prints (PT, PQ, KH and the
+// like) are accepted and ignored, as is EK, since the reduced kernel is used
+// throughout.
+//
+// Solver doesn't print anything, but does write a report in the style of NEC's
+// output for each run, which can be had from Output() and read with Listing()
+// and the methods built on it. It's also written to the writer given to
+// SetOutput(), if there is one.
+type Solver struct {
+	recorder
+	ground    GroundTypeFlag
+	groundSet bool // a GN card has been given
+	gpflag    GeoGroundPlaneFlag
+	eps       float64
+	mu        float64
+	basis     SolverBasis
+
+	solved    map[float64]*momSolution // solutions since the model last changed
+	solutions []*momSolution           // by the index Impedance() uses
+	gains     []*RadiationPattern      // by the index Gain() uses
+}
+
+// NewSolver makes a new pure Go solver, with the antenna in free space.
+func NewSolver() *Solver {
+	return &Solver{ground: Nullified, eps: eps0, mu: mu0}
+}
+
+// SolverBasis is the kind of basis functions a Solver expands the current on
+// the wires in.
+//
+// • TriangleBasis - triangle functions, tested with the same functions
+// (Galerkin's method) against the mixed potential form of Pocklington's
+// equation. This is the default.
+//
+// • PulseBasis - pulse functions, point matched against Pocklington's
+// equation, with its derivatives taken as differences between the middles of
+// segments.
+type SolverBasis int
+
+const (
+	TriangleBasis SolverBasis = iota
+	PulseBasis
+)
+
+// SetBasis sets the basis functions the solver uses from the next run on.
+func (s *Solver) SetBasis(b SolverBasis) {
+	s.basis = b
+	s.changed()
+}
+
+// unsupported is the error for a card the solver can't model.
+func unsupported(what string) error {
+	return fmt.Errorf("the pure Go solver can't model %s", what)
+}
+
+// changed throws away the solutions worked out for the model as it was.
+func (s *Solver) changed() {
+	s.solved = nil
+}
+
+// Delete does nothing, as there's nothing to free.
+func (s *Solver) Delete() error {
+	return nil
+}
+
+// Wire adds a straight wire. See NecppCtx.Wire().
+func (s *Solver) Wire(tagId int, segmentCount int, xw1 float64, yw1 float64, zw1 float64, xw2 float64, yw2 float64, zw2 float64, rad float64, rdel float64, rrad float64) error {
+	if segmentCount < 1 {
+		return fmt.Errorf("wire with tag %d has no segments", tagId)
+	}
+	if rad <= 0 {
+		return fmt.Errorf("wire with tag %d has no radius", tagId)
+	}
+	s.wire(WireSpec{Tag: tagId, Segments: segmentCount, X1: xw1, Y1: yw1, Z1: zw1, X2: xw2, Y2: yw2, Z2: zw2, Radius: rad, RDel: rdel, RRad: rrad})
+	s.changed()
+	return nil
+}
+
+// SpCard returns an error, as surface patches aren't supported.
+func (s *Solver) SpCard(ns PatchType, x1 float64, y1 float64, z1 float64, x2 float64, y2 float64, z2 float64) error {
+	return unsupported("surface patches")
+}
+
+// ScCard returns an error, as surface patches aren't supported.
+func (s *Solver) ScCard(i2 int, x3 float64, y3 float64, z3 float64, x4 float64, y4 float64, z4 float64) error {
+	return unsupported("surface patches")
+}
+
+// GmCard moves or copies part of the structure. See NecppCtx.GmCard().
+func (s *Solver) GmCard(itsi int, nrpt int, rox float64, roy float64, roz float64, xs float64, ys float64, zs float64, its int) error {
+	s.geom.move(itsi, nrpt, rox, roy, roz, xs, ys, zs, its)
+	s.changed()
+	return nil
+}
+
+// GxCard reflects the structure. See NecppCtx.GxCard().
+func (s *Solver) GxCard(i1 int, i2 int) error {
+	s.geom.reflect(i1, i2)
+	s.changed()
+	return nil
+}
+
+// GeometryComplete ends the geometry. A ground plane flag other than
+// NoGroundPlane puts the antenna over a perfect ground, unless a GN card says
+// otherwise.
+func (s *Solver) GeometryComplete(gpflag GeoGroundPlaneFlag) error {
+	s.gpflag = gpflag
+	s.changed()
+	return nil
+}
+
+// MediumParameters sets the permittivity and permeability of the medium the
+// antenna is in.
+func (s *Solver) MediumParameters(permittivity float64, permeability float64) error {
+	if permittivity <= 0 || permeability <= 0 {
+		return errors.New("the medium's permittivity and permeability must be positive")
+	}
+	s.eps, s.mu = permittivity, permeability
+	s.changed()
+	return nil
+}
+
+// GnCard sets the ground, which can only be Perfect, or Nullified for free
+// space.
+func (s *Solver) GnCard(iperf GroundTypeFlag, nradl int, epse float64, sig float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	switch iperf {
+	case Perfect, Nullified:
+	default:
+		return unsupported("a finite ground")
+	}
+	if nradl > 0 {
+		return unsupported("a radial ground screen")
+	}
+	s.ground, s.groundSet = iperf, true
+	s.changed()
+	return nil
+}
+
+// GdCard returns an error, as a second ground medium isn't supported.
+func (s *Solver) GdCard(tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64) error {
+	return unsupported("a second ground medium")
+}
+
+// SetGround makes the GN card for a ground, which must be a perfect one.
+func (s *Solver) SetGround(g Ground) error {
+	return setGround(s, g)
+}
+
+// FrCard sets the frequencies. See NecppCtx.FrCard().
+func (s *Solver) FrCard(inIfrq FrequencyRange, inNfrq int, inFreqMhz float64, inDelFreq float64) error {
+	if inFreqMhz <= 0 {
+		return errors.New("the frequency must be positive")
+	}
+	s.frCard(inIfrq, inNfrq, inFreqMhz, inDelFreq)
+	return nil
+}
+
+// EkCard is accepted, but makes no difference.
+func (s *Solver) EkCard(itmp1 WireKernel) error {
+	return nil
+}
+
+// LdCard loads segments. See NecppCtx.LdCard().
+func (s *Solver) LdCard(ldtype int, ldtag int, ldtagf int, ldtagt int, tmp1 float64, tmp2 float64, tmp3 float64) error {
+	if ldtype < -1 || ldtype > 5 {
+		return fmt.Errorf("%d isn't a load type", ldtype)
+	}
+	s.ldCard(ldtype, ldtag, ldtagf, ldtagt, tmp1, tmp2, tmp3)
+	s.changed()
+	return nil
+}
+
+// ExCard applies an excitation, which must be a voltage source.
+func (s *Solver) ExCard(extype Excitation, i2 int, i3 int, i4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	if extype != VoltageApplied && extype != VoltageSlope {
+		return unsupported("excitations other than voltage sources")
+	}
+	s.feed(i2, i3, complex(tmp1, tmp2))
+	s.changed()
+	return nil
+}
+
+// ExcitationVoltage puts a voltage source on a segment.
+func (s *Solver) ExcitationVoltage(tag int, segment int, voltageExcitation complex128) error {
+	s.feed(tag, segment, voltageExcitation)
+	s.changed()
+	return nil
+}
+
+// ExcitationCurrent returns an error, as current sources aren't supported.
+func (s *Solver) ExcitationCurrent(x float64, y float64, z float64, a float64, beta float64, moment float64) error {
+	return unsupported("current source excitations")
+}
+
+// ExcitationPlanewave returns an error, as plane waves aren't supported.
+func (s *Solver) ExcitationPlanewave(nTheta int, nPhi int, theta float64, phi float64, eta float64, dTheta float64, dPhi float64, polRatio float64) error {
+	return unsupported("plane wave excitations")
+}
+
+// TlCard returns an error, as transmission lines aren't supported.
+func (s *Solver) TlCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	return unsupported("transmission lines")
+}
+
+// NtCard returns an error, as networks aren't supported.
+func (s *Solver) NtCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	return unsupported("networks")
+}
+
+// ConnectTwoPort returns an error, as networks aren't supported.
+func (s *Solver) ConnectTwoPort(p1 Port, p2 Port, t TwoPort) error {
+	return connectTwoPort(s, p1, p2, t)
+}
+
+// XqCard solves the model at each frequency of the run, and works out the
+// vertical pattern cuts asked for, if any.
+func (s *Solver) XqCard(itmp1 ExecutionOption) error {
+	var grids []patternGrid
+	xz := patternGrid{nTheta: 91, nPhi: 1, dTheta: 1}
+	yz := patternGrid{nTheta: 91, nPhi: 1, phi0: 90, dTheta: 1}
+	switch itmp1 {
+	case XZPlane:
+		grids = append(grids, xz)
+	case YZPlane:
+		grids = append(grids, yz)
+	case BothPlane:
+		grids = append(grids, xz, yz)
+	}
+	if err := s.execute(grids, VerticalHorizontal, PowerGain, 0); err != nil {
+		return err
+	}
+	s.xqCard(itmp1)
+	return nil
+}
+
+// RpCard solves the model at each frequency of the run, and works out the
+// radiation pattern. Only the Normal calculation mode is supported, and
+// normalization and averaging are ignored.
+func (s *Solver) RpCard(calcMode RpCalcMode, nTheta int, nPhi int, outputFormat RpOutputFormat, normalization RpNormalization, d RpGain, a RpAveraging, theta0 float64, phi0 float64, deltaTheta float64, deltaPhi float64, radialDistance float64, gainNorm float64) error {
+	if calcMode != Normal {
+		return unsupported("radiation patterns other than the Normal mode")
+	}
+	g := patternGrid{nTheta: nTheta, nPhi: nPhi, theta0: theta0, phi0: phi0, dTheta: deltaTheta, dPhi: deltaPhi}
+	if err := s.execute([]patternGrid{g}, outputFormat, d, radialDistance); err != nil {
+		return err
+	}
+	s.recordPatterns(g)
+	return nil
+}
+
+// PtCard is accepted, but makes no difference.
+func (s *Solver) PtCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	return nil
+}
+
+// PqCard is accepted, but makes no difference.
+func (s *Solver) PqCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	return nil
+}
+
+// KhCard is accepted, but makes no difference.
+func (s *Solver) KhCard(tmp1 float64) error {
+	return nil
+}
+
+// NeCard returns an error, as near fields aren't supported.
+func (s *Solver) NeCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	return unsupported("near fields")
+}
+
+// NhCard returns an error, as near fields aren't supported.
+func (s *Solver) NhCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int, tmp1 float64, tmp2 float64, tmp3 float64, tmp4 float64, tmp5 float64, tmp6 float64) error {
+	return unsupported("near fields")
+}
+
+// CpCard is accepted, but makes no difference.
+func (s *Solver) CpCard(itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	return nil
+}
+
+// PlCard returns an error, as plot files aren't written.
+func (s *Solver) PlCard(ploutputFilename string, itmp1 int, itmp2 int, itmp3 int, itmp4 int) error {
+	return unsupported("plot files")
+}
+
+// perfectGround says whether the antenna is over a perfect ground.
+func (s *Solver) perfectGround() bool {
+	if s.groundSet {
+		return s.ground == Perfect
+	}
+	return s.gpflag != NoGroundPlane
+}
+
+// execute runs the simulation at each frequency of the run, working out a
+// pattern over each grid, and writes the report.
+func (s *Solver) execute(grids []patternGrid, format RpOutputFormat, d RpGain, distance float64) error {
+	var report strings.Builder
+	for _, f := range s.runFrequencies() {
+		if f == 0 {
+			f = defaultFreqMHz
+		}
+		sol, err := s.solve(f)
+		if err != nil {
+			return err
+		}
+		s.solutions = append(s.solutions, sol)
+		writeSolution(&report, sol)
+		for _, g := range grids {
+			g.freqMHz = f
+			p := sol.pattern(g, d)
+			s.gains = append(s.gains, p)
+			writePattern(&report, sol, p, format, distance)
+		}
+	}
+	s.record(report.String())
+	if s.stdout != nil {
+		io.WriteString(s.stdout, report.String())
+	}
+	return nil
+}
+
+// solve works out the currents at one frequency.
+func (s *Solver) solve(freqMHz float64) (*momSolution, error) {
+	if sol, ok := s.solved[freqMHz]; ok {
+		return sol, nil
+	}
+	if len(s.geom.Feeds) == 0 {
+		return nil, errors.New("the model has no voltage sources")
+	}
+	segs := s.geom.Segments()
+	sys, err := newMomSystem(segs, s.perfectGround())
+	if err != nil {
+		return nil, err
+	}
+	sys.pulse = s.basis == PulseBasis
+	med := newMomMedium(freqMHz, s.eps, s.mu)
+
+	var sources []momSource
+	for _, fd := range s.geom.Feeds {
+		sg, ok := s.geom.FindSegment(fd.Port)
+		if !ok {
+			return nil, fmt.Errorf("there's no segment %d with tag %d for the source", fd.Segment, fd.Tag)
+		}
+		sources = append(sources, momSource{seg: sg.Number - 1, tag: sg.Tag, voltage: fd.Voltage})
+	}
+	loads, err := s.loadImpedances(segs, med)
+	if err != nil {
+		return nil, err
+	}
+
+	sol, err := sys.solve(med, loads, sources)
+	if err != nil {
+		return nil, err
+	}
+	sol.freqMHz = freqMHz
+	if s.solved == nil {
+		s.solved = make(map[float64]*momSolution)
+	}
+	s.solved[freqMHz] = sol
+	return sol, nil
+}
+
+// loadImpedances works out the impedance loading each segment at a frequency.
+// Loads on the same segment are in series, and a load type of -1 clears all of
+// the loads before it.
+func (s *Solver) loadImpedances(segs []Segment, med momMedium) ([]complex128, error) {
+	z := make([]complex128, len(segs))
+	for _, ld := range s.geom.Loads {
+		if ld.Type == -1 {
+			z = make([]complex128, len(segs))
+			continue
+		}
+		found := false
+		for i, sg := range segs {
+			if !ld.applies(sg) {
+				continue
+			}
+			found = true
+			z[i] += ld.impedance(sg, med)
+		}
+		if !found {
+			return nil, fmt.Errorf("there are no segments for the load on tag %d segments %d to %d", ld.Tag, ld.From, ld.To)
+		}
+	}
+	return z, nil
+}
+
+// applies says whether a load is on a segment.
+func (ld Load) applies(sg Segment) bool {
+	switch {
+	case ld.Tag == 0 && ld.From == 0:
+		return true
+	case ld.Tag == 0:
+		return sg.Number >= ld.From && sg.Number <= ld.To
+	case ld.From == 0:
+		return sg.Tag == ld.Tag
+	}
+	return sg.Tag == ld.Tag && sg.TagIndex >= ld.From && sg.TagIndex <= ld.To
+}
+
+// impedance works out a load's impedance on a segment.
+func (ld Load) impedance(sg Segment, med momMedium) complex128 {
+	w := med.omega
+	l := sg.Length()
+	series := func(r float64, ind float64, c float64) complex128 {
+		z := complex(r, w*ind)
+		if c != 0 {
+			z += complex(0, -1/(w*c))
+		}
+		return z
+	}
+	parallel := func(r float64, ind float64, c float64) complex128 {
+		y := complex(0, w*c)
+		if r != 0 {
+			y += complex(1/r, 0)
+		}
+		if ind != 0 {
+			y += complex(0, -1/(w*ind))
+		}
+		if y == 0 {
+			return 0
+		}
+		return 1 / y
+	}
+	switch ld.Type {
+	case 0:
+		return series(ld.R, ld.L, ld.C)
+	case 1:
+		return parallel(ld.R, ld.L, ld.C)
+	case 2:
+		return series(ld.R*l, ld.L*l, ld.C/l)
+	case 3:
+		return parallel(ld.R*l, ld.L*l, ld.C/l)
+	case 4:
+		return complex(ld.R, ld.L)
+	case 5:
+		if ld.R <= 0 {
+			return 0
+		}
+		a := sg.Radius
+		depth := math.Sqrt(2 / (w * mu0 * ld.R))
+		if depth >= a {
+			return complex(l/(ld.R*math.Pi*a*a), 0)
+		}
+		r := l / (ld.R * 2 * math.Pi * a * depth)
+		return complex(r, r)
+	}
+	return 0
+}
+
+// pattern works out the gain over a grid of directions.
+func (sol *momSolution) pattern(g patternGrid, d RpGain) *RadiationPattern {
+	power := sol.inputPower()
+	if d == DirectiveGain {
+		power -= sol.loss()
+	}
+	p := newRadiationPattern(g)
+	p.Polarized = true
+	for i := range p.Points {
+		pt := &p.Points[i]
+		eTheta, ePhi := sol.farField(pt.Theta, pt.Phi)
+		rhcp := (eTheta + complex(0, 1)*ePhi) / complex(math.Sqrt2, 0)
+		lhcp := (eTheta - complex(0, 1)*ePhi) / complex(math.Sqrt2, 0)
+		pt.Vertical = gainDB(sol.gain(eTheta, power))
+		pt.Horizontal = gainDB(sol.gain(ePhi, power))
+		pt.RHCP = gainDB(sol.gain(rhcp, power))
+		pt.LHCP = gainDB(sol.gain(lhcp, power))
+		pt.Total = gainDB(sol.gain(eTheta, power) + sol.gain(ePhi, power))
+	}
+	return p
+}
+
+// gainDB turns a gain ratio into dB, using NEC's -999.99 for no gain.
+func gainDB(g float64) float64 {
+	if g <= 0 {
+		return -999.99
+	}
+	return math.Max(10*math.Log10(g), -999.99)
+}
+
+// Gain returns the gain, in dBi, at a point of a radiation pattern. See
+// NecppCtx.Gain().
+func (s *Solver) Gain(freqIndex int, thetaIndex int, phiIndex int) (float64, error) {
+	if freqIndex < 0 || freqIndex >= len(s.gains) {
+		return GainErrno, ErrNoPatternRequested
+	}
+	p := s.gains[freqIndex]
+	if thetaIndex < 0 || thetaIndex >= p.NTheta || phiIndex < 0 || phiIndex >= p.NPhi {
+		return GainErrno, fmt.Errorf("point (%d, %d) is outside the radiation pattern", thetaIndex, phiIndex)
+	}
+	return p.Point(thetaIndex, phiIndex).Total, nil
+}
+
+// gainStat works out one of the statistics of a pattern's gains.
+func (s *Solver) gainStat(freqIndex int, gain func(PatternPoint) float64, stat func([]float64) float64) (float64, error) {
+	if freqIndex < 0 || freqIndex >= len(s.gains) {
+		return GainErrno, ErrNoPatternRequested
+	}
+	pts := s.gains[freqIndex].Points
+	g := make([]float64, len(pts))
+	for i, pt := range pts {
+		g[i] = gain(pt)
+	}
+	return stat(g), nil
+}
+
+func totalGain(pt PatternPoint) float64 { return pt.Total }
+func rhcpGain(pt PatternPoint) float64  { return pt.RHCP }
+func lhcpGain(pt PatternPoint) float64  { return pt.LHCP }
+
+// GainMax returns the largest gain of a radiation pattern.
+func (s *Solver) GainMax(freqIndex int) (float64, error) {
+	return s.gainStat(freqIndex, totalGain, maxGain)
+}
+
+// GainMin returns the smallest gain of a radiation pattern.
+func (s *Solver) GainMin(freqIndex int) (float64, error) {
+	return s.gainStat(freqIndex, totalGain, minGain)
+}
+
+// GainMean returns the mean gain of a radiation pattern, taken over the gains
+// in dB as they are. libnecpp weights its statistics differently, so GainMean()
+// and GainSd() won't agree with NecppCtx's.
+func (s *Solver) GainMean(freqIndex int) (float64, error) {
+	return s.gainStat(freqIndex, totalGain, meanGain)
+}
+
+// GainSd returns the standard deviation of the gain of a radiation pattern.
+func (s *Solver) GainSd(freqIndex int) (float64, error) {
+	return s.gainStat(freqIndex, totalGain, sdGain)
+}
+
+func (s *Solver) GainRhcpMax(freqIndex int) (float64, error) {
+	return s.gainStat(freqIndex, rhcpGain, maxGain)
+}
+
+func (s *Solver) GainRhcpMin(freqIndex int) (float64, error) {
+	return s.gainStat(freqIndex, rhcpGain, minGain)
+}
+
+func (s *Solver) GainRhcpMean(freqIndex int) (float64, error) {
+	return s.gainStat(freqIndex, rhcpGain, meanGain)
+}
+
+func (s *Solver) GainRhcpSd(freqIndex int) (float64, error) {
+	return s.gainStat(freqIndex, rhcpGain, sdGain)
+}
+
+func (s *Solver) GainLhcpMax(freqIndex int) (float64, error) {
+	return s.gainStat(freqIndex, lhcpGain, maxGain)
+}
+
+func (s *Solver) GainLhcpMin(freqIndex int) (float64, error) {
+	return s.gainStat(freqIndex, lhcpGain, minGain)
+}
+
+func (s *Solver) GainLhcpMean(freqIndex int) (float64, error) {
+	return s.gainStat(freqIndex, lhcpGain, meanGain)
+}
+
+func (s *Solver) GainLhcpSd(freqIndex int) (float64, error) {
+	return s.gainStat(freqIndex, lhcpGain, sdGain)
+}
+
+// Impedance returns the impedance at the first voltage source. See
+// NecppCtx.Impedance().
+func (s *Solver) Impedance(freqIndex int) (complex128, error) {
+	if freqIndex < 0 || freqIndex >= len(s.solutions) {
+		return complex(GainErrno, GainErrno), fmt.Errorf("no simulation has been run for index %d", freqIndex)
+	}
+	return s.solutions[freqIndex].sources[0].impedance(), nil
+}
+
+// ImpedanceSweep returns the impedance at each of the frequencies of the FR
+// card.
+func (s *Solver) ImpedanceSweep() ([]ImpedancePoint, error) {
+	return s.impedanceSweep(s.Impedance)
+}
+
+// RadiationPattern returns a radiation pattern, with the gains of each
+// polarization filled in.
+func (s *Solver) RadiationPattern(freqIndex int) (*RadiationPattern, error) {
+	if freqIndex < 0 || freqIndex >= len(s.gains) {
+		return nil, fmt.Errorf("radiation pattern %d has not been calculated", freqIndex)
+	}
+	p := *s.gains[freqIndex]
+	p.Points = append([]PatternPoint(nil), p.Points...)
+	return &p, nil
+}
+
+// RadiationPatterns returns all of the radiation patterns calculated so far.
+func (s *Solver) RadiationPatterns() ([]*RadiationPattern, error) {
+	patterns := make([]*RadiationPattern, len(s.gains))
+	for i := range s.gains {
+		p, err := s.RadiationPattern(i)
+		if err != nil {
+			return nil, err
+		}
+		patterns[i] = p
+	}
+	return patterns, nil
+}
+
+// writeSolution writes the frequency, input parameters, currents and power
+// budget of a solution the way NEC prints them.
+func writeSolution(w io.Writer, sol *momSolution) {
+	lambda := SpeedOfLight / sol.freqMHz
+	fmt.Fprintf(w, "\n\n                               - - - - - - FREQUENCY - - - - - -\n\n")
+	fmt.Fprintf(w, "                                    FREQUENCY= %11.4E MHZ\n", sol.freqMHz)
+	fmt.Fprintf(w, "                                    WAVELENGTH= %11.4E METERS\n", lambda)
+
+	fmt.Fprintf(w, "\n\n                        - - - ANTENNA INPUT PARAMETERS - - -\n\n")
+	fmt.Fprintf(w, "  TAG   SEG.   VOLTAGE (VOLTS)            CURRENT (AMPS)             IMPEDANCE (OHMS)           ADMITTANCE (MHOS)          POWER\n")
+	fmt.Fprintf(w, "  NO.   NO.    REAL         IMAG.         REAL         IMAG.         REAL         IMAG.         REAL         IMAG.         (WATTS)\n")
+	for _, src := range sol.sources {
+		z := src.impedance()
+		y := 1 / z
+		p := 0.5 * real(src.voltage*cmplx.Conj(src.current))
+		fmt.Fprintf(w, " %4d %5d %13.5E %13.5E %13.5E %13.5E %13.5E %13.5E %13.5E %13.5E %13.5E\n", src.tag, src.seg+1,
+			real(src.voltage), imag(src.voltage), real(src.current), imag(src.current), real(z), imag(z), real(y), imag(y), p)
+	}
+
+	fmt.Fprintf(w, "\n\n                           - - - CURRENTS AND LOCATION - - -\n\n")
+	fmt.Fprintf(w, "                              DISTANCES IN WAVELENGTHS\n\n")
+	fmt.Fprintf(w, "   SEG.  TAG    COORD. OF SEG. CENTER     SEG.            - - - CURRENT (AMPS) - - -\n")
+	fmt.Fprintf(w, "   NO.   NO.     X         Y         Z     LENGTH     REAL        IMAG.       MAG.        PHASE\n")
+	for i, sg := range sol.sys.segs {
+		c := sg.Center()
+		cur := sol.center[i]
+		fmt.Fprintf(w, " %5d %5d %9.4f %9.4f %9.4f %9.5f %11.4E %11.4E %11.4E %8.3f\n", sg.Number, sg.Tag,
+			c[0]/lambda, c[1]/lambda, c[2]/lambda, sg.length/lambda, real(cur), imag(cur), cmplx.Abs(cur), cmplx.Phase(cur)*180/math.Pi)
+	}
+
+	in, loss := sol.inputPower(), sol.loss()
+	eff := 0.0
+	if in > 0 {
+		eff = 100 * (in - loss) / in
+	}
+	fmt.Fprintf(w, "\n\n                               - - - POWER BUDGET - - -\n\n")
+	fmt.Fprintf(w, "                               INPUT POWER   = %11.4E WATTS\n", in)
+	fmt.Fprintf(w, "                               RADIATED POWER= %11.4E WATTS\n", in-loss)
+	fmt.Fprintf(w, "                               STRUCTURE LOSS= %11.4E WATTS\n", loss)
+	fmt.Fprintf(w, "                               NETWORK LOSS  = %11.4E WATTS\n", 0.0)
+	fmt.Fprintf(w, "                               EFFICIENCY    = %7.2f PERCENT\n", eff)
+}
+
+// writePattern writes a radiation pattern the way NEC prints it. The fields
+// are given at distance, or as the field times the distance if it's zero.
+func writePattern(w io.Writer, sol *momSolution, p *RadiationPattern, format RpOutputFormat, distance float64) {
+	if distance <= 0 {
+		distance = 1
+	}
+	heads := "VERTC    HORIZ    TOTAL "
+	if format == MajorMinor {
+		heads = "MAJOR    MINOR    TOTAL "
+	}
+	fmt.Fprintf(w, "\n\n                               - - - RADIATION PATTERNS - - -\n\n")
+	fmt.Fprintf(w, "  - - ANGLES - -           - POWER GAINS -        - - - POLARIZATION - - -   - - - E(THETA) - - -    - - - E(PHI) - - -\n")
+	fmt.Fprintf(w, "  THETA     PHI        %s     AXIAL      TILT  SENSE   MAGNITUDE    PHASE     MAGNITUDE    PHASE\n", heads)
+	fmt.Fprintf(w, " DEGREES  DEGREES        DB       DB       DB        RATIO      DEG.            VOLTS/M   DEGREES     VOLTS/M   DEGREES\n")
+	for _, pt := range p.Points {
+		nTheta, nPhi := sol.farField(pt.Theta, pt.Phi)
+		eTheta, ePhi := sol.fieldStrength(nTheta, distance), sol.fieldStrength(nPhi, distance)
+		g1, g2 := pt.Vertical, pt.Horizontal
+		a2, b2 := cmplx.Abs(eTheta)*cmplx.Abs(eTheta), cmplx.Abs(ePhi)*cmplx.Abs(ePhi)
+		cross := cmplx.Abs(eTheta*eTheta + ePhi*ePhi)
+		major, minor := math.Sqrt((a2+b2+cross)/2), math.Sqrt(math.Max(0, (a2+b2-cross)/2))
+		if format == MajorMinor {
+			total := a2 + b2
+			g1 = gainDB(math.Pow(10, pt.Total/10) * major * major / total)
+			g2 = gainDB(math.Pow(10, pt.Total/10) * minor * minor / total)
+			if total == 0 {
+				g1, g2 = -999.99, -999.99
+			}
+		}
+		ratio := 0.0
+		if major > 0 {
+			ratio = minor / major
+		}
+		tilt := 0.5 * math.Atan2(2*real(eTheta*cmplx.Conj(ePhi)), a2-b2) * 180 / math.Pi
+		sense := "LINEAR"
+		if ratio > 1e-5 {
+			sense = "LEFT"
+			if imag(cmplx.Conj(eTheta)*ePhi) < 0 {
+				sense = "RIGHT"
+			}
+		}
+		fmt.Fprintf(w, " %8.2f %8.2f %9.2f%9.2f%9.2f %10.5f %9.2f %-6s %11.5E %8.2f %11.5E %8.2f\n", pt.Theta, pt.Phi, g1, g2, pt.Total,
+			ratio, tilt, sense, cmplx.Abs(eTheta), cmplx.Phase(eTheta)*180/math.Pi, cmplx.Abs(ePhi), cmplx.Phase(ePhi)*180/math.Pi)
+	}
+}
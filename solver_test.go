@@ -0,0 +1,225 @@
+package necpp
+
+import (
+	"context"
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+// radiatedFraction integrates a pattern's power gain over the directions it
+// covers, giving the fraction of the input power radiated through them.
+func radiatedFraction(p *RadiationPattern, dTheta float64, dPhi float64) float64 {
+	var sum float64
+	for _, pt := range p.Points {
+		sum += math.Pow(10, pt.Total/10) * math.Sin(pt.Theta*math.Pi/180)
+	}
+	return sum * (dTheta * math.Pi / 180) * (dPhi * math.Pi / 180) / (4 * math.Pi)
+}
+
+// The simple antenna's gains as libnecpp works them out, from
+// TestSimpleAntenna, so the solver can be checked against them without cgo.
+const (
+	libnecppSimpleMaxGain = 8.407404
+	libnecppSimpleMinGain = -999.99
+)
+
+// TestSolverSimpleAntenna runs TestSimpleAntenna's deck on the solver, with
+// each basis.
+func TestSolverSimpleAntenna(t *testing.T) {
+	for _, b := range []SolverBasis{TriangleBasis, PulseBasis} {
+		s := NewSolver()
+		s.SetBasis(b)
+		s.Wire(0, 9, 0, 0, 2, 0, 0, 7, 0.1, 1, 1)
+		s.GeometryComplete(CurrentExpansionModified)
+		s.GnCard(Perfect, 0, 0, 0, 0, 0, 0, 0)
+		s.FrCard(Linear, 1, 30, 0)
+		s.ExCard(VoltageApplied, 0, 5, 0, 1.0, 0, 0, 0, 0, 0)
+		if err := s.RpCard(Normal, 90, 1, MajorMinor, TotalNormalized, PowerGain, NoAvg, 0, 90, 1, 0, 0, 0); err != nil {
+			t.Fatal(err)
+		}
+		max, err := s.GainMax(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(max-libnecppSimpleMaxGain) > 0.025 {
+			t.Errorf("basis %d: max gain was %f, libnecpp gives %f", b, max, libnecppSimpleMaxGain)
+		}
+		if min, _ := s.GainMin(0); min != libnecppSimpleMinGain {
+			t.Errorf("basis %d: min gain was %f, libnecpp gives %f", b, min, libnecppSimpleMinGain)
+		}
+		if _, err := s.Impedance(0); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TestSolverPulseBasis checks the pulse basis against the triangle basis on a
+// half wave dipole, where they should both be close to 72 ohms.
+func TestSolverPulseBasis(t *testing.T) {
+	var z [2]complex128
+	for i, b := range []SolverBasis{TriangleBasis, PulseBasis} {
+		s := NewSolver()
+		s.SetBasis(b)
+		s.Wire(1, 41, 0, 0, -2.5, 0, 0, 2.5, 0.001, 1, 1)
+		s.GeometryComplete(NoGroundPlane)
+		s.FrCard(Linear, 1, 29, 0)
+		s.ExCard(VoltageApplied, 1, 21, 0, 1.0, 0, 0, 0, 0, 0)
+		if err := s.XqCard(NoPattern); err != nil {
+			t.Fatal(err)
+		}
+		z[i], _ = s.Impedance(0)
+	}
+	if d := cmplx.Abs(z[1] - z[0]); d > 0.05*cmplx.Abs(z[0]) {
+		t.Errorf("the pulse basis gave %v, the triangle basis %v", z[1], z[0])
+	}
+	if real(z[1]) < 65 || real(z[1]) > 80 || math.Abs(imag(z[1])) > 10 {
+		t.Errorf("the pulse basis gave %v, should have been about 72 ohms", z[1])
+	}
+}
+
+func TestSolverDipole(t *testing.T) {
+	s := NewSolver()
+	s.Wire(1, 21, 0, 0, -2.5, 0, 0, 2.5, 0.001, 1, 1)
+	s.GeometryComplete(NoGroundPlane)
+	s.FrCard(Linear, 3, 28, 1)
+	s.ExCard(VoltageApplied, 1, 11, 0, 1.0, 0, 0, 0, 0, 0)
+	if err := s.RpCard(Normal, 180, 36, VerticalHorizontal, NoNormalization, PowerGain, NoAvg, 0.5, 0, 1, 10, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	sweep, err := s.ImpedanceSweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sweep) != 3 {
+		t.Fatalf("got %d impedances, should have been 3", len(sweep))
+	}
+	// a half wave dipole is resonant near 29 MHz, with the reactance rising
+	// with frequency
+	z := sweep[1].Impedance
+	if real(z) < 65 || real(z) > 80 || math.Abs(imag(z)) > 10 {
+		t.Errorf("impedance at %g MHz was %v, should have been about 73 ohms", sweep[1].FreqMHz, z)
+	}
+	if imag(sweep[0].Impedance) > 0 || imag(sweep[2].Impedance) < 0 {
+		t.Errorf("reactance didn't go from capacitive to inductive: %v", sweep)
+	}
+
+	patterns, err := s.RadiationPatterns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patterns) != 3 {
+		t.Fatalf("got %d patterns, should have been 3", len(patterns))
+	}
+	for _, p := range patterns {
+		if max := p.MaxGain(); math.Abs(max.Total-2.15) > 0.1 || math.Abs(max.Theta-90) > 1 {
+			t.Errorf("max gain at %g MHz was %g dBi at theta %g", p.FreqMHz, max.Total, max.Theta)
+		}
+		if f := radiatedFraction(p, 1, 10); math.Abs(f-1) > 0.01 {
+			t.Errorf("%g of the input power was radiated at %g MHz", f, p.FreqMHz)
+		}
+	}
+	// a vertical wire's field is all vertically polarized
+	if pt := patterns[1].Point(90, 0); pt.Horizontal != -999.99 || math.Abs(pt.Vertical-pt.Total) > 1e-9 {
+		t.Errorf("gains at the horizon were %+v", pt)
+	}
+}
+
+func TestSolverLoads(t *testing.T) {
+	s := NewSolver()
+	s.Wire(1, 21, 0, 0, -2.5, 0, 0, 2.5, 0.001, 1, 1)
+	s.GeometryComplete(NoGroundPlane)
+	s.LdCard(0, 1, 5, 5, 20, 0, 0)
+	s.FrCard(Linear, 1, 29, 0)
+	s.ExCard(VoltageApplied, 1, 11, 0, 1.0, 0, 0, 0, 0, 0)
+	s.RpCard(Normal, 180, 36, VerticalHorizontal, NoNormalization, PowerGain, NoAvg, 0.5, 0, 1, 10, 0, 0)
+	s.RpCard(Normal, 180, 36, VerticalHorizontal, NoNormalization, DirectiveGain, NoAvg, 0.5, 0, 1, 10, 0, 0)
+
+	l, err := s.Listing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Power) != 2 || len(l.Inputs) != 2 || len(l.Currents) != 2 || len(l.Patterns) != 2 {
+		t.Fatalf("listing had %d power budgets, %d input parameters, %d currents and %d patterns", len(l.Power), len(l.Inputs), len(l.Currents), len(l.Patterns))
+	}
+	budget := l.Power[0]
+	if budget.StructureLoss <= 0 || budget.Efficiency >= 100 {
+		t.Errorf("the load didn't lose any power: %+v", budget)
+	}
+	in, err := s.InputPower(0)
+	if err != nil || math.Abs(in-budget.InputPower) > 1e-6*in {
+		t.Errorf("input power was %g, %v, should have been %g", in, err, budget.InputPower)
+	}
+
+	p, _ := s.RadiationPattern(0)
+	if f := radiatedFraction(p, 1, 10); math.Abs(f-budget.Efficiency/100) > 0.01 {
+		t.Errorf("%g of the input power was radiated, should have been %g", f, budget.Efficiency/100)
+	}
+	d, _ := s.RadiationPattern(1)
+	if f := radiatedFraction(d, 1, 10); math.Abs(f-1) > 0.01 {
+		t.Errorf("directive gain integrated to %g, should have been 1", f)
+	}
+}
+
+// TestSolverJunctions runs the seven wire antenna from test-nec, which has a
+// wire grounded at one end and several junctions of three wires.
+func TestSolverJunctions(t *testing.T) {
+	s := NewSolver()
+	s.Wire(1, 9, 0.0, 0.0, 0.0, -0.0166, 0.0045, 0.0714, 0.001, 1.0, 1.0)
+	s.Wire(2, 7, -0.0166, 0.0045, 0.0714, -0.0318, -0.0166, 0.017, 0.001, 1.0, 1.0)
+	s.Wire(3, 7, -0.0318, -0.0166, 0.017, -0.0318, -0.0287, 0.0775, 0.001, 1.0, 1.0)
+	s.Wire(4, 11, -0.0318, -0.0287, 0.0775, -0.0318, 0.0439, 0.014, 0.001, 1.0, 1.0)
+	s.Wire(6, 5, -0.0318, 0.0045, 0.0624, -0.0106, 0.0378, 0.0866, 0.001, 1.0, 1.0)
+	s.Wire(7, 7, -0.0106, 0.0378, 0.0866, -0.0106, 0.0257, 0.023, 0.001, 1.0, 1.0)
+	s.GeometryComplete(CurrentExpansionModified)
+	s.GnCard(Perfect, 0, 0, 0, 0, 0, 0, 0)
+	s.FrCard(Linear, 1, 1600.0, 0.0)
+	s.ExCard(VoltageApplied, 1, 1, 0, 1.0, 0.0, 0.0, 0.0, 0.0, 0.0)
+	if err := s.RpCard(Normal, 90, 72, MajorMinor, TotalNormalized, PowerGain, NoAvg, 0.5, 0, 1, 5, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	p, err := s.RadiationPattern(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the power all goes into the upper half space
+	if f := radiatedFraction(p, 1, 5); math.Abs(f-1) > 0.01 {
+		t.Errorf("%g of the input power was radiated above the ground, should have been all of it", f)
+	}
+	z, _ := s.Impedance(0)
+	if real(z) <= 0 {
+		t.Errorf("impedance was %v", z)
+	}
+}
+
+func TestSolverModel(t *testing.T) {
+	res, err := simpleModel().RunEngine(context.Background(), NewSolver())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Patterns) != 1 || len(res.Impedance) != 1 {
+		t.Fatalf("got %d patterns and %d impedances, should have been one of each", len(res.Patterns), len(res.Impedance))
+	}
+	if max := res.Patterns[0].MaxGain().Total; math.Abs(max-libnecppSimpleMaxGain) > 0.025 {
+		t.Errorf("max gain was %f, libnecpp gives %f", max, libnecppSimpleMaxGain)
+	}
+	if len(res.Listing.Currents) != 1 || len(res.Listing.Currents[0]) != 9 {
+		t.Errorf("the currents weren't in the listing")
+	}
+}
+
+func TestSolverUnsupported(t *testing.T) {
+	s := NewSolver()
+	if err := s.SpCard(Rectangular, 0, 0, 0, 1, 0, 0); err == nil {
+		t.Errorf("a surface patch should have been an error")
+	}
+	if err := s.GnCard(Finite, 0, 13, 0.005, 0, 0, 0, 0); err == nil {
+		t.Errorf("a finite ground should have been an error")
+	}
+	if err := s.NeCard(0, 1, 1, 1, 0, 0, 0, 0, 0, 0); err == nil {
+		t.Errorf("a near field should have been an error")
+	}
+	if err := s.XqCard(NoPattern); err == nil {
+		t.Errorf("running without a source should have been an error")
+	}
+}
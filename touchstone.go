@@ -0,0 +1,349 @@
+package necpp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/cmplx"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TouchstoneFormat is the format the network parameters are written in to a
+// Touchstone file.
+//
+// • MagnitudeAngle - linear magnitude and angle in degrees (MA).
+//
+// • DecibelAngle - magnitude in dB and angle in degrees (DB).
+//
+// • RealImaginary - real and imaginary parts (RI).
+type TouchstoneFormat int
+
+const (
+	MagnitudeAngle TouchstoneFormat = iota
+	DecibelAngle
+	RealImaginary
+)
+
+// String returns the keyword used for the format in a Touchstone option line.
+func (f TouchstoneFormat) String() string {
+	switch f {
+	case MagnitudeAngle:
+		return "MA"
+	case DecibelAngle:
+		return "DB"
+	case RealImaginary:
+		return "RI"
+	}
+	return fmt.Sprintf("TouchstoneFormat(%d)", int(f))
+}
+
+// DefaultReferenceImpedance is the usual reference impedance for Touchstone
+// files, and what a VNA will almost certainly have been calibrated to.
+const DefaultReferenceImpedance float64 = 50.0
+
+// ImpedancePoint is the feed point impedance of the antenna at a single
+// frequency of a sweep.
+type ImpedancePoint struct {
+	FreqMHz   float64
+	Impedance complex128
+}
+
+// NetworkPoint is the port impedance matrix of a multi-port model at a single
+// frequency. Z[i][j] is the open circuit voltage at port i per amp of current
+// into port j.
+type NetworkPoint struct {
+	FreqMHz float64
+	Z       [][]complex128
+}
+
+// WriteS1P writes the impedance sweep to w as a one port Touchstone (.s1p)
+// file, with the reflection coefficient calculated against the reference
+// impedance z0 and written out in the given format. Frequencies are written in
+// MHz.
+func WriteS1P(w io.Writer, sweep []ImpedancePoint, z0 float64, format TouchstoneFormat) error {
+	if z0 <= 0 {
+		return fmt.Errorf("reference impedance must be positive, got %g", z0)
+	}
+	points := make([]touchstonePoint, len(sweep))
+	for i, p := range sweep {
+		s := ReflectionCoefficient(p.Impedance, z0)
+		points[i] = touchstonePoint{freqMHz: p.FreqMHz, s: [][]complex128{{s}}}
+	}
+	return writeTouchstone(w, 1, points, z0, format)
+}
+
+// WriteS2P writes the port impedance matrices of a two port model to w as a
+// two port Touchstone (.s2p) file. The S-parameters are calculated with the
+// reference impedance z0 on both ports, and written out in the given format.
+// Frequencies are written in MHz.
+//
+// The matrices can be measured from a model with TwoPortSweep().
+func WriteS2P(w io.Writer, points []NetworkPoint, z0 float64, format TouchstoneFormat) error {
+	if z0 <= 0 {
+		return fmt.Errorf("reference impedance must be positive, got %g", z0)
+	}
+	tp := make([]touchstonePoint, len(points))
+	for i, p := range points {
+		if len(p.Z) != 2 || len(p.Z[0]) != 2 || len(p.Z[1]) != 2 {
+			return fmt.Errorf("network at %g MHz is not a two port network", p.FreqMHz)
+		}
+		s, err := zToS(p.Z, z0)
+		if err != nil {
+			return fmt.Errorf("network at %g MHz: %s", p.FreqMHz, err.Error())
+		}
+		tp[i] = touchstonePoint{freqMHz: p.FreqMHz, s: s}
+	}
+	return writeTouchstone(w, 2, tp, z0, format)
+}
+
+type touchstonePoint struct {
+	freqMHz float64
+	s       [][]complex128
+}
+
+func writeTouchstone(w io.Writer, ports int, points []touchstonePoint, z0 float64, format TouchstoneFormat) error {
+	if format < MagnitudeAngle || format > RealImaginary {
+		return errors.New("unknown Touchstone format")
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "! %d port S-parameters written by go-libnecpp\n", ports)
+	fmt.Fprintf(bw, "# MHZ S %s R %g\n", format, z0)
+	for _, p := range points {
+		fmt.Fprintf(bw, "%.9g", p.freqMHz)
+		// Two port files are the odd one out, being written in column
+		// major order (S11 S21 S12 S22).
+		for _, v := range touchstoneOrder(p.s, ports) {
+			a, b := touchstonePair(v, format)
+			fmt.Fprintf(bw, " %.9g %.9g", a, b)
+		}
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
+
+func touchstoneOrder(s [][]complex128, ports int) []complex128 {
+	vals := make([]complex128, 0, ports*ports)
+	if ports == 2 {
+		return append(vals, s[0][0], s[1][0], s[0][1], s[1][1])
+	}
+	for i := 0; i < ports; i++ {
+		vals = append(vals, s[i]...)
+	}
+	return vals
+}
+
+func touchstonePair(v complex128, format TouchstoneFormat) (float64, float64) {
+	switch format {
+	case DecibelAngle:
+		return 20 * math.Log10(cmplx.Abs(v)), phaseDeg(v)
+	case RealImaginary:
+		return real(v), imag(v)
+	}
+	return cmplx.Abs(v), phaseDeg(v)
+}
+
+// Touchstone holds the network data read from a Touchstone file, converted to
+// port impedance matrices whatever parameters the file was written with.
+type Touchstone struct {
+	Ports  int
+	Z0     float64 // the reference impedance given in the file
+	Points []NetworkPoint
+}
+
+// Sweep returns the impedance data of a one port Touchstone file in the same
+// form as NecppCtx.ImpedanceSweep(), for comparing against a simulation.
+func (t *Touchstone) Sweep() ([]ImpedancePoint, error) {
+	if t.Ports != 1 {
+		return nil, fmt.Errorf("expected a one port network, got %d ports", t.Ports)
+	}
+	sweep := make([]ImpedancePoint, len(t.Points))
+	for i, p := range t.Points {
+		sweep[i] = ImpedancePoint{FreqMHz: p.FreqMHz, Impedance: p.Z[0][0]}
+	}
+	return sweep, nil
+}
+
+var touchstoneExt = regexp.MustCompile(`(?i)^\.s(\d+)p$`)
+
+// ReadTouchstoneFile reads a Touchstone file from disk, working out the number
+// of ports from the file's extension (.s1p, .s2p, and so on).
+func ReadTouchstoneFile(path string) (*Touchstone, error) {
+	m := touchstoneExt.FindStringSubmatch(filepath.Ext(path))
+	if m == nil {
+		return nil, fmt.Errorf("can't tell the number of ports from the name of %s", path)
+	}
+	ports, _ := strconv.Atoi(m[1])
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadTouchstone(f, ports)
+}
+
+// ReadTouchstone reads version 1 Touchstone data with the given number of ports
+// from r. S, Y and Z parameters in any of the MA, DB, or RI formats are
+// understood. Noise parameters at the end of a two port file are skipped.
+func ReadTouchstone(r io.Reader, ports int) (*Touchstone, error) {
+	if ports < 1 {
+		return nil, fmt.Errorf("invalid number of ports %d", ports)
+	}
+	// the defaults for anything left out of the option line
+	freqMult := 1.0e9
+	param := "S"
+	format := MagnitudeAngle
+	z0 := DefaultReferenceImpedance
+
+	var nums []float64
+	sawOptions := false
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.Index(line, "!"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line[0] == '#' {
+			if sawOptions {
+				// only the first option line counts
+				continue
+			}
+			sawOptions = true
+			var err error
+			freqMult, param, format, z0, err = parseTouchstoneOptions(line[1:], freqMult, param, format, z0)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNo, err.Error())
+			}
+			continue
+		}
+		for _, f := range strings.Fields(line) {
+			v, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad number %q", lineNo, f)
+			}
+			nums = append(nums, v)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	t := &Touchstone{Ports: ports, Z0: z0}
+	recLen := 1 + 2*ports*ports
+	lastFreq := math.Inf(-1)
+	for len(nums) > 0 {
+		freq := nums[0] * freqMult / 1.0e6
+		if ports == 2 && freq <= lastFreq {
+			// the noise parameters have started
+			nums = nil
+			break
+		}
+		if len(nums) < recLen {
+			break
+		}
+		lastFreq = freq
+		vals := make([]complex128, ports*ports)
+		for i := range vals {
+			vals[i] = touchstoneValue(nums[1+2*i], nums[2+2*i], format)
+		}
+		m := newMatrix(ports, ports)
+		for i := 0; i < ports; i++ {
+			for j := 0; j < ports; j++ {
+				if ports == 2 {
+					m[i][j] = vals[j*2+i]
+				} else {
+					m[i][j] = vals[i*ports+j]
+				}
+			}
+		}
+		z, err := touchstoneToZ(m, param, z0)
+		if err != nil {
+			return nil, fmt.Errorf("data at %g MHz: %s", freq, err.Error())
+		}
+		t.Points = append(t.Points, NetworkPoint{FreqMHz: freq, Z: z})
+		nums = nums[recLen:]
+	}
+	if len(nums) != 0 {
+		return nil, fmt.Errorf("%d numbers left over at the end of the data, which doesn't match %d ports", len(nums), ports)
+	}
+	return t, nil
+}
+
+func parseTouchstoneOptions(opts string, freqMult float64, param string, format TouchstoneFormat, z0 float64) (float64, string, TouchstoneFormat, float64, error) {
+	fields := strings.Fields(strings.ToUpper(opts))
+	for i := 0; i < len(fields); i++ {
+		switch f := fields[i]; f {
+		case "HZ":
+			freqMult = 1
+		case "KHZ":
+			freqMult = 1.0e3
+		case "MHZ":
+			freqMult = 1.0e6
+		case "GHZ":
+			freqMult = 1.0e9
+		case "S", "Y", "Z":
+			param = f
+		case "H", "G":
+			return 0, "", 0, 0, fmt.Errorf("%s parameters are not supported", f)
+		case "MA":
+			format = MagnitudeAngle
+		case "DB":
+			format = DecibelAngle
+		case "RI":
+			format = RealImaginary
+		case "R":
+			if i+1 >= len(fields) {
+				return 0, "", 0, 0, errors.New("missing reference impedance")
+			}
+			i++
+			r, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil || r <= 0 {
+				return 0, "", 0, 0, fmt.Errorf("bad reference impedance %q", fields[i])
+			}
+			z0 = r
+		default:
+			return 0, "", 0, 0, fmt.Errorf("unknown option %q", f)
+		}
+	}
+	return freqMult, param, format, z0, nil
+}
+
+func touchstoneValue(a float64, b float64, format TouchstoneFormat) complex128 {
+	switch format {
+	case DecibelAngle:
+		return cmplx.Rect(math.Pow(10, a/20), b*math.Pi/180)
+	case RealImaginary:
+		return complex(a, b)
+	}
+	return cmplx.Rect(a, b*math.Pi/180)
+}
+
+// touchstoneToZ converts the parameters in a Touchstone file to an impedance
+// matrix. Version 1 files store Y and Z parameters normalized to the reference
+// impedance.
+func touchstoneToZ(m [][]complex128, param string, z0 float64) ([][]complex128, error) {
+	switch param {
+	case "Z":
+		scaleMatrix(m, complex(z0, 0))
+		return m, nil
+	case "Y":
+		z, err := matrixInverse(m)
+		if err != nil {
+			return nil, err
+		}
+		scaleMatrix(z, complex(z0, 0))
+		return z, nil
+	}
+	return sToZ(m, z0)
+}
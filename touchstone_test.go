@@ -0,0 +1,134 @@
+package necpp
+
+import (
+	"bytes"
+	"math/cmplx"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteS1P(t *testing.T) {
+	sweep := []ImpedancePoint{
+		{FreqMHz: 14.0, Impedance: complex(50, 0)},
+		{FreqMHz: 14.1, Impedance: complex(150, 0)},
+	}
+	var buf bytes.Buffer
+	if err := WriteS1P(&buf, sweep, 50, RealImaginary); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d: %q", len(lines), buf.String())
+	}
+	if lines[1] != "# MHZ S RI R 50" {
+		t.Errorf("option line was %q", lines[1])
+	}
+	if lines[2] != "14 0 0" {
+		t.Errorf("matched point was %q, should have been %q", lines[2], "14 0 0")
+	}
+	if lines[3] != "14.1 0.5 0" {
+		t.Errorf("mismatched point was %q, should have been %q", lines[3], "14.1 0.5 0")
+	}
+}
+
+func TestWriteS2POrder(t *testing.T) {
+	// A non-reciprocal network, so each S-parameter is different and a wrong
+	// column order shows up.
+	z := [][]complex128{{complex(60, 10), 20}, {complex(35, -5), 80}}
+	const z0 = 50
+	points := []NetworkPoint{{FreqMHz: 7.0, Z: z}}
+	var buf bytes.Buffer
+	if err := WriteS2P(&buf, points, z0, RealImaginary); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) != 9 {
+		t.Fatalf("expected 9 fields, got %d: %q", len(fields), lines[len(lines)-1])
+	}
+
+	// S = (Z - z0)(Z + z0)^-1, worked out by hand for the 2x2 case
+	a := [2][2]complex128{{z[0][0] - z0, z[0][1]}, {z[1][0], z[1][1] - z0}}
+	b := [2][2]complex128{{z[0][0] + z0, z[0][1]}, {z[1][0], z[1][1] + z0}}
+	det := b[0][0]*b[1][1] - b[0][1]*b[1][0]
+	inv := [2][2]complex128{{b[1][1] / det, -b[0][1] / det}, {-b[1][0] / det, b[0][0] / det}}
+	var sp [2][2]complex128
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			sp[i][j] = a[i][0]*inv[0][j] + a[i][1]*inv[1][j]
+		}
+	}
+	want := []struct {
+		name string
+		s    complex128
+	}{
+		{"S11", sp[0][0]},
+		{"S21", sp[1][0]},
+		{"S12", sp[0][1]},
+		{"S22", sp[1][1]},
+	}
+	for i, w := range want {
+		re, _ := strconv.ParseFloat(fields[1+2*i], 64)
+		im, _ := strconv.ParseFloat(fields[2+2*i], 64)
+		if cmplx.Abs(complex(re, im)-w.s) > 1e-6 {
+			t.Errorf("column %d was %g%+gi, should have been %s, %v", i+1, re, im, w.name, w.s)
+		}
+	}
+}
+
+func TestWriteS2PNotTwoPort(t *testing.T) {
+	points := []NetworkPoint{{FreqMHz: 7.0, Z: [][]complex128{{50}}}}
+	if err := WriteS2P(&bytes.Buffer{}, points, 50, MagnitudeAngle); err == nil {
+		t.Errorf("writing a one port network as an s2p file should have failed")
+	}
+}
+
+func TestTouchstoneRoundTrip(t *testing.T) {
+	sweep := []ImpedancePoint{
+		{FreqMHz: 14.0, Impedance: complex(23, -14)},
+		{FreqMHz: 14.2, Impedance: complex(48, 3)},
+	}
+	for _, format := range []TouchstoneFormat{MagnitudeAngle, DecibelAngle, RealImaginary} {
+		var buf bytes.Buffer
+		if err := WriteS1P(&buf, sweep, 75, format); err != nil {
+			t.Fatal(err)
+		}
+		ts, err := ReadTouchstone(&buf, 1)
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+		if ts.Z0 != 75 {
+			t.Errorf("%s: reference impedance was %g, should have been 75", format, ts.Z0)
+		}
+		got, err := ts.Sweep()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range sweep {
+			if got[i].FreqMHz != sweep[i].FreqMHz || cmplx.Abs(got[i].Impedance-sweep[i].Impedance) > 1e-6 {
+				t.Errorf("%s: point %d was %v, should have been %v", format, i, got[i], sweep[i])
+			}
+		}
+	}
+}
+
+func TestReadS2PWithNoise(t *testing.T) {
+	data := `! a lossy two port, then some noise parameters
+# GHz S RI R 50
+1.0 0.2 0 0.5 0 0.5 0 0.2 0
+2.0 0.2 0 0 -0.5 0 -0.5 0.2 0
+! noise
+1.0 2.5 0.5 45 0.3
+`
+	ts, err := ReadTouchstone(strings.NewReader(data), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts.Points) != 2 {
+		t.Fatalf("expected 2 points, got %d", len(ts.Points))
+	}
+	if ts.Points[1].FreqMHz != 2000 {
+		t.Errorf("frequency was %g MHz, should have been 2000", ts.Points[1].FreqMHz)
+	}
+}
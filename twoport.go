@@ -0,0 +1,198 @@
+package necpp
+
+import (
+	"errors"
+	"fmt"
+	"math/cmplx"
+)
+
+// TwoPortForm is the kind of parameters a TwoPort is described by.
+//
+// • YParameters - short circuit admittance parameters, in mhos. This is what
+// the NT card takes.
+//
+// • ZParameters - open circuit impedance parameters, in ohms.
+//
+// • SParameters - scattering parameters, against a real reference impedance on
+// both ports.
+//
+// • ABCDParameters - chain (transmission) parameters, with port 1 as the input
+// and port 2 as the output.
+type TwoPortForm int
+
+const (
+	YParameters TwoPortForm = iota
+	ZParameters
+	SParameters
+	ABCDParameters
+)
+
+func (f TwoPortForm) String() string {
+	switch f {
+	case YParameters:
+		return "Y"
+	case ZParameters:
+		return "Z"
+	case SParameters:
+		return "S"
+	case ABCDParameters:
+		return "ABCD"
+	}
+	return fmt.Sprintf("TwoPortForm(%d)", int(f))
+}
+
+// TwoPort is a linear two port network, given by whichever parameters are
+// handiest, which can be put into a model with ConnectTwoPort(). Z0 is the
+// reference impedance for S parameters, and isn't used otherwise.
+type TwoPort struct {
+	Form   TwoPortForm
+	Params [2][2]complex128
+	Z0     float64
+}
+
+// NewYTwoPort, NewZTwoPort, NewSTwoPort and NewABCDTwoPort make a TwoPort out of
+// the given parameters.
+func NewYTwoPort(y [2][2]complex128) TwoPort {
+	return TwoPort{Form: YParameters, Params: y}
+}
+
+func NewZTwoPort(z [2][2]complex128) TwoPort {
+	return TwoPort{Form: ZParameters, Params: z}
+}
+
+func NewSTwoPort(s [2][2]complex128, z0 float64) TwoPort {
+	return TwoPort{Form: SParameters, Params: s, Z0: z0}
+}
+
+func NewABCDTwoPort(abcd [2][2]complex128) TwoPort {
+	return TwoPort{Form: ABCDParameters, Params: abcd}
+}
+
+// Y returns the network's short circuit admittance parameters. Networks with no
+// Y parameters, such as a plain shunt admittance, give an error.
+func (t TwoPort) Y() ([2][2]complex128, error) {
+	switch t.Form {
+	case YParameters:
+		return t.Params, nil
+	case ZParameters:
+		return inverse2(t.Params)
+	case SParameters:
+		z, err := t.Z()
+		if err != nil {
+			return [2][2]complex128{}, err
+		}
+		return inverse2(z)
+	case ABCDParameters:
+		m := t.Params
+		b := m[0][1]
+		if b == 0 {
+			return [2][2]complex128{}, errors.New("the network has no series impedance, so it has no Y parameters")
+		}
+		det := m[0][0]*m[1][1] - m[0][1]*m[1][0]
+		return [2][2]complex128{{m[1][1] / b, -det / b}, {-1 / b, m[0][0] / b}}, nil
+	}
+	return [2][2]complex128{}, fmt.Errorf("unknown two port form %d", int(t.Form))
+}
+
+// Z returns the network's open circuit impedance parameters.
+func (t TwoPort) Z() ([2][2]complex128, error) {
+	switch t.Form {
+	case ZParameters:
+		return t.Params, nil
+	case SParameters:
+		if t.Z0 <= 0 {
+			return [2][2]complex128{}, fmt.Errorf("reference impedance must be positive, got %g", t.Z0)
+		}
+		z, err := sToZ(toSlice2(t.Params), t.Z0)
+		if err != nil {
+			return [2][2]complex128{}, err
+		}
+		return fromSlice2(z), nil
+	case ABCDParameters:
+		m := t.Params
+		c := m[1][0]
+		if c == 0 {
+			return [2][2]complex128{}, errors.New("the network has no shunt admittance, so it has no Z parameters")
+		}
+		det := m[0][0]*m[1][1] - m[0][1]*m[1][0]
+		return [2][2]complex128{{m[0][0] / c, det / c}, {1 / c, m[1][1] / c}}, nil
+	}
+	y, err := t.Y()
+	if err != nil {
+		return [2][2]complex128{}, err
+	}
+	return inverse2(y)
+}
+
+// S returns the network's scattering parameters, against the reference
+// impedance z0 on both ports.
+func (t TwoPort) S(z0 float64) ([2][2]complex128, error) {
+	if z0 <= 0 {
+		return [2][2]complex128{}, fmt.Errorf("reference impedance must be positive, got %g", z0)
+	}
+	if t.Form == SParameters && t.Z0 == z0 {
+		return t.Params, nil
+	}
+	z, err := t.Z()
+	if err != nil {
+		return [2][2]complex128{}, err
+	}
+	s, err := zToS(toSlice2(z), z0)
+	if err != nil {
+		return [2][2]complex128{}, err
+	}
+	return fromSlice2(s), nil
+}
+
+// ABCD returns the network's chain parameters.
+func (t TwoPort) ABCD() ([2][2]complex128, error) {
+	if t.Form == ABCDParameters {
+		return t.Params, nil
+	}
+	y, err := t.Y()
+	if err != nil {
+		return [2][2]complex128{}, err
+	}
+	y21 := y[1][0]
+	if y21 == 0 {
+		return [2][2]complex128{}, errors.New("the ports aren't connected, so the network has no ABCD parameters")
+	}
+	det := y[0][0]*y[1][1] - y[0][1]*y[1][0]
+	return [2][2]complex128{{-y[1][1] / y21, -1 / y21}, {-det / y21, -y[0][0] / y21}}, nil
+}
+
+// ConnectTwoPort puts a two port network into the model with an NT card, from
+// the segment at p1 to the segment at p2. NEC only takes reciprocal networks
+// (Y12 the same as Y21), and the parameters apply at every frequency, so a
+// network made of real components is only right at the frequency it was worked
+// out for.
+func (n *NecppCtx) ConnectTwoPort(p1 Port, p2 Port, t TwoPort) error {
+	return connectTwoPort(n, p1, p2, t)
+}
+
+func connectTwoPort(e Engine, p1 Port, p2 Port, t TwoPort) error {
+	y, err := t.Y()
+	if err != nil {
+		return err
+	}
+	if cmplx.Abs(y[0][1]-y[1][0]) > 1e-9*(cmplx.Abs(y[0][1])+cmplx.Abs(y[1][0])) {
+		return fmt.Errorf("the network isn't reciprocal (Y12 %v, Y21 %v), which an NT card can't model", y[0][1], y[1][0])
+	}
+	return e.NtCard(p1.Tag, p1.Segment, p2.Tag, p2.Segment, real(y[0][0]), imag(y[0][0]), real(y[0][1]), imag(y[0][1]), real(y[1][1]), imag(y[1][1]))
+}
+
+func inverse2(m [2][2]complex128) ([2][2]complex128, error) {
+	det := m[0][0]*m[1][1] - m[0][1]*m[1][0]
+	if det == 0 {
+		return [2][2]complex128{}, errSingularMatrix
+	}
+	return [2][2]complex128{{m[1][1] / det, -m[0][1] / det}, {-m[1][0] / det, m[0][0] / det}}, nil
+}
+
+func toSlice2(m [2][2]complex128) [][]complex128 {
+	return [][]complex128{{m[0][0], m[0][1]}, {m[1][0], m[1][1]}}
+}
+
+func fromSlice2(m [][]complex128) [2][2]complex128 {
+	return [2][2]complex128{{m[0][0], m[0][1]}, {m[1][0], m[1][1]}}
+}
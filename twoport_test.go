@@ -0,0 +1,74 @@
+package necpp
+
+import (
+	"math/cmplx"
+	"testing"
+)
+
+func close2(a [2][2]complex128, b [2][2]complex128) bool {
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			if cmplx.Abs(a[i][j]-b[i][j]) > 1e-9*(1+cmplx.Abs(b[i][j])) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestTwoPortConversions(t *testing.T) {
+	// a T network: 10+20j ohms, then 200-50j ohms across, then 30-5j ohms
+	za, zb, zc := complex(10, 20), complex(200, -50), complex(30, -5)
+	z := [2][2]complex128{{za + zb, zb}, {zb, zb + zc}}
+	// the same network in ABCD form
+	abcd := [2][2]complex128{{1 + za/zb, za + zc + za*zc/zb}, {1 / zb, 1 + zc/zb}}
+
+	zt := NewZTwoPort(z)
+	y, err := zt.Y()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := zt.S(50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tp := range []TwoPort{zt, NewYTwoPort(y), NewSTwoPort(s, 50), NewABCDTwoPort(abcd)} {
+		gotZ, err := tp.Z()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !close2(gotZ, z) {
+			t.Errorf("%s parameters gave Z %v, should have been %v", tp.Form, gotZ, z)
+		}
+		gotY, err := tp.Y()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !close2(gotY, y) {
+			t.Errorf("%s parameters gave Y %v, should have been %v", tp.Form, gotY, y)
+		}
+		gotABCD, err := tp.ABCD()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !close2(gotABCD, abcd) {
+			t.Errorf("%s parameters gave ABCD %v, should have been %v", tp.Form, gotABCD, abcd)
+		}
+		gotS, err := tp.S(50)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !close2(gotS, s) {
+			t.Errorf("%s parameters gave S %v, should have been %v", tp.Form, gotS, s)
+		}
+	}
+
+	// a plain series impedance has Y parameters but no Z parameters
+	series := NewABCDTwoPort([2][2]complex128{{1, 50}, {0, 1}})
+	if _, err := series.Y(); err != nil {
+		t.Errorf("series impedance should have had Y parameters: %s", err)
+	}
+	if _, err := series.Z(); err == nil {
+		t.Errorf("series impedance shouldn't have had Z parameters")
+	}
+}
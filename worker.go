@@ -0,0 +1,324 @@
+package necpp
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// workerEnv and progressEnv are set in a worker process's environment, to the
+// file it writes its reply to and the file it logs each card to as it runs.
+const (
+	workerEnv   = "NECPP_WORKER_REPLY"
+	progressEnv = "NECPP_WORKER_PROGRESS"
+)
+
+// Worker runs models in a child process, so a run can be stopped partway
+// through a card. libnecpp can't be interrupted once it's started on a card,
+// and an RP or XQ card on a big model can take minutes, so Model.Run() can
+// only check its context between cards; a Worker kills the process instead.
+//
+// The child process is a worker: a program that calls ServeWorkerIfRequested()
+// at the start of main(), started with the model to run on its standard input.
+// By default it's the running program itself, started again, so a program
+// that leaves Path empty must make that call; the necpp-worker command does
+// nothing else, for programs that would rather not.
+//
+// A worker says it's ready before it's sent the model, so a program that's
+// started as one but doesn't serve isn't left to carry on: if the child doesn't
+// say so within StartTimeout, or says anything else, it's killed and Run()
+// returns an error. The program will still have started, though, so whatever
+// its main() does before that happens will have been done.
+//
+// The worker also keeps a bad model from taking down the program running it:
+// if libnecpp aborts or segfaults, only the worker dies, and Run() returns a
+// *CrashError saying how, and on which card.
+//
+// • Path - the worker executable. Empty means the running program.
+//
+// • Solver - run the model on the pure Go Solver instead of libnecpp.
+//
+// • StartTimeout - how long the worker has to say it's ready. Zero means
+// DefaultWorkerStartTimeout.
+type Worker struct {
+	Path         string
+	Solver       bool
+	StartTimeout time.Duration
+}
+
+// DefaultWorkerStartTimeout is how long a worker has to say it's ready, unless
+// the Worker's StartTimeout says otherwise.
+const DefaultWorkerStartTimeout = 10 * time.Second
+
+// workerReady is what a worker writes to its standard output as soon as it
+// starts, before it reads the model.
+const workerReady = "necpp-worker ready\n"
+
+// workerRequest is what's sent to a worker process.
+type workerRequest struct {
+	Model  *Model
+	Solver bool
+}
+
+// workerReply is what a worker process sends back.
+type workerReply struct {
+	Result *Result
+	Err    string
+}
+
+func init() {
+	// the geometry cards and requests a model can hold
+	gob.Register(WireSpec{})
+	gob.Register(PatchSpec{})
+	gob.Register(Move{})
+	gob.Register(Reflection{})
+	gob.Register(PatternRequest{})
+	gob.Register(NearFieldRequest{})
+	gob.Register(Execute(0))
+}
+
+// ServeWorkerIfRequested runs the model it's been sent and exits, if the
+// program has been started as a worker by Worker.Run(), and otherwise returns
+// straight away. It should be the first thing main() does, before anything
+// that writes to standard output or starts other work, in any program that
+// runs a Worker with an empty Path. Tests that use such a Worker call it from
+// TestMain(), as the test binary is the program that's started again.
+func ServeWorkerIfRequested() {
+	if reply := os.Getenv(workerEnv); reply != "" {
+		os.Exit(serveWorker(reply, os.Getenv(progressEnv)))
+	}
+}
+
+// serveWorker runs the model sent on standard input, logging each card to the
+// progress file, if there is one, and writes the reply to the reply file. It
+// returns the process's exit status.
+func serveWorker(replyPath string, progressPath string) int {
+	if _, err := io.WriteString(os.Stdout, workerReady); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var progress io.Writer
+	if progressPath != "" {
+		f, err := os.OpenFile(progressPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		progress = f
+	}
+
+	var req workerRequest
+	var reply workerReply
+	if err := gob.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		reply.Err = fmt.Sprintf("reading the model: %s", err.Error())
+	} else if res, err := req.run(progress); err != nil {
+		reply.Err = err.Error()
+	} else {
+		reply.Result = res
+	}
+
+	f, err := os.Create(replyPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer f.Close()
+	if err := gob.NewEncoder(f).Encode(reply); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// run runs the requested model on a new engine, logging each card to progress
+// if it isn't nil.
+func (req workerRequest) run(progress io.Writer) (*Result, error) {
+	var e Engine
+	if req.Solver {
+		e = NewSolver()
+	} else {
+		n, err := New()
+		if err != nil {
+			return nil, err
+		}
+		e = n
+	}
+	defer e.Delete()
+	if progress != nil {
+		e = &tracer{Engine: e, log: progress}
+	}
+	// the report comes back in the Result; it mustn't go to standard output
+	req.Model.Output = ioutil.Discard
+	return req.Model.RunEngine(context.Background(), e)
+}
+
+// Run runs the model in a new worker process, and returns its results, the
+// same as Model.Run() would. If ctx is cancelled or its deadline passes before
+// the model has finished, the worker is killed, and ctx.Err() is returned.
+//
+// The printed report is passed on to the model's Output once the run is over,
+// rather than while it happens.
+func (w *Worker) Run(ctx context.Context, m *Model) (*Result, error) {
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if os.Getenv(workerEnv) != "" {
+		// this is a worker whose main() went on past
+		// ServeWorkerIfRequested(), or never called it; starting another
+		// would only do the same
+		return nil, errors.New("a worker process can't run a Worker; main() should call ServeWorkerIfRequested() first")
+	}
+	path := w.Path
+	if path == "" {
+		var err error
+		if path, err = os.Executable(); err != nil {
+			return nil, fmt.Errorf("finding the worker executable: %s", err.Error())
+		}
+	}
+
+	mc := m.Clone()
+	mc.Output = nil
+	var req bytes.Buffer
+	if err := gob.NewEncoder(&req).Encode(workerRequest{Model: mc, Solver: w.Solver}); err != nil {
+		return nil, fmt.Errorf("sending the model to the worker: %s", err.Error())
+	}
+
+	dir, err := ioutil.TempDir("", "necpp-worker")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	replyPath := filepath.Join(dir, "reply")
+	progressPath := filepath.Join(dir, "progress")
+
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Env = append(os.Environ(), workerEnv+"="+replyPath, progressEnv+"="+progressPath)
+	stderr := &tailWriter{limit: stderrLimit}
+	cmd.Stderr = stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting the worker process: %s", err.Error())
+	}
+	if err = w.awaitReady(ctx, stdout); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if s := strings.TrimSpace(stderr.String()); s != "" {
+			err = fmt.Errorf("%s, and on its standard error: %s", err.Error(), s)
+		}
+		return nil, fmt.Errorf("%s didn't start as a worker (does its main() call ServeWorkerIfRequested()?): %s", path, err.Error())
+	}
+
+	// nothing else the worker prints is wanted, but it mustn't block on it
+	drained := make(chan struct{})
+	go func() {
+		io.Copy(ioutil.Discard, stdout)
+		close(drained)
+	}()
+	// if the worker dies before it's read all of the model, Wait() says how
+	stdin.Write(req.Bytes())
+	stdin.Close()
+	<-drained
+	err = cmd.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if exit, ok := err.(*exec.ExitError); ok {
+		return nil, crashError(exit, progressPath, stderr.String())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("running the worker process: %s", err.Error())
+	}
+
+	f, err := os.Open(replyPath)
+	if err != nil {
+		return nil, fmt.Errorf("the worker process didn't reply: %s", err.Error())
+	}
+	defer f.Close()
+	var reply workerReply
+	if err := gob.NewDecoder(f).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("reading the worker's reply: %s", err.Error())
+	}
+	if reply.Err != "" {
+		return nil, errors.New(reply.Err)
+	}
+
+	out := m.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	if _, err := out.Write([]byte(reply.Result.Output)); err != nil {
+		return nil, err
+	}
+	return reply.Result, nil
+}
+
+// awaitReady waits for the worker to say it's ready on its standard output.
+func (w *Worker) awaitReady(ctx context.Context, stdout io.Reader) error {
+	timeout := w.StartTimeout
+	if timeout == 0 {
+		timeout = DefaultWorkerStartTimeout
+	}
+	read := make(chan error, 1)
+	go func() {
+		buf := make([]byte, len(workerReady))
+		if k, err := io.ReadFull(stdout, buf); err != nil {
+			read <- fmt.Errorf("it printed %q and stopped", buf[:k])
+		} else if string(buf) != workerReady {
+			read <- fmt.Errorf("it printed %q", buf)
+		} else {
+			read <- nil
+		}
+	}()
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case err := <-read:
+		return err
+	case <-t.C:
+		return fmt.Errorf("it didn't say it was ready within %s", timeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// crashError works out what a worker that died was doing at the time.
+func crashError(exit *exec.ExitError, progressPath string, stderr string) *CrashError {
+	e := &CrashError{ExitCode: exit.ExitCode(), Status: exit.String(), Stderr: stderr}
+	if b, err := ioutil.ReadFile(progressPath); err == nil {
+		if cards := strings.Fields(string(b)); len(cards) > 0 {
+			e.CardIndex = len(cards) - 1
+			e.Card = cards[e.CardIndex]
+		}
+	}
+	return e
+}
+
+// RunWorker runs the model in a new worker process, using libnecpp, so it can
+// be stopped with ctx partway through a card. The worker is the running
+// program, so its main() must call ServeWorkerIfRequested(); see Worker.
+func (m *Model) RunWorker(ctx context.Context) (*Result, error) {
+	return new(Worker).Run(ctx, m)
+}
@@ -0,0 +1,174 @@
+package necpp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain lets the test binary be started again as a worker.
+func TestMain(m *testing.M) {
+	ServeWorkerIfRequested()
+	os.Exit(m.Run())
+}
+
+func TestWorkerRun(t *testing.T) {
+	var buf bytes.Buffer
+	m := simpleModel()
+	m.Output = &buf
+	res, err := (&Worker{Solver: true}).Run(context.Background(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Patterns) != 1 || len(res.Impedance) != 1 {
+		t.Fatalf("got %d patterns and %d impedances, should have been one of each", len(res.Patterns), len(res.Impedance))
+	}
+	if max := res.Patterns[0].MaxGain().Total; math.Abs(max-8.407404) > 0.05 {
+		t.Errorf("max gain was %f, libnecpp gives 8.407404", max)
+	}
+	if buf.String() != res.Output || !strings.Contains(res.Output, "POWER BUDGET") {
+		t.Errorf("the report wasn't passed on to the model's output")
+	}
+}
+
+func TestWorkerError(t *testing.T) {
+	m := simpleModel()
+	m.Add(PatchSpec{Shape: Triangular, Corners: [][3]float64{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}})
+	_, err := (&Worker{Solver: true}).Run(context.Background(), m)
+	if err == nil || !strings.Contains(err.Error(), "surface patches") {
+		t.Errorf("running a model with a patch gave %v", err)
+	}
+}
+
+func TestWorkerCancel(t *testing.T) {
+	// big enough to take the solver much longer than the deadline
+	m := NewModel().
+		AddWire(1, 2000, 0, 0, -50, 0, 0, 50, 0.001).
+		SetFrequency(30, 0, 1).
+		AddFeed(Port{Tag: 1, Segment: 1000}, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := (&Worker{Solver: true}).Run(ctx, m)
+	if err != context.DeadlineExceeded {
+		t.Errorf("running past the deadline gave %v, should have been %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("the worker took %s to stop", d)
+	}
+}
+
+// crashingWorker is a worker that gets partway through the model and then
+// segfaults.
+const crashingWorker = `#!/bin/sh
+echo "necpp-worker ready"
+cat > /dev/null
+printf 'Wire\nGeometryComplete\nRpCard\n' >> "$NECPP_WORKER_PROGRESS"
+echo "nec++: the matrix is singular" >&2
+kill -SEGV $$
+`
+
+func TestWorkerCrash(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the crashing worker is a shell script")
+	}
+	dir, err := ioutil.TempDir("", "necpp-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "worker")
+	if err := ioutil.WriteFile(path, []byte(crashingWorker), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = (&Worker{Path: path}).Run(context.Background(), simpleModel())
+	crash, ok := err.(*CrashError)
+	if !ok {
+		t.Fatalf("running a crashing worker gave %v, should have been a *CrashError", err)
+	}
+	if crash.Card != "RpCard" || crash.CardIndex != 2 {
+		t.Errorf("the worker crashed on card %d, %s, should have been card 2, RpCard", crash.CardIndex, crash.Card)
+	}
+	if crash.ExitCode != -1 || !strings.Contains(crash.Status, "segmentation") {
+		t.Errorf("the worker ended with %d, %q", crash.ExitCode, crash.Status)
+	}
+	if !strings.Contains(crash.Error(), "the matrix is singular") {
+		t.Errorf("the error was %q", crash.Error())
+	}
+}
+
+// notWorkers are programs that are started as workers but don't serve: one
+// that prints its usage and exits, and one that gets on with something else.
+var notWorkers = []string{
+	"#!/bin/sh\necho 'usage: prog [flags]'\n",
+	"#!/bin/sh\nexec sleep 30\n",
+}
+
+func TestWorkerNotServing(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the programs are shell scripts")
+	}
+	dir, err := ioutil.TempDir("", "necpp-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for i, script := range notWorkers {
+		path := filepath.Join(dir, fmt.Sprintf("prog%d", i))
+		if err := ioutil.WriteFile(path, []byte(script), 0700); err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		_, err := (&Worker{Path: path, StartTimeout: 500 * time.Millisecond}).Run(context.Background(), simpleModel())
+		if err == nil || !strings.Contains(err.Error(), "ServeWorkerIfRequested") {
+			t.Errorf("running a program that isn't a worker gave %v", err)
+		}
+		if d := time.Since(start); d > 5*time.Second {
+			t.Errorf("took %s to give up on a program that isn't a worker", d)
+		}
+	}
+}
+
+func TestTracer(t *testing.T) {
+	var buf bytes.Buffer
+	f := new(FakeEngine)
+	m := simpleModel()
+	// a GN and a GD card, both made by SetGround()
+	m.Ground = &Ground{
+		Type:         Finite,
+		Soil:         Soil{Permittivity: 13, Conductivity: 0.005},
+		Screen:       &GroundScreen{Radials: 60, Radius: 10, WireRadius: 0.001},
+		SecondMedium: &SecondMedium{Soil: Soil{Permittivity: 80, Conductivity: 5}, Distance: 20},
+	}
+	if _, err := m.RunEngine(context.Background(), &tracer{Engine: f, log: &buf}); err != nil {
+		t.Fatal(err)
+	}
+	cards := strings.Fields(buf.String())
+	if len(cards) != len(f.Calls) {
+		t.Fatalf("traced %d cards, but %d were made", len(cards), len(f.Calls))
+	}
+	for i, c := range f.Calls {
+		if cards[i] != c.Method {
+			t.Errorf("card %d was traced as %s, should have been %s", i, cards[i], c.Method)
+		}
+	}
+}
+
+func TestTailWriter(t *testing.T) {
+	w := &tailWriter{limit: 10}
+	for i := 0; i < 100; i++ {
+		w.Write([]byte("0123456789abc"))
+	}
+	if s := w.String(); s != "3456789abc" {
+		t.Errorf("kept %q, should have been the last 10 bytes", s)
+	}
+}